@@ -31,8 +31,35 @@ type Achievement struct {
 
 // Sub-struct untuk Attachments
 type Attachment struct {
-	FileName   string    `bson:"file_name" json:"file_name"`
-	FileURL    string    `bson:"file_url" json:"file_url"`
-	FileType   string    `bson:"file_type" json:"file_type"`
-	UploadedAt time.Time `bson:"uploaded_at" json:"uploaded_at"`
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	FileName   string             `bson:"file_name" json:"file_name"`
+	FileURL    string             `bson:"file_url" json:"file_url"`
+	FileType   string             `bson:"file_type" json:"file_type"`
+	StorageKey string             `bson:"storage_key,omitempty" json:"storage_key,omitempty"` // Key di backend storage
+	Size       int64              `bson:"size" json:"size"`
+	Checksum   string             `bson:"checksum,omitempty" json:"checksum,omitempty"`       // SHA-256 (hex)
+	UploadedBy string             `bson:"uploaded_by,omitempty" json:"uploaded_by,omitempty"` // User ID (Postgres UUID)
+	UploadedAt time.Time          `bson:"uploaded_at" json:"uploaded_at"`
+
+	// Status hasil scan malware: pending | clean | rejected (kosong = attachment lama)
+	Status          string `bson:"status,omitempty" json:"status,omitempty"`
+	RejectionReason string `bson:"rejection_reason,omitempty" json:"rejection_reason,omitempty"`
+
+	// Thumbnail gambar / preview halaman pertama PDF (diisi worker background)
+	ThumbnailKey string `bson:"thumbnail_key,omitempty" json:"-"`
+	ThumbnailURL string `bson:"thumbnail_url,omitempty" json:"thumbnail_url,omitempty"`
+
+	// Metadata PDF (diisi worker background), membantu dosen wali mendeteksi sertifikat yang diedit
+	Document *DocumentMetadata `bson:"document,omitempty" json:"document,omitempty"`
+}
+
+// Metadata dari dictionary /Info PDF
+type DocumentMetadata struct {
+	Title      string     `bson:"title,omitempty" json:"title,omitempty"`
+	Author     string     `bson:"author,omitempty" json:"author,omitempty"`
+	Creator    string     `bson:"creator,omitempty" json:"creator,omitempty"`   // aplikasi pembuat dokumen
+	Producer   string     `bson:"producer,omitempty" json:"producer,omitempty"` // aplikasi penulis PDF
+	CreatedAt  *time.Time `bson:"created_at,omitempty" json:"created_at,omitempty"`
+	ModifiedAt *time.Time `bson:"modified_at,omitempty" json:"modified_at,omitempty"`
+	PageCount  int        `bson:"page_count" json:"page_count"`
 }
@@ -0,0 +1,23 @@
+package mongo
+
+import "time"
+
+// Collection evidence_files: satu dokumen per isi file unik (content-addressed).
+// Attachment yang isinya identik memakai StorageKey yang sama, RefCount = jumlah attachment.
+type EvidenceFile struct {
+	Hash        string `bson:"_id" json:"hash"` // SHA-256 (hex)
+	StorageKey  string `bson:"storage_key" json:"storage_key"`
+	ContentType string `bson:"content_type" json:"content_type"`
+	Size        int64  `bson:"size" json:"size"`
+	RefCount    int    `bson:"ref_count" json:"ref_count"`
+
+	// Hasil pemrosesan background (lihat service.EvidenceProcessor)
+	ScanStatus   string            `bson:"scan_status,omitempty" json:"scan_status,omitempty"` // clean | rejected
+	ScanMessage  string            `bson:"scan_message,omitempty" json:"scan_message,omitempty"`
+	ThumbnailKey string            `bson:"thumbnail_key,omitempty" json:"thumbnail_key,omitempty"`
+	Document     *DocumentMetadata `bson:"document,omitempty" json:"document,omitempty"` // khusus PDF
+	ProcessedAt  *time.Time        `bson:"processed_at,omitempty" json:"processed_at,omitempty"`
+
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
+}
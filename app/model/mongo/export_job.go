@@ -0,0 +1,31 @@
+package mongo
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Collection export_jobs: export bundle evidence (zip) yang dibuat di background
+type ExportJob struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Status      string             `bson:"status" json:"status"`             // queued | running | done | failed | expired
+	RequestedBy string             `bson:"requested_by" json:"requested_by"` // User ID (Postgres UUID)
+
+	// Parameter export: prestasi verified milik prodi ini dengan verified_at di [From, To)
+	ProgramStudy string    `bson:"program_study" json:"program_study"`
+	From         time.Time `bson:"from" json:"from"`
+	To           time.Time `bson:"to" json:"to"`
+
+	// Hasil
+	StorageKey       string `bson:"storage_key,omitempty" json:"-"`
+	Size             int64  `bson:"size,omitempty" json:"size,omitempty"`
+	AchievementCount int    `bson:"achievement_count" json:"achievement_count"`
+	FileCount        int    `bson:"file_count" json:"file_count"`
+	Error            string `bson:"error,omitempty" json:"error,omitempty"`
+
+	CreatedAt  time.Time  `bson:"created_at" json:"created_at"`
+	StartedAt  *time.Time `bson:"started_at,omitempty" json:"started_at,omitempty"`
+	FinishedAt *time.Time `bson:"finished_at,omitempty" json:"finished_at,omitempty"`
+	ExpiresAt  *time.Time `bson:"expires_at,omitempty" json:"expires_at,omitempty"` // file zip dihapus setelah ini
+}
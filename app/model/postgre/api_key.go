@@ -0,0 +1,36 @@
+package postgre
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Tabel service_accounts: akun mesin untuk integrasi (website fakultas, sistem informasi akademik).
+// Tidak punya password/role, aksesnya ditentukan scope setiap API key.
+type ServiceAccount struct {
+	ID          uuid.UUID  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	Name        string     `gorm:"type:varchar(100);unique;not null"`
+	Description string     `gorm:"type:text"`
+	IsActive    bool       `gorm:"default:true"`
+	CreatedBy   *uuid.UUID `gorm:"type:uuid"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// Tabel api_keys: yang disimpan hanya hash SHA-256, key utuh ditampilkan sekali saat dibuat
+type APIKey struct {
+	ID               uuid.UUID      `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	ServiceAccountID uuid.UUID      `gorm:"type:uuid;not null;index"`
+	ServiceAccount   ServiceAccount `gorm:"foreignKey:ServiceAccountID;constraint:OnDelete:CASCADE"`
+	Name             string         `gorm:"type:varchar(100);not null"`
+	Prefix           string         `gorm:"type:varchar(16);not null"`          // awal key, untuk dikenali di daftar/log
+	KeyHash          string         `gorm:"type:char(64);uniqueIndex;not null"` // SHA-256 (hex)
+	Scopes           string         `gorm:"type:text;not null"`                 // nama permission dipisah spasi
+	ExpiresAt        *time.Time
+	LastUsedAt       *time.Time
+	LastUsedIP       string `gorm:"type:varchar(45)"`
+	RevokedAt        *time.Time
+	CreatedBy        *uuid.UUID `gorm:"type:uuid"`
+	CreatedAt        time.Time
+}
@@ -0,0 +1,35 @@
+package postgre
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Alasan percobaan login (kolom reason)
+const (
+	LoginSuccess            = "success"
+	LoginSuccessMFA         = "success_2fa"
+	LoginSuccessOIDC        = "success_oidc"
+	LoginSuccessLDAP        = "success_ldap"
+	LoginInvalidCredentials = "invalid_credentials" // dihitung untuk lockout akun & throttling IP
+	LoginInvalidMFACode     = "invalid_mfa_code"    // idem
+	LoginAccountLocked      = "account_locked"
+	LoginIPThrottled        = "ip_throttled"
+	LoginAccountInactive    = "account_inactive"
+	LoginOIDCNoAccount      = "oidc_no_account"
+	LoginAccountRejected    = "account_rejected" // password benar, tapi provider menolak akun (misal grup LDAP tidak dipetakan)
+)
+
+// Tabel login_attempts: log setiap percobaan login (sukses/gagal).
+// Dipakai untuk throttling per IP dan dapat dilihat admin.
+type LoginAttempt struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	UserID    *uuid.UUID `gorm:"type:uuid;index"` // nil jika username tidak dikenal
+	Username  string     `gorm:"type:varchar(100);index"`
+	IPAddress string     `gorm:"type:varchar(45);index:idx_login_attempts_ip_created"`
+	UserAgent string     `gorm:"type:varchar(255)"`
+	Success   bool
+	Reason    string    `gorm:"type:varchar(30)"`
+	CreatedAt time.Time `gorm:"index;index:idx_login_attempts_ip_created"`
+}
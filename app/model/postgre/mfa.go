@@ -0,0 +1,17 @@
+package postgre
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Tabel mfa_recovery_codes: kode cadangan 2FA sekali pakai, yang disimpan hanya hash SHA-256
+type MFARecoveryCode struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	UserID    uuid.UUID `gorm:"type:uuid;not null;index"`
+	User      User      `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	CodeHash  string    `gorm:"type:char(64);not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
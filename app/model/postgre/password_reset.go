@@ -0,0 +1,20 @@
+package postgre
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Tabel password_reset_tokens: token dikirim lewat email, yang disimpan hanya hash SHA-256.
+// Sekali pakai (UsedAt) dan berumur pendek (ExpiresAt).
+type PasswordResetToken struct {
+	ID          uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	UserID      uuid.UUID `gorm:"type:uuid;not null;index"`
+	User        User      `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	TokenHash   string    `gorm:"type:char(64);uniqueIndex;not null"`
+	RequestedIP string    `gorm:"type:varchar(45)"`
+	ExpiresAt   time.Time `gorm:"not null"`
+	UsedAt      *time.Time
+	CreatedAt   time.Time
+}
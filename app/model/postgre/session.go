@@ -0,0 +1,35 @@
+package postgre
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Tabel user_sessions: satu baris per login (satu "family" refresh token).
+// ID dipakai sebagai claim "sid" di access token.
+type UserSession struct {
+	ID            uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	UserID        uuid.UUID `gorm:"type:uuid;not null;index"`
+	User          User      `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	UserAgent     string    `gorm:"type:varchar(255)"`
+	IPAddress     string    `gorm:"type:varchar(45)"`
+	LastUsedAt    time.Time
+	ExpiresAt     time.Time  // refresh token terakhir kedaluwarsa
+	RevokedAt     *time.Time `gorm:"index"`
+	RevokedReason string     `gorm:"type:varchar(100)"`
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// Tabel refresh_tokens: setiap rotasi membuat baris baru, yang lama ditandai UsedAt.
+// Token lama yang dipakai lagi = indikasi pencurian, seluruh session dicabut.
+type RefreshToken struct {
+	ID        uuid.UUID   `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	SessionID uuid.UUID   `gorm:"type:uuid;not null;index"`
+	Session   UserSession `gorm:"foreignKey:SessionID;constraint:OnDelete:CASCADE"`
+	TokenHash string      `gorm:"type:char(64);uniqueIndex;not null"` // SHA-256 (hex), token asli tidak disimpan
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
@@ -15,6 +15,12 @@ type Role struct {
 	UpdatedAt   time.Time
 }
 
+// Sumber verifikasi password user (kolom auth_source)
+const (
+	AuthSourceLocal = "local" // bcrypt di kolom password_hash
+	AuthSourceLDAP  = "ldap"  // bind ke LDAP / Active Directory kampus
+)
+
 // Tabel users
 type User struct {
 	ID           uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
@@ -25,8 +31,18 @@ type User struct {
 	RoleID       uuid.UUID `gorm:"type:uuid;not null"`
 	Role         Role      `gorm:"foreignKey:RoleID"` // Relasi ke tabel Role
 	IsActive     bool      `gorm:"default:true"`
-	CreatedAt    time.Time
-	UpdatedAt    time.Time
+	AuthSource   string    `gorm:"type:varchar(20);not null;default:'local'"`
+	// Wajib ganti password sebelum bisa memakai API lain (akun baru / password lama tidak memenuhi policy)
+	MustChangePassword bool `gorm:"default:false"`
+	// Lockout: jumlah login gagal berturut-turut & akun dikunci sampai waktu ini
+	FailedLoginCount int `gorm:"default:0"`
+	LockedUntil      *time.Time
+	// 2FA (TOTP): secret disimpan terenkripsi, aktif jika TOTPEnabledAt terisi
+	TOTPSecret    string `gorm:"type:varchar(255)" json:"-"`
+	TOTPEnabledAt *time.Time
+	TOTPLastStep  int64 `json:"-"` // time step terakhir yang dipakai, kode yang sama tidak bisa dipakai ulang
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
 }
 
 // S Permissions & RolePermissions
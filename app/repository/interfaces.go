@@ -5,6 +5,7 @@ import (
 	mongoModel "reportachievement/app/model/mongo"
 	"reportachievement/app/model/postgre"
 	postgreRepo "reportachievement/app/repository/postgre" // Import untuk struct AchievementFilter
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -13,6 +14,20 @@ import (
 type IUserRepository interface {
 	FindByUsername(username string) (*postgre.User, error)
 	FindByID(id uuid.UUID) (*postgre.User, error)
+	UpdatePassword(id uuid.UUID, passwordHash string, mustChange bool) error
+}
+
+// Interface untuk Session Repository (refresh token)
+type ISessionRepository interface {
+	Create(session *postgre.UserSession, token *postgre.RefreshToken) error
+	FindByID(id uuid.UUID) (*postgre.UserSession, error)
+	FindRefreshToken(tokenHash string) (*postgre.RefreshToken, error)
+	Rotate(oldTokenID uuid.UUID, newToken *postgre.RefreshToken) (bool, error)
+	Revoke(sessionID uuid.UUID, reason string) error
+	RevokeAllForUser(userID uuid.UUID, reason string) error
+	RevokeOthers(userID, keepID uuid.UUID, reason string) error
+	FindActiveByUser(userID uuid.UUID) ([]postgre.UserSession, error)
+	Touch(sessionID uuid.UUID, at time.Time) error
 }
 
 // Interface untuk Student Repository
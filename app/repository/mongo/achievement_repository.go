@@ -2,12 +2,15 @@ package mongo
 
 import (
 	"context"
+	"net/url"
 	"reportachievement/app/model/mongo"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	mongoDriver "go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type AchievementRepository struct {
@@ -81,6 +84,171 @@ func (r *AchievementRepository) AddAttachment(ctx context.Context, id string, at
 	return err
 }
 
+// 4a. FindReferencedAttachments: attachment dari prestasi yang belum dihapus,
+// atau dihapus setelah deletedAfter (masih dalam masa tenggang garbage collector)
+func (r *AchievementRepository) FindReferencedAttachments(ctx context.Context, deletedAfter time.Time) ([]mongo.Attachment, error) {
+	pipeline := mongoDriver.Pipeline{
+		{{Key: "$match", Value: bson.M{"$or": []bson.M{
+			{"deleted_at": nil}, // juga cocok untuk field yang tidak ada
+			{"deleted_at": bson.M{"$gt": deletedAfter}},
+		}}}},
+		{{Key: "$unwind", Value: "$attachments"}},
+		{{Key: "$replaceRoot", Value: bson.M{"newRoot": "$attachments"}}},
+	}
+	cursor, err := r.Coll.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var attachments []mongo.Attachment
+	if err = cursor.All(ctx, &attachments); err != nil {
+		return nil, err
+	}
+	return attachments, nil
+}
+
+// 4b. RemoveAttachment
+func (r *AchievementRepository) RemoveAttachment(ctx context.Context, id string, attachmentID primitive.ObjectID) error {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	filter := bson.M{"_id": objID}
+	update := bson.M{"$pull": bson.M{"attachments": bson.M{"_id": attachmentID}}}
+	_, err = r.Coll.UpdateOne(ctx, filter, update)
+	return err
+}
+
+// 4c. ReplaceAttachment (berdasarkan attachment.ID)
+func (r *AchievementRepository) ReplaceAttachment(ctx context.Context, id string, attachment mongo.Attachment) error {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	filter := bson.M{"_id": objID, "attachments._id": attachment.ID}
+	update := bson.M{"$set": bson.M{"attachments.$": attachment}}
+	_, err = r.Coll.UpdateOne(ctx, filter, update)
+	return err
+}
+
+// 4d. SetAttachmentFields: update field (status scan, thumbnail, dll) pada SEMUA attachment
+// dengan checksum tersebut. Key fields = nama field bson attachment.
+func (r *AchievementRepository) SetAttachmentFields(ctx context.Context, checksum string, fields map[string]interface{}) error {
+	set := bson.M{}
+	for field, value := range fields {
+		set["attachments.$[a]."+field] = value
+	}
+	filter := bson.M{"attachments.checksum": checksum}
+	update := bson.M{"$set": set}
+	opts := options.Update().SetArrayFilters(options.ArrayFilters{
+		Filters: []interface{}{bson.M{"a.checksum": checksum}},
+	})
+	_, err := r.Coll.UpdateMany(ctx, filter, update, opts)
+	return err
+}
+
+// 5. FindByAttachmentKey (Untuk otorisasi download evidence).
+// Satu file bisa dipakai beberapa prestasi (content-addressed), jadi hasilnya list.
+func (r *AchievementRepository) FindByAttachmentKey(ctx context.Context, key string) ([]mongo.Achievement, error) {
+	filter := bson.M{
+		"$or": bson.A{
+			bson.M{"attachments.storage_key": key},
+			bson.M{"attachments.thumbnail_key": key},
+		},
+		"deleted_at": bson.M{"$exists": false},
+	}
+	cursor, err := r.Coll.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var achievements []mongo.Achievement
+	if err = cursor.All(ctx, &achievements); err != nil {
+		return nil, err
+	}
+	return achievements, nil
+}
+
+// 6. BackfillStorageKeys: lengkapi storage_key attachment lama (sebelum ada storage backend)
+// yang masih menyimpan URL static "/uploads/<nama file>".
+func (r *AchievementRepository) BackfillStorageKeys(ctx context.Context, fileURL func(key string) string) (int, error) {
+	filter := bson.M{"attachments": bson.M{"$elemMatch": bson.M{"storage_key": bson.M{"$exists": false}}}}
+	cursor, err := r.Coll.Find(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	updated := 0
+	for cursor.Next(ctx) {
+		var achievement mongo.Achievement
+		if err := cursor.Decode(&achievement); err != nil {
+			return updated, err
+		}
+		for i, att := range achievement.Attachments {
+			if att.StorageKey != "" {
+				continue
+			}
+			key, ok := LegacyStorageKey(att.FileURL)
+			if !ok {
+				continue
+			}
+			achievement.Attachments[i].StorageKey = key
+			achievement.Attachments[i].FileURL = fileURL(key)
+		}
+		update := bson.M{"$set": bson.M{"attachments": achievement.Attachments}}
+		if _, err := r.Coll.UpdateByID(ctx, achievement.ID, update); err != nil {
+			return updated, err
+		}
+		updated++
+	}
+	return updated, cursor.Err()
+}
+
+// LegacyStorageKey: key storage dari URL static lama (.../uploads/<key>) milik attachment tanpa storage_key
+func LegacyStorageKey(fileURL string) (string, bool) {
+	idx := strings.LastIndex(fileURL, "/uploads/")
+	if idx < 0 {
+		return "", false
+	}
+	key := fileURL[idx+len("/uploads/"):]
+	if unescaped, err := url.PathUnescape(key); err == nil {
+		key = unescaped
+	}
+	return key, key != ""
+}
+
+// 7. BackfillAttachmentIDs: beri _id pada attachment lama agar bisa dihapus/diganti satu per satu
+func (r *AchievementRepository) BackfillAttachmentIDs(ctx context.Context) (int, error) {
+	filter := bson.M{"attachments": bson.M{"$elemMatch": bson.M{"_id": bson.M{"$exists": false}}}}
+	cursor, err := r.Coll.Find(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	updated := 0
+	for cursor.Next(ctx) {
+		var achievement mongo.Achievement
+		if err := cursor.Decode(&achievement); err != nil {
+			return updated, err
+		}
+		for i := range achievement.Attachments {
+			if achievement.Attachments[i].ID.IsZero() {
+				achievement.Attachments[i].ID = primitive.NewObjectID()
+			}
+		}
+		update := bson.M{"$set": bson.M{"attachments": achievement.Attachments}}
+		if _, err := r.Coll.UpdateByID(ctx, achievement.ID, update); err != nil {
+			return updated, err
+		}
+		updated++
+	}
+	return updated, cursor.Err()
+}
+
 // ---  AGGREGATIONS ---
 
 // Struct hasil agregasi Top Student
@@ -145,3 +313,51 @@ func (r *AchievementRepository) GetStatsByType(ctx context.Context) ([]GroupStat
 	}
 	return results, nil
 }
+
+// Struct hasil agregasi evidence duplikat
+type DuplicateEvidenceResult struct {
+	Hash         string                 `bson:"_id"`
+	StudentIDs   []string               `bson:"students"`
+	Achievements []DuplicateAchievement `bson:"achievements"`
+}
+
+type DuplicateAchievement struct {
+	ID                primitive.ObjectID `bson:"id"`
+	StudentPostgresID string             `bson:"student_postgres_id"`
+	Title             string             `bson:"title"`
+	FileName          string             `bson:"file_name"`
+}
+
+// C. Get Duplicate Evidence (file identik dipakai oleh lebih dari satu mahasiswa)
+func (r *AchievementRepository) GetDuplicateEvidence(ctx context.Context) ([]DuplicateEvidenceResult, error) {
+	pipeline := mongoDriver.Pipeline{
+		{{Key: "$match", Value: bson.M{"deleted_at": bson.M{"$exists": false}}}},
+		{{Key: "$unwind", Value: "$attachments"}},
+		{{Key: "$match", Value: bson.M{"attachments.checksum": bson.M{"$nin": bson.A{nil, ""}}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":      "$attachments.checksum",
+			"students": bson.M{"$addToSet": "$student_postgres_id"},
+			"achievements": bson.M{"$push": bson.M{
+				"id":                  "$_id",
+				"student_postgres_id": "$student_postgres_id",
+				"title":               "$title",
+				"file_name":           "$attachments.file_name",
+			}},
+		}}},
+		// Minimal 2 mahasiswa berbeda
+		{{Key: "$match", Value: bson.M{"students.1": bson.M{"$exists": true}}}},
+		{{Key: "$sort", Value: bson.M{"_id": 1}}},
+	}
+
+	cursor, err := r.Coll.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []DuplicateEvidenceResult
+	if err = cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
@@ -0,0 +1,137 @@
+package mongo
+
+import (
+	"context"
+	"reportachievement/app/model/mongo"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	mongoDriver "go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type EvidenceFileRepository struct {
+	Coll *mongoDriver.Collection
+}
+
+func NewEvidenceFileRepository(db *mongoDriver.Database) *EvidenceFileRepository {
+	return &EvidenceFileRepository{
+		Coll: db.Collection("evidence_files"),
+	}
+}
+
+// 1. FindByHash
+func (r *EvidenceFileRepository) FindByHash(ctx context.Context, hash string) (*mongo.EvidenceFile, error) {
+	var file mongo.EvidenceFile
+	if err := r.Coll.FindOne(ctx, bson.M{"_id": hash}).Decode(&file); err != nil {
+		return nil, err
+	}
+	return &file, nil
+}
+
+// 2. Acquire: tambah 1 referensi (upsert). created = true jika hash belum pernah ada,
+// artinya pemanggil wajib menyimpan isi file ke storage.
+func (r *EvidenceFileRepository) Acquire(ctx context.Context, file mongo.EvidenceFile) (bool, error) {
+	now := time.Now()
+	update := bson.M{
+		"$inc": bson.M{"ref_count": 1},
+		"$set": bson.M{"updated_at": now},
+		"$setOnInsert": bson.M{
+			"storage_key":  file.StorageKey,
+			"content_type": file.ContentType,
+			"size":         file.Size,
+			"created_at":   now,
+		},
+	}
+	result, err := r.Coll.UpdateByID(ctx, file.Hash, update, options.Update().SetUpsert(true))
+	if err != nil {
+		return false, err
+	}
+	return result.UpsertedCount == 1, nil
+}
+
+// 3. Release: kurangi 1 referensi, kembalikan sisa referensi.
+// Jika sisa 0 dokumen dihapus, dan pemanggil wajib menghapus file di storage.
+func (r *EvidenceFileRepository) Release(ctx context.Context, hash string) (int, error) {
+	var file mongo.EvidenceFile
+	err := r.Coll.FindOneAndUpdate(ctx,
+		bson.M{"_id": hash},
+		bson.M{"$inc": bson.M{"ref_count": -1}, "$set": bson.M{"updated_at": time.Now()}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&file)
+	if err != nil {
+		return 0, err
+	}
+	if file.RefCount > 0 {
+		return file.RefCount, nil
+	}
+	// Filter ref_count <= 0: jangan hapus jika ada upload baru yang Acquire di sela-sela
+	result, err := r.Coll.DeleteOne(ctx, bson.M{"_id": hash, "ref_count": bson.M{"$lte": 0}})
+	if err != nil {
+		return 0, err
+	}
+	if result.DeletedCount == 0 {
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// 4. FindUnprocessed (Untuk antre ulang saat aplikasi start & resync berkala)
+func (r *EvidenceFileRepository) FindUnprocessed(ctx context.Context) ([]mongo.EvidenceFile, error) {
+	cursor, err := r.Coll.Find(ctx, bson.M{"processed_at": bson.M{"$exists": false}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var files []mongo.EvidenceFile
+	if err = cursor.All(ctx, &files); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// 5. MarkProcessed: simpan hasil pemrosesan (scan, lokasi file, thumbnail, metadata PDF)
+func (r *EvidenceFileRepository) MarkProcessed(ctx context.Context, file mongo.EvidenceFile) error {
+	now := time.Now()
+	set := bson.M{
+		"storage_key":  file.StorageKey,
+		"scan_status":  file.ScanStatus,
+		"scan_message": file.ScanMessage,
+		"processed_at": now,
+		"updated_at":   now,
+	}
+	if file.ThumbnailKey != "" {
+		set["thumbnail_key"] = file.ThumbnailKey
+	}
+	if file.Document != nil {
+		set["document"] = file.Document
+	}
+	_, err := r.Coll.UpdateByID(ctx, file.Hash, bson.M{"$set": set})
+	return err
+}
+
+// 6. FindAll (Untuk garbage collector storage)
+func (r *EvidenceFileRepository) FindAll(ctx context.Context) ([]mongo.EvidenceFile, error) {
+	cursor, err := r.Coll.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var files []mongo.EvidenceFile
+	if err = cursor.All(ctx, &files); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// 7. DeleteStale: hapus record yang tidak disentuh sejak before.
+// deleted = false jika record baru saja di-Acquire lagi (updated_at berubah).
+func (r *EvidenceFileRepository) DeleteStale(ctx context.Context, hash string, before time.Time) (bool, error) {
+	result, err := r.Coll.DeleteOne(ctx, bson.M{"_id": hash, "updated_at": bson.M{"$lt": before}})
+	if err != nil {
+		return false, err
+	}
+	return result.DeletedCount == 1, nil
+}
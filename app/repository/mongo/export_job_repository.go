@@ -0,0 +1,81 @@
+package mongo
+
+import (
+	"context"
+	"reportachievement/app/model/mongo"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	mongoDriver "go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type ExportJobRepository struct {
+	Coll *mongoDriver.Collection
+}
+
+func NewExportJobRepository(db *mongoDriver.Database) *ExportJobRepository {
+	return &ExportJobRepository{
+		Coll: db.Collection("export_jobs"),
+	}
+}
+
+// 1. Insert
+func (r *ExportJobRepository) Insert(ctx context.Context, job *mongo.ExportJob) error {
+	result, err := r.Coll.InsertOne(ctx, job)
+	if err != nil {
+		return err
+	}
+	job.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+// 2. FindByID
+func (r *ExportJobRepository) FindByID(ctx context.Context, id string) (*mongo.ExportJob, error) {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, mongoDriver.ErrNoDocuments
+	}
+	var job mongo.ExportJob
+	if err := r.Coll.FindOne(ctx, bson.M{"_id": objID}).Decode(&job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// 3. FindRecent (Daftar export terbaru)
+func (r *ExportJobRepository) FindRecent(ctx context.Context, limit int64) ([]mongo.ExportJob, error) {
+	opts := options.Find().SetSort(bson.M{"created_at": -1}).SetLimit(limit)
+	return r.find(ctx, bson.M{}, opts)
+}
+
+// 4. FindUnfinished (Untuk antre ulang saat aplikasi start)
+func (r *ExportJobRepository) FindUnfinished(ctx context.Context) ([]mongo.ExportJob, error) {
+	return r.find(ctx, bson.M{"status": bson.M{"$in": []string{"queued", "running"}}}, options.Find().SetSort(bson.M{"created_at": 1}))
+}
+
+// 5. FindExpired (File zip yang sudah melewati masa simpan)
+func (r *ExportJobRepository) FindExpired(ctx context.Context, now time.Time) ([]mongo.ExportJob, error) {
+	return r.find(ctx, bson.M{"status": "done", "expires_at": bson.M{"$lt": now}}, options.Find())
+}
+
+// 6. Update: set sebagian field (status, hasil, waktu)
+func (r *ExportJobRepository) Update(ctx context.Context, id primitive.ObjectID, fields map[string]interface{}) error {
+	_, err := r.Coll.UpdateByID(ctx, id, bson.M{"$set": fields})
+	return err
+}
+
+func (r *ExportJobRepository) find(ctx context.Context, filter bson.M, opts *options.FindOptions) ([]mongo.ExportJob, error) {
+	cursor, err := r.Coll.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var jobs []mongo.ExportJob
+	if err = cursor.All(ctx, &jobs); err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
@@ -2,6 +2,7 @@ package postgre
 
 import (
 	"reportachievement/app/model/postgre"
+	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
@@ -73,6 +74,15 @@ func (r *AchievementRepository) FindByID(id uuid.UUID) (*postgre.AchievementRefe
 	return &achievement, err
 }
 
+// 3b. FIND BY MONGO ID (Relasi balik dari dokumen Mongo)
+func (r *AchievementRepository) FindByMongoID(mongoID string) (*postgre.AchievementReference, error) {
+	var achievement postgre.AchievementReference
+	err := r.db.Preload("Student").
+		Preload("Student.User").
+		First(&achievement, "mongo_achievement_id = ?", mongoID).Error
+	return &achievement, err
+}
+
 // 4. VERIFY OR REJECT (Update Status)
 func (r *AchievementRepository) VerifyOrReject(id uuid.UUID, updates map[string]interface{}) error {
 	return r.db.Model(&postgre.AchievementReference{}).Where("id = ?", id).Updates(updates).Error
@@ -82,3 +92,25 @@ func (r *AchievementRepository) VerifyOrReject(id uuid.UUID, updates map[string]
 func (r *AchievementRepository) UpdateStatus(id uuid.UUID, status string) error {
 	return r.db.Model(&postgre.AchievementReference{}).Where("id = ?", id).Update("status", status).Error
 }
+
+// 6. FIND VERIFIED BY STUDENT IDS (Untuk SKPI)
+func (r *AchievementRepository) FindVerifiedByStudentIDs(studentIDs []uuid.UUID) ([]postgre.AchievementReference, error) {
+	var achievements []postgre.AchievementReference
+	err := r.db.Preload("Verifier").
+		Where("status = ? AND student_id IN ?", "verified", studentIDs).
+		Order("verified_at ASC").
+		Find(&achievements).Error
+	return achievements, err
+}
+
+// 7. FIND VERIFIED BY PROGRAM (Untuk export akreditasi), verified_at di [from, to)
+func (r *AchievementRepository) FindVerifiedByProgram(programStudy string, from, to time.Time) ([]postgre.AchievementReference, error) {
+	var achievements []postgre.AchievementReference
+	err := r.db.Preload("Student").Preload("Student.User").Preload("Verifier").
+		Joins("JOIN students ON students.id = achievement_references.student_id").
+		Where("achievement_references.status = ? AND students.program_study = ?", "verified", programStudy).
+		Where("achievement_references.verified_at >= ? AND achievement_references.verified_at < ?", from, to).
+		Order("students.nim ASC, achievement_references.verified_at ASC").
+		Find(&achievements).Error
+	return achievements, err
+}
@@ -0,0 +1,90 @@
+package postgre
+
+import (
+	"reportachievement/app/model/postgre"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type APIKeyRepository struct {
+	db *gorm.DB
+}
+
+func NewAPIKeyRepository(db *gorm.DB) *APIKeyRepository {
+	return &APIKeyRepository{db: db}
+}
+
+// 1. CreateAccount
+func (r *APIKeyRepository) CreateAccount(account *postgre.ServiceAccount) error {
+	return r.db.Create(account).Error
+}
+
+// 2. FindAccounts (urut nama)
+func (r *APIKeyRepository) FindAccounts() ([]postgre.ServiceAccount, error) {
+	var accounts []postgre.ServiceAccount
+	err := r.db.Order("name").Find(&accounts).Error
+	return accounts, err
+}
+
+// 3. FindAccountByID
+func (r *APIKeyRepository) FindAccountByID(id uuid.UUID) (*postgre.ServiceAccount, error) {
+	var account postgre.ServiceAccount
+	if err := r.db.First(&account, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+// 4. UpdateAccount
+func (r *APIKeyRepository) UpdateAccount(account *postgre.ServiceAccount) error {
+	return r.db.Save(account).Error
+}
+
+// 5. DeleteAccount (API key ikut terhapus)
+func (r *APIKeyRepository) DeleteAccount(id uuid.UUID) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("service_account_id = ?", id).Delete(&postgre.APIKey{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&postgre.ServiceAccount{}, "id = ?", id).Error
+	})
+}
+
+// 6. CreateKey
+func (r *APIKeyRepository) CreateKey(key *postgre.APIKey) error {
+	return r.db.Create(key).Error
+}
+
+// 7. FindKeysByAccount (terbaru dulu, termasuk yang sudah dicabut)
+func (r *APIKeyRepository) FindKeysByAccount(accountID uuid.UUID) ([]postgre.APIKey, error) {
+	var keys []postgre.APIKey
+	err := r.db.Where("service_account_id = ?", accountID).Order("created_at DESC").Find(&keys).Error
+	return keys, err
+}
+
+// 8. FindKeyByHash (untuk middleware, beserta service account-nya)
+func (r *APIKeyRepository) FindKeyByHash(keyHash string) (*postgre.APIKey, error) {
+	var key postgre.APIKey
+	if err := r.db.Preload("ServiceAccount").Where("key_hash = ?", keyHash).First(&key).Error; err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// 9. RevokeKey: false jika key tidak ada / sudah dicabut
+func (r *APIKeyRepository) RevokeKey(accountID, keyID uuid.UUID) (bool, error) {
+	result := r.db.Model(&postgre.APIKey{}).
+		Where("id = ? AND service_account_id = ? AND revoked_at IS NULL", keyID, accountID).
+		Update("revoked_at", time.Now())
+	return result.RowsAffected > 0, result.Error
+}
+
+// 10. TouchKey: catat waktu & IP pemakaian terakhir
+func (r *APIKeyRepository) TouchKey(keyID uuid.UUID, usedAt time.Time, ip string) error {
+	return r.db.Model(&postgre.APIKey{}).Where("id = ?", keyID).Updates(map[string]interface{}{
+		"last_used_at": usedAt,
+		"last_used_ip": ip,
+	}).Error
+}
@@ -0,0 +1,75 @@
+package postgre
+
+import (
+	"reportachievement/app/model/postgre"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type LoginAttemptFilter struct {
+	Page      int
+	Limit     int
+	UserID    *uuid.UUID
+	Username  string
+	IPAddress string
+	Success   *bool
+}
+
+type LoginAttemptRepository struct {
+	db *gorm.DB
+}
+
+func NewLoginAttemptRepository(db *gorm.DB) *LoginAttemptRepository {
+	return &LoginAttemptRepository{db: db}
+}
+
+// 1. Create
+func (r *LoginAttemptRepository) Create(attempt *postgre.LoginAttempt) error {
+	return r.db.Create(attempt).Error
+}
+
+// 2. RecentFailuresByIP: waktu kegagalan (password / kode 2FA salah, username tidak dikenal) terbaru dari satu IP
+func (r *LoginAttemptRepository) RecentFailuresByIP(ip string, since time.Time, limit int) ([]time.Time, error) {
+	var times []time.Time
+	reasons := []string{postgre.LoginInvalidCredentials, postgre.LoginInvalidMFACode}
+	err := r.db.Model(&postgre.LoginAttempt{}).
+		Where("ip_address = ? AND reason IN ? AND created_at > ?", ip, reasons, since).
+		Order("created_at DESC").Limit(limit).
+		Pluck("created_at", &times).Error
+	return times, err
+}
+
+// 3. FindAll: untuk admin, terbaru lebih dulu
+func (r *LoginAttemptRepository) FindAll(filter LoginAttemptFilter) ([]postgre.LoginAttempt, int64, error) {
+	var attempts []postgre.LoginAttempt
+	var total int64
+
+	query := r.db.Model(&postgre.LoginAttempt{})
+	if filter.UserID != nil {
+		query = query.Where("user_id = ?", *filter.UserID)
+	}
+	if filter.Username != "" {
+		query = query.Where("username = ?", filter.Username)
+	}
+	if filter.IPAddress != "" {
+		query = query.Where("ip_address = ?", filter.IPAddress)
+	}
+	if filter.Success != nil {
+		query = query.Where("success = ?", *filter.Success)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (filter.Page - 1) * filter.Limit
+	err := query.Limit(filter.Limit).Offset(offset).Order("created_at DESC").Find(&attempts).Error
+	return attempts, total, err
+}
+
+// 4. DeleteBefore: retensi log
+func (r *LoginAttemptRepository) DeleteBefore(before time.Time) error {
+	return r.db.Where("created_at < ?", before).Delete(&postgre.LoginAttempt{}).Error
+}
@@ -0,0 +1,51 @@
+package postgre
+
+import (
+	"reportachievement/app/model/postgre"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type MFARepository struct {
+	db *gorm.DB
+}
+
+func NewMFARepository(db *gorm.DB) *MFARepository {
+	return &MFARepository{db: db}
+}
+
+// 1. ReplaceRecoveryCodes: kode lama (terpakai maupun belum) diganti set baru
+func (r *MFARepository) ReplaceRecoveryCodes(userID uuid.UUID, codeHashes []string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&postgre.MFARecoveryCode{}).Error; err != nil {
+			return err
+		}
+		codes := make([]postgre.MFARecoveryCode, 0, len(codeHashes))
+		for _, hash := range codeHashes {
+			codes = append(codes, postgre.MFARecoveryCode{UserID: userID, CodeHash: hash})
+		}
+		return tx.Create(&codes).Error
+	})
+}
+
+// 2. UseRecoveryCode: atomic, false jika kode tidak ada atau sudah dipakai
+func (r *MFARepository) UseRecoveryCode(userID uuid.UUID, codeHash string) (bool, error) {
+	result := r.db.Model(&postgre.MFARecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", time.Now())
+	return result.RowsAffected > 0, result.Error
+}
+
+// 3. CountUnused
+func (r *MFARepository) CountUnused(userID uuid.UUID) (int64, error) {
+	var count int64
+	err := r.db.Model(&postgre.MFARecoveryCode{}).Where("user_id = ? AND used_at IS NULL", userID).Count(&count).Error
+	return count, err
+}
+
+// 4. DeleteByUser: 2FA dinonaktifkan / direset admin
+func (r *MFARepository) DeleteByUser(userID uuid.UUID) error {
+	return r.db.Where("user_id = ?", userID).Delete(&postgre.MFARecoveryCode{}).Error
+}
@@ -0,0 +1,69 @@
+package postgre
+
+import (
+	"reportachievement/app/model/postgre"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type PasswordResetRepository struct {
+	db *gorm.DB
+}
+
+func NewPasswordResetRepository(db *gorm.DB) *PasswordResetRepository {
+	return &PasswordResetRepository{db: db}
+}
+
+// 1. Create: token lama user yang belum dipakai dibatalkan, hanya link terbaru yang berlaku
+func (r *PasswordResetRepository) Create(token *postgre.PasswordResetToken) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&postgre.PasswordResetToken{}).
+			Where("user_id = ? AND used_at IS NULL", token.UserID).
+			Update("used_at", time.Now()).Error; err != nil {
+			return err
+		}
+		return tx.Create(token).Error
+	})
+}
+
+// 2. FindLatestByUser: untuk membatasi frekuensi permintaan
+func (r *PasswordResetRepository) FindLatestByUser(userID uuid.UUID) (*postgre.PasswordResetToken, error) {
+	var token postgre.PasswordResetToken
+	err := r.db.Where("user_id = ?", userID).Order("created_at DESC").First(&token).Error
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// 3. FindByHash
+func (r *PasswordResetRepository) FindByHash(tokenHash string) (*postgre.PasswordResetToken, error) {
+	var token postgre.PasswordResetToken
+	err := r.db.Where("token_hash = ?", tokenHash).First(&token).Error
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// 4. MarkUsed: atomic, false jika token sudah dipakai request lain
+func (r *PasswordResetRepository) MarkUsed(id uuid.UUID) (bool, error) {
+	result := r.db.Model(&postgre.PasswordResetToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	return result.RowsAffected == 1, result.Error
+}
+
+// 5. InvalidateByUser: batalkan semua token user yang belum dipakai (password sudah diganti)
+func (r *PasswordResetRepository) InvalidateByUser(userID uuid.UUID) error {
+	return r.db.Model(&postgre.PasswordResetToken{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Update("used_at", time.Now()).Error
+}
+
+// 6. DeleteExpired: bersihkan token kedaluwarsa
+func (r *PasswordResetRepository) DeleteExpired(before time.Time) error {
+	return r.db.Where("expires_at < ?", before).Delete(&postgre.PasswordResetToken{}).Error
+}
@@ -0,0 +1,134 @@
+package postgre
+
+import (
+	"reportachievement/app/model/postgre"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PermissionRepository struct {
+	db *gorm.DB
+}
+
+func NewPermissionRepository(db *gorm.DB) *PermissionRepository {
+	return &PermissionRepository{db: db}
+}
+
+// RolePermissionName: pasangan nama role & nama permission (hasil join)
+type RolePermissionName struct {
+	RoleName       string
+	PermissionName string
+}
+
+// 1. FindAllRolePermissions: seluruh mapping role -> permission (dimuat sekaligus untuk cache)
+func (r *PermissionRepository) FindAllRolePermissions() ([]RolePermissionName, error) {
+	var rows []RolePermissionName
+	err := r.db.Table("role_permissions").
+		Select("roles.name AS role_name, permissions.name AS permission_name").
+		Joins("JOIN roles ON roles.id = role_permissions.role_id").
+		Joins("JOIN permissions ON permissions.id = role_permissions.permission_id").
+		Scan(&rows).Error
+	return rows, err
+}
+
+// 2. FindAllPermissions
+func (r *PermissionRepository) FindAllPermissions() ([]postgre.Permission, error) {
+	var permissions []postgre.Permission
+	err := r.db.Order("name").Find(&permissions).Error
+	return permissions, err
+}
+
+// 3. FindPermissionByName
+func (r *PermissionRepository) FindPermissionByName(name string) (*postgre.Permission, error) {
+	var permission postgre.Permission
+	err := r.db.Where("name = ?", name).First(&permission).Error
+	return &permission, err
+}
+
+// 4. FindAllRoles
+func (r *PermissionRepository) FindAllRoles() ([]postgre.Role, error) {
+	var roles []postgre.Role
+	err := r.db.Order("name").Find(&roles).Error
+	return roles, err
+}
+
+// 5. FindRoleByID
+func (r *PermissionRepository) FindRoleByID(id uuid.UUID) (*postgre.Role, error) {
+	var role postgre.Role
+	err := r.db.First(&role, "id = ?", id).Error
+	return &role, err
+}
+
+// 6. CreateRole beserta permission awalnya (1 transaksi)
+func (r *PermissionRepository) CreateRole(role *postgre.Role, permissionIDs []uuid.UUID) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(role).Error; err != nil {
+			return err
+		}
+		for _, permissionID := range permissionIDs {
+			if err := tx.Create(&postgre.RolePermission{RoleID: role.ID, PermissionID: permissionID}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// 7. UpdateRole (nama & deskripsi)
+func (r *PermissionRepository) UpdateRole(role *postgre.Role) error {
+	return r.db.Save(role).Error
+}
+
+// 8. DeleteRole: mapping permission ikut dihapus
+func (r *PermissionRepository) DeleteRole(id uuid.UUID) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("role_id = ?", id).Delete(&postgre.RolePermission{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&postgre.Role{}, "id = ?", id).Error
+	})
+}
+
+// 9. AttachPermission (idempotent)
+func (r *PermissionRepository) AttachPermission(roleID, permissionID uuid.UUID) error {
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&postgre.RolePermission{RoleID: roleID, PermissionID: permissionID}).Error
+}
+
+// 10. DetachPermission
+func (r *PermissionRepository) DetachPermission(roleID, permissionID uuid.UUID) error {
+	return r.db.Where("role_id = ? AND permission_id = ?", roleID, permissionID).
+		Delete(&postgre.RolePermission{}).Error
+}
+
+// 11. CountUsersByRole: per role ID (role tanpa user tidak muncul)
+func (r *PermissionRepository) CountUsersByRole() (map[uuid.UUID]int64, error) {
+	var rows []struct {
+		RoleID uuid.UUID
+		Total  int64
+	}
+	if err := r.db.Model(&postgre.User{}).Select("role_id, COUNT(*) AS total").Group("role_id").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	counts := make(map[uuid.UUID]int64, len(rows))
+	for _, row := range rows {
+		counts[row.RoleID] = row.Total
+	}
+	return counts, nil
+}
+
+// 12. CountActiveUsersWithPermission: jumlah user aktif yang role-nya memiliki permission,
+// tidak menghitung exceptUserID dan role exceptRoleID (uuid.Nil = tanpa pengecualian).
+// Dipakai untuk memastikan selalu ada admin yang tersisa.
+func (r *PermissionRepository) CountActiveUsersWithPermission(permission string, exceptUserID, exceptRoleID uuid.UUID) (int64, error) {
+	var count int64
+	err := r.db.Model(&postgre.User{}).
+		Joins("JOIN role_permissions ON role_permissions.role_id = users.role_id").
+		Joins("JOIN permissions ON permissions.id = role_permissions.permission_id").
+		Where("permissions.name = ? AND users.is_active = ?", permission, true).
+		Where("users.id <> ? AND users.role_id <> ?", exceptUserID, exceptRoleID).
+		Count(&count).Error
+	return count, err
+}
@@ -0,0 +1,106 @@
+package postgre
+
+import (
+	"reportachievement/app/model/postgre"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type SessionRepository struct {
+	db *gorm.DB
+}
+
+func NewSessionRepository(db *gorm.DB) *SessionRepository {
+	return &SessionRepository{db: db}
+}
+
+// 1. Create: session baru beserta refresh token pertamanya (Login)
+func (r *SessionRepository) Create(session *postgre.UserSession, token *postgre.RefreshToken) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(session).Error; err != nil {
+			return err
+		}
+		token.SessionID = session.ID
+		return tx.Create(token).Error
+	})
+}
+
+// 2a. FindByID (Untuk cek session di middleware, termasuk status & role user saat ini)
+func (r *SessionRepository) FindByID(id uuid.UUID) (*postgre.UserSession, error) {
+	var session postgre.UserSession
+	if err := r.db.Preload("User.Role").First(&session, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// 2b. FindRefreshToken (berdasarkan hash, beserta session-nya)
+func (r *SessionRepository) FindRefreshToken(tokenHash string) (*postgre.RefreshToken, error) {
+	var token postgre.RefreshToken
+	err := r.db.Preload("Session").Where("token_hash = ?", tokenHash).First(&token).Error
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// 3. Rotate: tandai token lama terpakai & simpan token baru dalam satu transaksi.
+// rotated = false jika token lama sudah dipakai request lain (dianggap reuse).
+func (r *SessionRepository) Rotate(oldTokenID uuid.UUID, newToken *postgre.RefreshToken) (bool, error) {
+	rotated := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		result := tx.Model(&postgre.RefreshToken{}).
+			Where("id = ? AND used_at IS NULL", oldTokenID).
+			Update("used_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		if err := tx.Create(newToken).Error; err != nil {
+			return err
+		}
+		rotated = true
+		return tx.Model(&postgre.UserSession{}).Where("id = ?", newToken.SessionID).
+			Updates(map[string]interface{}{"last_used_at": now, "expires_at": newToken.ExpiresAt}).Error
+	})
+	return rotated && err == nil, err
+}
+
+// 4. Revoke: cabut session (seluruh family refresh token ikut tidak berlaku)
+func (r *SessionRepository) Revoke(sessionID uuid.UUID, reason string) error {
+	return r.db.Model(&postgre.UserSession{}).
+		Where("id = ? AND revoked_at IS NULL", sessionID).
+		Updates(map[string]interface{}{"revoked_at": time.Now(), "revoked_reason": reason}).Error
+}
+
+// 5. RevokeAllForUser: cabut semua session aktif milik user (logout semua perangkat, akun dinonaktifkan)
+func (r *SessionRepository) RevokeAllForUser(userID uuid.UUID, reason string) error {
+	return r.db.Model(&postgre.UserSession{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Updates(map[string]interface{}{"revoked_at": time.Now(), "revoked_reason": reason}).Error
+}
+
+// 6. RevokeOthers: cabut semua session user kecuali keepID (ganti password tanpa logout perangkat ini)
+func (r *SessionRepository) RevokeOthers(userID, keepID uuid.UUID, reason string) error {
+	return r.db.Model(&postgre.UserSession{}).
+		Where("user_id = ? AND id <> ? AND revoked_at IS NULL", userID, keepID).
+		Updates(map[string]interface{}{"revoked_at": time.Now(), "revoked_reason": reason}).Error
+}
+
+// 7. FindActiveByUser: session yang belum dicabut & belum kedaluwarsa (daftar perangkat yang sedang login)
+func (r *SessionRepository) FindActiveByUser(userID uuid.UUID) ([]postgre.UserSession, error) {
+	var sessions []postgre.UserSession
+	err := r.db.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_used_at DESC").Find(&sessions).Error
+	return sessions, err
+}
+
+// 8. Touch: catat waktu terakhir session dipakai (kolom "last seen")
+func (r *SessionRepository) Touch(sessionID uuid.UUID, at time.Time) error {
+	return r.db.Model(&postgre.UserSession{}).Where("id = ?", sessionID).Update("last_used_at", at).Error
+}
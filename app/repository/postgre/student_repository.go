@@ -32,6 +32,27 @@ func (r *StudentRepository) FindByIDs(ids []uuid.UUID) ([]postgre.Student, error
 	return students, err
 }
 
+// 3. FindByID (Untuk SKPI per mahasiswa)
+func (r *StudentRepository) FindByID(id uuid.UUID) (*postgre.Student, error) {
+	var student postgre.Student
+	err := r.db.Preload("User").First(&student, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &student, nil
+}
+
+// 4. FindByCohort (Untuk SKPI per angkatan, programStudy opsional)
+func (r *StudentRepository) FindByCohort(academicYear, programStudy string) ([]postgre.Student, error) {
+	var students []postgre.Student
+	query := r.db.Preload("User").Where("academic_year = ?", academicYear)
+	if programStudy != "" {
+		query = query.Where("program_study = ?", programStudy)
+	}
+	err := query.Order("nim ASC").Find(&students).Error
+	return students, err
+}
+
 // --- Cari List ID Mahasiswa Bimbingan Dosen Tertentu ---
 func (r *StudentRepository) FindIDsByAdvisorID(advisorID uuid.UUID) ([]uuid.UUID, error) {
 	var students []postgre.Student
@@ -2,6 +2,7 @@ package postgre
 
 import (
 	"reportachievement/app/model/postgre"
+	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
@@ -42,6 +43,16 @@ func (r *UserRepository) FindByID(id uuid.UUID) (*postgre.User, error) {
 	return &user, err
 }
 
+// 3a. FindByEmail (Untuk lupa password)
+func (r *UserRepository) FindByEmail(email string) (*postgre.User, error) {
+	var user postgre.User
+	err := r.db.Preload("Role").Where("LOWER(email) = LOWER(?)", email).First(&user).Error
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
 // 4. Create User (Standard)
 func (r *UserRepository) Create(user *postgre.User) error {
 	return r.db.Create(user).Error
@@ -80,6 +91,63 @@ func (r *UserRepository) Update(user *postgre.User) error {
 	return r.db.Save(user).Error
 }
 
+// 6a. UpdatePassword: hanya kolom password_hash & must_change_password
+func (r *UserRepository) UpdatePassword(id uuid.UUID, passwordHash string, mustChange bool) error {
+	return r.db.Model(&postgre.User{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"password_hash":        passwordHash,
+		"must_change_password": mustChange,
+	}).Error
+}
+
+// 6b. UpdateRole: ganti role user saja (tanpa menyimpan ulang relasi Role)
+func (r *UserRepository) UpdateRole(id, roleID uuid.UUID) error {
+	return r.db.Model(&postgre.User{}).Where("id = ?", id).Update("role_id", roleID).Error
+}
+
+// 6c. IncrementFailedLogin: atomic, mengembalikan jumlah gagal berturut-turut terbaru
+func (r *UserRepository) IncrementFailedLogin(id uuid.UUID) (int, error) {
+	var count int
+	err := r.db.Raw("UPDATE users SET failed_login_count = failed_login_count + 1 WHERE id = ? RETURNING failed_login_count", id).
+		Scan(&count).Error
+	return count, err
+}
+
+// 6d. LockUntil: kunci akun sementara
+func (r *UserRepository) LockUntil(id uuid.UUID, until time.Time) error {
+	return r.db.Model(&postgre.User{}).Where("id = ?", id).Update("locked_until", until).Error
+}
+
+// 6e. ResetLoginFailures: login sukses, reset password atau unlock oleh admin
+func (r *UserRepository) ResetLoginFailures(id uuid.UUID) error {
+	return r.db.Model(&postgre.User{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"failed_login_count": 0,
+		"locked_until":       nil,
+	}).Error
+}
+
+// 6f. SetTOTP: simpan secret (terenkripsi) & status aktif, secret kosong = 2FA dinonaktifkan
+func (r *UserRepository) SetTOTP(id uuid.UUID, secret string, enabledAt *time.Time) error {
+	return r.db.Model(&postgre.User{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"totp_secret":     secret,
+		"totp_enabled_at": enabledAt,
+		"totp_last_step":  0,
+	}).Error
+}
+
+// 6g. UseTOTPStep: atomic, false jika kode dari step ini (atau sesudahnya) sudah pernah dipakai
+func (r *UserRepository) UseTOTPStep(id uuid.UUID, step int64) (bool, error) {
+	result := r.db.Model(&postgre.User{}).Where("id = ? AND totp_last_step < ?", id, step).Update("totp_last_step", step)
+	return result.RowsAffected > 0, result.Error
+}
+
+// 6h. SyncProfile: email & nama dari direktori eksternal (LDAP)
+func (r *UserRepository) SyncProfile(id uuid.UUID, email, fullName string) error {
+	return r.db.Model(&postgre.User{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"email":     email,
+		"full_name": fullName,
+	}).Error
+}
+
 // 7. Delete (Hard Delete atau Soft Delete via IsActive)
 // Di sini kita pakai Hard Delete data user, gorm akan handle cascade jika disetting.
 func (r *UserRepository) Delete(id uuid.UUID) error {
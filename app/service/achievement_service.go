@@ -1,8 +1,18 @@
 package service
 
 import (
+	"bufio"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -12,6 +22,8 @@ import (
 	postgreModel "reportachievement/app/model/postgre"
 	mongoRepo "reportachievement/app/repository/mongo"
 	postgreRepo "reportachievement/app/repository/postgre"
+	"reportachievement/metadata"
+	"reportachievement/storage"
 )
 
 type AchievementService struct {
@@ -19,6 +31,11 @@ type AchievementService struct {
 	lecturerRepo *postgreRepo.LecturerRepository
 	achRefRepo   *postgreRepo.AchievementRepository
 	achMongoRepo *mongoRepo.AchievementRepository
+	evidenceRepo *mongoRepo.EvidenceFileRepository
+	storage      storage.Storage
+	policy       EvidencePolicy
+	links        *FileLinker
+	processor    *EvidenceProcessor
 }
 
 func NewAchievementService(
@@ -26,12 +43,22 @@ func NewAchievementService(
 	lecturerRepo *postgreRepo.LecturerRepository,
 	achRefRepo *postgreRepo.AchievementRepository,
 	achMongoRepo *mongoRepo.AchievementRepository,
+	evidenceRepo *mongoRepo.EvidenceFileRepository,
+	fileStorage storage.Storage,
+	policy EvidencePolicy,
+	links *FileLinker,
+	processor *EvidenceProcessor,
 ) *AchievementService {
 	return &AchievementService{
 		studentRepo:  studentRepo,
 		lecturerRepo: lecturerRepo,
 		achRefRepo:   achRefRepo,
 		achMongoRepo: achMongoRepo,
+		evidenceRepo: evidenceRepo,
+		storage:      fileStorage,
+		policy:       policy,
+		links:        links,
+		processor:    processor,
 	}
 }
 
@@ -44,21 +71,33 @@ type CreateAchievementRequest struct {
 }
 
 type AchievementListResponse struct {
-	ID          uuid.UUID              `json:"id"`
-	Status      string                 `json:"status"`
-	StudentName string                 `json:"student_name"`
-	NIM         string                 `json:"nim"`
-	Title       string                 `json:"title"`
-	Type        string                 `json:"type"`
-	Points      int                    `json:"points"`
-	Details     map[string]interface{} `json:"details"`
-	CreatedAt   string                 `json:"created_at"`
+	ID          uuid.UUID               `json:"id"`
+	Status      string                  `json:"status"`
+	StudentName string                  `json:"student_name"`
+	NIM         string                  `json:"nim"`
+	Title       string                  `json:"title"`
+	Type        string                  `json:"type"`
+	Points      int                     `json:"points"`
+	Details     map[string]interface{}  `json:"details"`
+	Attachments []mongoModel.Attachment `json:"attachments"`
+	CreatedAt   string                  `json:"created_at"`
 }
 
 type AttachmentDTO struct {
+	ID       string
 	FileName string
 	FileURL  string
 	FileType string
+	Size     int64
+	Checksum string
+}
+
+// Input upload evidence dari handler (multipart)
+type EvidenceFile struct {
+	FileName    string
+	ContentType string
+	Size        int64
+	Content     io.Reader
 }
 
 // --- METHODS ---
@@ -103,11 +142,13 @@ func (s *AchievementService) Create(ctx context.Context, userID uuid.UUID, req C
 	return pgData, nil
 }
 
-// 2.  Role Based Filtering di GetAll
-func (s *AchievementService) GetAll(ctx context.Context, userID uuid.UUID, userRole string, filter postgreRepo.AchievementFilter) ([]AchievementListResponse, int64, error) {
+// 2.  Filtering di GetAll berdasarkan cakupan permission
+func (s *AchievementService) GetAll(ctx context.Context, userID uuid.UUID, scope AccessScope, filter postgreRepo.AchievementFilter) ([]AchievementListResponse, int64, error) {
 
-	// --- LOGIKA FILTER BERDASARKAN ROLE ---
-	if userRole == "Mahasiswa" {
+	// --- LOGIKA FILTER BERDASARKAN CAKUPAN ---
+	if scope == ScopeNone {
+		return nil, 0, ErrAccessDenied
+	} else if scope == ScopeOwn {
 		// Mahasiswa HANYA boleh lihat prestasi miliknya sendiri
 		student, err := s.studentRepo.FindByUserID(userID)
 		if err != nil {
@@ -116,7 +157,7 @@ func (s *AchievementService) GetAll(ctx context.Context, userID uuid.UUID, userR
 		// Paksa filter hanya ID mahasiswa ini
 		filter.StudentIDs = []uuid.UUID{student.ID}
 
-	} else if userRole == "Dosen Wali" {
+	} else if scope == ScopeAdvisee {
 		// Dosen HANYA boleh lihat prestasi mahasiswa bimbingannya
 		lecturer, err := s.lecturerRepo.FindByUserID(userID)
 		if err != nil {
@@ -137,7 +178,7 @@ func (s *AchievementService) GetAll(ctx context.Context, userID uuid.UUID, userR
 		// Paksa filter ID mahasiswa bimbingan
 		filter.StudentIDs = studentIDs
 	}
-	// Jika Admin, tidak ada filter tambahan (lihat semua)
+	// ScopeAll (Admin), tidak ada filter tambahan (lihat semua)
 
 	// --- QUERY DATABASE ---
 	pgData, total, err := s.achRefRepo.FindAll(filter)
@@ -188,6 +229,7 @@ func (s *AchievementService) GetAll(ctx context.Context, userID uuid.UUID, userR
 			res.Type = mongoDetail.AchievementType
 			res.Points = mongoDetail.Points
 			res.Details = mongoDetail.Details
+			res.Attachments = mongoDetail.Attachments
 		} else {
 			res.Title = "[Deleted or Missing]"
 		}
@@ -238,6 +280,17 @@ func (s *AchievementService) Submit(ctx context.Context, userID uuid.UUID, achie
 	if ach.Status != "draft" {
 		return errors.New("only draft achievement can be submitted")
 	}
+	docs, err := s.achMongoRepo.FindByIDs(ctx, []string{ach.MongoAchievementID})
+	if err != nil {
+		return err
+	}
+	for _, doc := range docs {
+		for _, att := range doc.Attachments {
+			if att.Status == AttachmentRejected {
+				return errors.New("remove rejected attachment before submitting: " + att.FileName + " (" + att.RejectionReason + ")")
+			}
+		}
+	}
 	now := time.Now()
 	updateData := map[string]interface{}{"status": "submitted", "submitted_at": &now}
 	return s.achRefRepo.VerifyOrReject(ach.ID, updateData)
@@ -289,21 +342,391 @@ func (s *AchievementService) Reject(ctx context.Context, lecturerUserID uuid.UUI
 	return s.achRefRepo.VerifyOrReject(ach.ID, updateData)
 }
 
-func (s *AchievementService) UploadEvidence(ctx context.Context, userID uuid.UUID, achievementID uuid.UUID, file AttachmentDTO) error {
+func (s *AchievementService) UploadEvidence(ctx context.Context, userID uuid.UUID, achievementID uuid.UUID, file EvidenceFile) (*AttachmentDTO, error) {
+	ach, err := s.findEditableAchievement(userID, achievementID, "upload evidence")
+	if err != nil {
+		return nil, err
+	}
+
+	attachment, err := s.storeEvidence(ctx, userID, file)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.achMongoRepo.AddAttachment(ctx, ach.MongoAchievementID, *attachment); err != nil {
+		// Rollback referensi file agar tidak jadi sampah di storage
+		s.releaseEvidence(ctx, attachment)
+		return nil, err
+	}
+	s.enqueueProcessing(attachment)
+	return toAttachmentDTO(attachment), nil
+}
+
+// Hapus satu attachment (hanya saat draft/rejected), file di storage ikut dihapus jika tidak dipakai lagi
+func (s *AchievementService) DeleteAttachment(ctx context.Context, userID uuid.UUID, achievementID uuid.UUID, attachmentID string) error {
+	ach, err := s.findEditableAchievement(userID, achievementID, "delete evidence")
+	if err != nil {
+		return err
+	}
+	old, err := s.findAttachmentByID(ctx, ach.MongoAchievementID, attachmentID)
+	if err != nil {
+		return err
+	}
+	if err := s.achMongoRepo.RemoveAttachment(ctx, ach.MongoAchievementID, old.ID); err != nil {
+		return err
+	}
+	s.releaseEvidence(ctx, old)
+	return nil
+}
+
+// Ganti file satu attachment (ID attachment tetap), referensi file lama dilepas
+func (s *AchievementService) ReplaceAttachment(ctx context.Context, userID uuid.UUID, achievementID uuid.UUID, attachmentID string, file EvidenceFile) (*AttachmentDTO, error) {
+	ach, err := s.findEditableAchievement(userID, achievementID, "replace evidence")
+	if err != nil {
+		return nil, err
+	}
+	old, err := s.findAttachmentByID(ctx, ach.MongoAchievementID, attachmentID)
+	if err != nil {
+		return nil, err
+	}
+
+	attachment, err := s.storeEvidence(ctx, userID, file)
+	if err != nil {
+		return nil, err
+	}
+	attachment.ID = old.ID
+	if err := s.achMongoRepo.ReplaceAttachment(ctx, ach.MongoAchievementID, *attachment); err != nil {
+		s.releaseEvidence(ctx, attachment)
+		return nil, err
+	}
+	s.releaseEvidence(ctx, old)
+	s.enqueueProcessing(attachment)
+	return toAttachmentDTO(attachment), nil
+}
+
+// findEditableAchievement: prestasi milik mahasiswa ini & masih draft/rejected
+func (s *AchievementService) findEditableAchievement(userID uuid.UUID, achievementID uuid.UUID, action string) (*postgreModel.AchievementReference, error) {
 	ach, err := s.achRefRepo.FindByID(achievementID)
 	if err != nil {
-		return errors.New("achievement not found")
+		return nil, errors.New("achievement not found")
 	}
 	student, err := s.studentRepo.FindByUserID(userID)
 	if err != nil {
-		return errors.New("student profile not found")
+		return nil, errors.New("student profile not found")
 	}
 	if ach.StudentID != student.ID {
-		return errors.New("unauthorized action")
+		return nil, errors.New("unauthorized action")
 	}
 	if ach.Status != "draft" && ach.Status != "rejected" {
-		return errors.New("cannot upload evidence for status: " + ach.Status)
+		return nil, errors.New("cannot " + action + " for status: " + ach.Status)
+	}
+	return ach, nil
+}
+
+func (s *AchievementService) findAttachmentByID(ctx context.Context, mongoID string, attachmentID string) (*mongoModel.Attachment, error) {
+	objID, err := primitive.ObjectIDFromHex(attachmentID)
+	if err != nil {
+		return nil, ErrAttachmentNotFound
+	}
+	docs, err := s.achMongoRepo.FindByIDs(ctx, []string{mongoID})
+	if err != nil || len(docs) == 0 {
+		return nil, ErrAttachmentNotFound
+	}
+	for i := range docs[0].Attachments {
+		if docs[0].Attachments[i].ID == objID {
+			return &docs[0].Attachments[i], nil
+		}
+	}
+	return nil, ErrAttachmentNotFound
+}
+
+// storeEvidence: validasi isi file, hitung SHA-256, lalu simpan secara content-addressed.
+// Isi yang sudah pernah diupload (oleh siapa pun) tidak disimpan ulang, cukup tambah referensi.
+func (s *AchievementService) storeEvidence(ctx context.Context, userID uuid.UUID, file EvidenceFile) (*mongoModel.Attachment, error) {
+	file, err := s.policy.Validate(file)
+	if err != nil {
+		return nil, err
+	}
+
+	// Tampung dulu ke file sementara karena key baru diketahui setelah hash selesai dihitung
+	tmp, err := os.CreateTemp("", "evidence-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	// Metadata gambar (EXIF/XMP, termasuk lokasi GPS) dibuang sebelum hash dihitung,
+	// sehingga yang tersimpan & checksum selalu versi yang sudah bersih
+	hasher := sha256.New()
+	out := &countingWriter{w: io.MultiWriter(tmp, hasher)}
+	if err := metadata.StripImage(file.ContentType, file.Content, out); err != nil {
+		if errors.Is(err, ErrFileTooLarge) {
+			return nil, ErrFileTooLarge
+		}
+		return nil, errors.New("failed to read file: " + err.Error())
+	}
+	size := out.n
+	hash := hex.EncodeToString(hasher.Sum(nil))
+	key := evidenceKey(hash)
+
+	// Isi file yang sama sudah pernah terdeteksi malware: langsung tolak
+	if known, err := s.evidenceRepo.FindByHash(ctx, hash); err == nil && known.ScanStatus == AttachmentRejected {
+		return nil, fmt.Errorf("%w: %s", ErrEvidenceRejected, known.ScanMessage)
+	}
+
+	// Referensi diambil dulu agar file tidak dihapus Release/GC di sela-sela penyimpanan
+	created, err := s.evidenceRepo.Acquire(ctx, mongoModel.EvidenceFile{Hash: hash, StorageKey: key, ContentType: file.ContentType, Size: size})
+	if err != nil {
+		return nil, err
+	}
+	// Upload isi yang sama bersamaan: file belum tentu sudah (berhasil) ditulis pengupload pertama,
+	// jadi pastikan ada sebelum attachment dibuat. Put idempotent untuk isi yang sama.
+	if created || !s.storedFileExists(ctx, key) {
+		if err := s.putEvidence(ctx, key, tmp, size, file.ContentType); err != nil {
+			if remaining, releaseErr := s.evidenceRepo.Release(ctx, hash); releaseErr == nil && remaining == 0 {
+				s.deleteStoredFile(ctx, key)
+			}
+			return nil, errors.New("failed to store file: " + err.Error())
+		}
+	}
+
+	return &mongoModel.Attachment{
+		ID:         primitive.NewObjectID(),
+		FileName:   file.FileName,
+		FileURL:    s.links.URL(key),
+		FileType:   file.ContentType,
+		StorageKey: key,
+		Size:       size,
+		Checksum:   hash,
+		UploadedBy: userID.String(),
+		UploadedAt: time.Now(),
+		Status:     AttachmentPending, // diperbarui EvidenceProcessor setelah scan
+	}, nil
+}
+
+// releaseEvidence: lepas referensi attachment ke file; file dihapus dari storage
+// jika tidak ada lagi attachment yang memakainya (atau file lama non content-addressed).
+func (s *AchievementService) releaseEvidence(ctx context.Context, attachment *mongoModel.Attachment) {
+	if attachment.Checksum != "" && attachment.StorageKey == evidenceKey(attachment.Checksum) {
+		remaining, err := s.evidenceRepo.Release(ctx, attachment.Checksum)
+		if err != nil {
+			log.Println("⚠️ Gagal release evidence:", attachment.Checksum, err)
+			return
+		}
+		if remaining > 0 {
+			return
+		}
+		s.deleteStoredFile(ctx, thumbnailKey(attachment.Checksum))
+	}
+	s.deleteStoredFile(ctx, attachment.StorageKey)
+}
+
+// enqueueProcessing: thumbnail/preview dibuat di background setelah attachment tersimpan
+func (s *AchievementService) enqueueProcessing(attachment *mongoModel.Attachment) {
+	if s.processor == nil {
+		return
+	}
+	s.processor.Enqueue(EvidenceJob{Hash: attachment.Checksum, StorageKey: attachment.StorageKey, ContentType: attachment.FileType})
+}
+
+func (s *AchievementService) putEvidence(ctx context.Context, key string, tmp *os.File, size int64, contentType string) error {
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	return s.storage.Put(ctx, key, tmp, size, contentType)
+}
+
+// storedFileExists: error selain ErrNotFound dianggap ada, Put ulang tidak memperbaiki storage yang bermasalah
+func (s *AchievementService) storedFileExists(ctx context.Context, key string) bool {
+	r, err := s.storage.Get(ctx, key)
+	if err != nil {
+		return !errors.Is(err, storage.ErrNotFound)
+	}
+	r.Close()
+	return true
+}
+
+func (s *AchievementService) deleteStoredFile(ctx context.Context, key string) {
+	if key == "" {
+		return
+	}
+	if err := s.storage.Delete(ctx, key); err != nil {
+		log.Println("⚠️ Gagal hapus file evidence:", key, err)
+	}
+}
+
+func toAttachmentDTO(a *mongoModel.Attachment) *AttachmentDTO {
+	return &AttachmentDTO{
+		ID:       a.ID.Hex(),
+		FileName: a.FileName,
+		FileURL:  a.FileURL,
+		FileType: a.FileType,
+		Size:     a.Size,
+		Checksum: a.Checksum,
+	}
+}
+
+// --- DOWNLOAD EVIDENCE ---
+
+var (
+	ErrAttachmentNotFound = errors.New("attachment not found")
+	ErrAccessDenied       = errors.New("access denied: you may not view this attachment")
+	ErrAttachmentPending  = errors.New("attachment is still being scanned, try again shortly")
+	ErrEvidenceRejected   = errors.New("evidence rejected")
+)
+
+// File evidence yang siap dikirim ke client
+type AttachmentFile struct {
+	Attachment mongoModel.Attachment
+	Content    io.ReadCloser
+}
+
+// Sniff: tipe file dari isinya, bukan FileType tersimpan (attachment lama memakai Content-Type dari client).
+// inline = tipe ada di allowlist evidence sehingga aman ditampilkan di browser.
+// Content diganti reader yang sudah di-peek, tetap utuh untuk dikirim.
+func (f *AttachmentFile) Sniff() (contentType string, inline bool) {
+	br := bufio.NewReaderSize(f.Content, 512)
+	head, _ := br.Peek(512)
+	f.Content = struct {
+		io.Reader
+		io.Closer
+	}{br, f.Content}
+
+	contentType, _, _ = strings.Cut(http.DetectContentType(head), ";")
+	_, inline = evidenceExtensions[contentType]
+	return contentType, inline
+}
+
+// OpenAttachment: download oleh user login (pemilik, dosen wali, atau admin)
+func (s *AchievementService) OpenAttachment(ctx context.Context, userID uuid.UUID, scope AccessScope, key string) (*AttachmentFile, error) {
+	attachment, err := s.authorizeAttachment(ctx, userID, scope, key)
+	if err != nil {
+		return nil, err
+	}
+	return s.openAttachment(ctx, attachment)
+}
+
+// OpenSignedAttachment: download via signed URL (tanpa token)
+func (s *AchievementService) OpenSignedAttachment(ctx context.Context, key, expires, signature string) (*AttachmentFile, error) {
+	if err := s.links.Verify(key, expires, signature); err != nil {
+		return nil, err
+	}
+	docs, _, err := s.findAttachment(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	// Signed URL yang terbit sebelum prestasi dihapus ikut tidak berlaku
+	for _, doc := range docs {
+		ach, err := s.achRefRepo.FindByMongoID(doc.ID.Hex())
+		if err != nil || ach.Status == "deleted" {
+			continue
+		}
+		if attachment := attachmentForKey(doc, key); attachment != nil {
+			return s.openAttachment(ctx, attachment)
+		}
+	}
+	return nil, ErrAttachmentNotFound
+}
+
+// SignAttachmentURL: buat signed URL berumur pendek untuk user yang berhak melihat file
+func (s *AchievementService) SignAttachmentURL(ctx context.Context, userID uuid.UUID, scope AccessScope, key string) (string, time.Time, error) {
+	if _, err := s.authorizeAttachment(ctx, userID, scope, key); err != nil {
+		return "", time.Time{}, err
+	}
+	signedURL, expiresAt := s.links.SignedURL(key)
+	return signedURL, expiresAt, nil
+}
+
+// MigrateLegacyAttachments: lengkapi attachment lama (URL /uploads static, tanpa ID)
+func (s *AchievementService) MigrateLegacyAttachments(ctx context.Context) (int, error) {
+	withKeys, err := s.achMongoRepo.BackfillStorageKeys(ctx, s.links.URL)
+	if err != nil {
+		return withKeys, err
+	}
+	withIDs, err := s.achMongoRepo.BackfillAttachmentIDs(ctx)
+	return withKeys + withIDs, err
+}
+
+// findAttachment: semua prestasi yang memakai file ini (isi identik bisa dipakai beberapa prestasi)
+func (s *AchievementService) findAttachment(ctx context.Context, key string) ([]mongoModel.Achievement, *mongoModel.Attachment, error) {
+	docs, err := s.achMongoRepo.FindByAttachmentKey(ctx, key)
+	if err != nil || len(docs) == 0 {
+		return nil, nil, ErrAttachmentNotFound
+	}
+	if attachment := attachmentForKey(docs[0], key); attachment != nil {
+		return docs, attachment, nil
+	}
+	return nil, nil, ErrAttachmentNotFound
+}
+
+// attachmentForKey: metadata file untuk key tersebut, baik file asli maupun thumbnail-nya
+func attachmentForKey(doc mongoModel.Achievement, key string) *mongoModel.Attachment {
+	for _, att := range doc.Attachments {
+		if att.StorageKey == key {
+			return &att
+		}
+		if att.ThumbnailKey != "" && att.ThumbnailKey == key {
+			name := strings.TrimSuffix(att.FileName, filepath.Ext(att.FileName))
+			return &mongoModel.Attachment{ID: att.ID, FileName: name + "_thumbnail.jpg", FileType: "image/jpeg", StorageKey: key}
+		}
+	}
+	return nil
+}
+
+// authorizeAttachment: boleh jika user berhak atas salah satu prestasi yang memakai file ini
+func (s *AchievementService) authorizeAttachment(ctx context.Context, userID uuid.UUID, scope AccessScope, key string) (*mongoModel.Attachment, error) {
+	docs, _, err := s.findAttachment(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	found := false
+	for _, doc := range docs {
+		ach, err := s.achRefRepo.FindByMongoID(doc.ID.Hex())
+		if err != nil || ach.Status == "deleted" {
+			continue
+		}
+		found = true
+		if s.canViewStudent(userID, scope, &ach.Student) {
+			// Kembalikan metadata attachment milik prestasi yang berhak dilihat (nama file bisa beda)
+			if attachment := attachmentForKey(doc, key); attachment != nil {
+				return attachment, nil
+			}
+		}
+	}
+	if !found {
+		return nil, ErrAttachmentNotFound
+	}
+	return nil, ErrAccessDenied
+}
+
+func (s *AchievementService) canViewStudent(userID uuid.UUID, scope AccessScope, student *postgreModel.Student) bool {
+	switch scope {
+	case ScopeAll:
+		return true
+	case ScopeOwn:
+		return student.UserID == userID
+	case ScopeAdvisee:
+		lecturer, err := s.lecturerRepo.FindByUserID(userID)
+		return err == nil && student.AdvisorID != nil && *student.AdvisorID == lecturer.ID
+	}
+	return false
+}
+
+func (s *AchievementService) openAttachment(ctx context.Context, attachment *mongoModel.Attachment) (*AttachmentFile, error) {
+	switch attachment.Status {
+	case AttachmentPending:
+		return nil, ErrAttachmentPending
+	case AttachmentRejected:
+		return nil, fmt.Errorf("%w: %s", ErrEvidenceRejected, attachment.RejectionReason)
+	}
+
+	content, err := s.storage.Get(ctx, attachment.StorageKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, ErrAttachmentNotFound
+		}
+		return nil, err
 	}
-	attachment := mongoModel.Attachment{FileName: file.FileName, FileURL: file.FileURL, FileType: file.FileType, UploadedAt: time.Now()}
-	return s.achMongoRepo.AddAttachment(ctx, ach.MongoAchievementID, attachment)
+	return &AttachmentFile{Attachment: *attachment, Content: content}, nil
 }
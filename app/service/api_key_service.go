@@ -0,0 +1,329 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"reportachievement/app/model/postgre"
+	repo "reportachievement/app/repository/postgre"
+	"reportachievement/config"
+
+	"github.com/google/uuid"
+)
+
+// APIKeyPrefix: awal setiap API key, membedakannya dari JWT di header Authorization
+const APIKeyPrefix = "prs_"
+
+const (
+	// Status key di-cache agar middleware tidak query DB di setiap request (pencabutan dari proses ini langsung berlaku)
+	apiKeyCacheTTL = 30 * time.Second
+	// last_used_at ditulis paling sering sekali per interval ini per key
+	apiKeyTouchInterval = time.Minute
+)
+
+var (
+	ErrServiceAccountNotFound = errors.New("service account not found")
+	ErrAPIKeyNotFound         = errors.New("API key not found or already revoked")
+	ErrAPIKeyScope            = errors.New("invalid API key scope")
+)
+
+// Permission yang tidak pernah boleh dipegang API key: administrasi akun & keamanan tetap butuh manusia
+var forbiddenAPIKeyScopes = map[string]bool{
+	"user:manage":            true,
+	"role:manage":            true,
+	"auth:manage_keys":       true,
+	"auth:require_2fa":       true,
+	"service_account:manage": true,
+}
+
+type APIKeyService struct {
+	repo          *repo.APIKeyRepository
+	permRepo      *repo.PermissionRepository
+	defaultScopes []string
+
+	mu    sync.Mutex
+	cache map[string]apiKeyCacheEntry // key hash -> status
+}
+
+type apiKeyCacheEntry struct {
+	keyID      uuid.UUID
+	accountID  uuid.UUID
+	scopes     []string
+	active     bool
+	expiresAt  *time.Time
+	lastUsedAt *time.Time
+	checkedAt  time.Time
+}
+
+func NewAPIKeyService(apiKeyRepo *repo.APIKeyRepository, permRepo *repo.PermissionRepository, cfg *config.Config) *APIKeyService {
+	return &APIKeyService{
+		repo:          apiKeyRepo,
+		permRepo:      permRepo,
+		defaultScopes: strings.Fields(cfg.APIKeyDefaultScopes),
+		cache:         make(map[string]apiKeyCacheEntry),
+	}
+}
+
+// DTO: Input Create/Update Service Account
+type ServiceAccountRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	IsActive    *bool  `json:"is_active,omitempty"` // hanya dipakai saat update
+}
+
+// DTO: Input Create API Key
+type CreateAPIKeyRequest struct {
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes,omitempty"`          // kosong = scope default (read-only)
+	AllowWrite    bool     `json:"allow_write,omitempty"`     // wajib true untuk scope selain read
+	ExpiresInDays int      `json:"expires_in_days,omitempty"` // 0 = tidak kedaluwarsa
+}
+
+type APIKeyResponse struct {
+	ID         uuid.UUID  `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	LastUsedIP string     `json:"last_used_ip"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// CreatedAPIKey: key utuh hanya ada di response pembuatan
+type CreatedAPIKey struct {
+	APIKeyResponse
+	Key string `json:"key"`
+}
+
+// 1. CreateAccount
+func (s *APIKeyService) CreateAccount(req ServiceAccountRequest, createdBy uuid.UUID) (*postgre.ServiceAccount, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > 100 {
+		return nil, errors.New("service account name is required (max 100 characters)")
+	}
+	account := &postgre.ServiceAccount{Name: name, Description: req.Description, IsActive: true, CreatedBy: &createdBy}
+	if err := s.repo.CreateAccount(account); err != nil {
+		return nil, errors.New("service account already exists: " + name)
+	}
+	return account, nil
+}
+
+// 2. ListAccounts
+func (s *APIKeyService) ListAccounts() ([]postgre.ServiceAccount, error) {
+	return s.repo.FindAccounts()
+}
+
+// 3. UpdateAccount: deskripsi & status aktif (nonaktif = semua key langsung ditolak)
+func (s *APIKeyService) UpdateAccount(id uuid.UUID, req ServiceAccountRequest) error {
+	account, err := s.repo.FindAccountByID(id)
+	if err != nil {
+		return ErrServiceAccountNotFound
+	}
+	if req.Description != "" {
+		account.Description = req.Description
+	}
+	if req.IsActive != nil {
+		account.IsActive = *req.IsActive
+	}
+	if err := s.repo.UpdateAccount(account); err != nil {
+		return err
+	}
+	s.invalidate()
+	return nil
+}
+
+// 4. DeleteAccount beserta seluruh key-nya
+func (s *APIKeyService) DeleteAccount(id uuid.UUID) error {
+	if _, err := s.repo.FindAccountByID(id); err != nil {
+		return ErrServiceAccountNotFound
+	}
+	if err := s.repo.DeleteAccount(id); err != nil {
+		return err
+	}
+	s.invalidate()
+	return nil
+}
+
+// 5. CreateKey: key utuh dikembalikan sekali, setelah itu hanya prefix yang bisa dilihat
+func (s *APIKeyService) CreateKey(accountID uuid.UUID, req CreateAPIKeyRequest, createdBy uuid.UUID) (*CreatedAPIKey, error) {
+	if _, err := s.repo.FindAccountByID(accountID); err != nil {
+		return nil, ErrServiceAccountNotFound
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > 100 {
+		return nil, errors.New("API key name is required (max 100 characters)")
+	}
+	scopes, err := s.validateScopes(req.Scopes, req.AllowWrite)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, _, err := newOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	rawKey := APIKeyPrefix + secret
+	key := &postgre.APIKey{
+		ServiceAccountID: accountID,
+		Name:             name,
+		Prefix:           rawKey[:len(APIKeyPrefix)+8],
+		KeyHash:          hashToken(rawKey),
+		Scopes:           strings.Join(scopes, " "),
+		CreatedBy:        &createdBy,
+	}
+	if req.ExpiresInDays > 0 {
+		expiresAt := time.Now().AddDate(0, 0, req.ExpiresInDays)
+		key.ExpiresAt = &expiresAt
+	}
+	if err := s.repo.CreateKey(key); err != nil {
+		return nil, err
+	}
+	return &CreatedAPIKey{APIKeyResponse: toAPIKeyResponse(*key), Key: rawKey}, nil
+}
+
+// 6. ListKeys milik satu service account (tanpa key utuh)
+func (s *APIKeyService) ListKeys(accountID uuid.UUID) ([]APIKeyResponse, error) {
+	if _, err := s.repo.FindAccountByID(accountID); err != nil {
+		return nil, ErrServiceAccountNotFound
+	}
+	keys, err := s.repo.FindKeysByAccount(accountID)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]APIKeyResponse, len(keys))
+	for i, key := range keys {
+		result[i] = toAPIKeyResponse(key)
+	}
+	return result, nil
+}
+
+// 7. RevokeKey, berlaku langsung di proses ini (instance lain paling lambat setelah cache TTL)
+func (s *APIKeyService) RevokeKey(accountID, keyID uuid.UUID) error {
+	revoked, err := s.repo.RevokeKey(accountID, keyID)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return ErrAPIKeyNotFound
+	}
+	s.invalidate()
+	return nil
+}
+
+// Authenticate: dipakai middleware. subject = ID service account, scopes = permission key.
+func (s *APIKeyService) Authenticate(rawKey, ip string) (subject string, scopes []string, ok bool) {
+	if !strings.HasPrefix(rawKey, APIKeyPrefix) {
+		return "", nil, false
+	}
+	keyHash := hashToken(rawKey)
+	now := time.Now()
+
+	s.mu.Lock()
+	entry, cached := s.cache[keyHash]
+	s.mu.Unlock()
+	if !cached || now.Sub(entry.checkedAt) > apiKeyCacheTTL {
+		// Key yang tidak ada tidak di-cache, agar key acak tidak memenuhi memori
+		key, err := s.repo.FindKeyByHash(keyHash)
+		if err != nil {
+			return "", nil, false
+		}
+		entry = apiKeyCacheEntry{
+			keyID:      key.ID,
+			accountID:  key.ServiceAccountID,
+			scopes:     strings.Fields(key.Scopes),
+			active:     key.RevokedAt == nil && key.ServiceAccount.IsActive,
+			expiresAt:  key.ExpiresAt,
+			lastUsedAt: key.LastUsedAt,
+			checkedAt:  now,
+		}
+	}
+	if !entry.active || (entry.expiresAt != nil && now.After(*entry.expiresAt)) {
+		s.store(keyHash, entry)
+		return "", nil, false
+	}
+
+	if entry.lastUsedAt == nil || now.Sub(*entry.lastUsedAt) >= apiKeyTouchInterval {
+		if err := s.repo.TouchKey(entry.keyID, now, truncate(ip, 45)); err != nil {
+			log.Println("⚠️ Gagal mencatat pemakaian API key:", err)
+		} else {
+			entry.lastUsedAt = &now
+		}
+	}
+	s.store(keyHash, entry)
+	return entry.accountID.String(), entry.scopes, true
+}
+
+func (s *APIKeyService) store(keyHash string, entry apiKeyCacheEntry) {
+	s.mu.Lock()
+	s.cache[keyHash] = entry
+	s.mu.Unlock()
+}
+
+func (s *APIKeyService) invalidate() {
+	s.mu.Lock()
+	s.cache = make(map[string]apiKeyCacheEntry)
+	s.mu.Unlock()
+}
+
+// validateScopes: scope harus permission yang ada. Tanpa allow_write hanya permission baca (action read*).
+func (s *APIKeyService) validateScopes(requested []string, allowWrite bool) ([]string, error) {
+	if len(requested) == 0 {
+		requested = s.defaultScopes
+	}
+	permissions, err := s.permRepo.FindAllPermissions()
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[string]bool, len(permissions))
+	for _, p := range permissions {
+		known[p.Name] = true
+	}
+
+	var scopes []string
+	seen := map[string]bool{}
+	for _, scope := range requested {
+		scope = strings.TrimSpace(scope)
+		switch {
+		case seen[scope]:
+			continue
+		case !known[scope]:
+			return nil, fmt.Errorf("%w: unknown permission %s", ErrAPIKeyScope, scope)
+		case forbiddenAPIKeyScopes[scope]:
+			return nil, fmt.Errorf("%w: %s cannot be granted to an API key", ErrAPIKeyScope, scope)
+		case !allowWrite && !isReadPermission(scope):
+			return nil, fmt.Errorf("%w: %s is not read-only, set allow_write to grant it", ErrAPIKeyScope, scope)
+		}
+		seen[scope] = true
+		scopes = append(scopes, scope)
+	}
+	if len(scopes) == 0 {
+		return nil, fmt.Errorf("%w: at least one scope is required", ErrAPIKeyScope)
+	}
+	return scopes, nil
+}
+
+// isReadPermission: "resource:read..." (misal achievement:read_all, report:read)
+func isReadPermission(name string) bool {
+	_, action, _ := strings.Cut(name, ":")
+	return strings.HasPrefix(action, "read")
+}
+
+func toAPIKeyResponse(key postgre.APIKey) APIKeyResponse {
+	return APIKeyResponse{
+		ID:         key.ID,
+		Name:       key.Name,
+		Prefix:     key.Prefix,
+		Scopes:     strings.Fields(key.Scopes),
+		ExpiresAt:  key.ExpiresAt,
+		LastUsedAt: key.LastUsedAt,
+		LastUsedIP: key.LastUsedIP,
+		RevokedAt:  key.RevokedAt,
+		CreatedAt:  key.CreatedAt,
+	}
+}
@@ -0,0 +1,41 @@
+package service
+
+import (
+	"errors"
+
+	"reportachievement/app/model/postgre"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var ErrAuthProviderUnavailable = errors.New("authentication service is temporarily unavailable, please try again later")
+
+// AuthProvider: verifikasi password login untuk satu sumber akun (users.auth_source).
+// Lockout, throttling IP, 2FA dan session tetap diurus AuthService untuk semua provider.
+type AuthProvider interface {
+	Name() string
+	// Authenticate: user = nil jika username belum terdaftar (hanya untuk provider dengan provisioning).
+	// Password salah = ErrInvalidCredentials, server provider bermasalah = ErrAuthProviderUnavailable.
+	Authenticate(user *postgre.User, username, plainPassword string) (*postgre.User, error)
+}
+
+// localProvider: password bcrypt di tabel users
+type localProvider struct {
+	auth *AuthService
+}
+
+func (p localProvider) Name() string {
+	return postgre.AuthSourceLocal
+}
+
+func (p localProvider) Authenticate(user *postgre.User, username, plainPassword string) (*postgre.User, error) {
+	if user == nil {
+		return nil, ErrInvalidCredentials
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(plainPassword)); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	// Upgrade hash & tandai password lama yang tidak memenuhi policy
+	p.auth.upgradePassword(user, plainPassword)
+	return user, nil
+}
@@ -1,67 +1,466 @@
 package service
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
+	"log"
 	"reportachievement/app/model/postgre"
 	"reportachievement/app/repository" // Import Interface
 	"reportachievement/config"
+	"reportachievement/jwtkeys"
+	"reportachievement/password"
+	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
-	"golang.org/x/crypto/bcrypt"
 )
 
+var (
+	ErrInvalidCredentials  = errors.New("invalid username or password")
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected, session revoked")
+	ErrInvalidMFAChallenge = errors.New("invalid or expired 2FA challenge, please log in again")
+	ErrSessionNotFound     = errors.New("session not found")
+)
+
+// Langkah akun yang harus diselesaikan sebelum session bisa memakai API lain (middleware "code")
+const (
+	PendingPasswordChange = "password_change_required"
+	PendingMFAEnrollment  = "mfa_enrollment_required"
+)
+
+const (
+	// Role dengan permission ini wajib mengaktifkan 2FA
+	mfaRequiredPermission = "auth:require_2fa"
+	// Challenge token: jeda antara password benar dan kode 2FA
+	mfaChallengeTTL  = 5 * time.Minute
+	mfaChallengeType = "mfa_challenge"
+)
+
+// Status session di-cache sebentar agar middleware tidak query DB di setiap request.
+// Pencabutan dari proses ini langsung menghapus cache; dari instance lain berlaku paling lambat setelah TTL.
+const sessionCacheTTL = 30 * time.Second
+
+// last_used_at session ditulis paling sering sekali per interval ini (kolom "last seen" di daftar session)
+const sessionTouchInterval = time.Minute
+
 type AuthService struct {
-	userRepo repository.IUserRepository // Gunakan Interface
+	userRepo    repository.IUserRepository // Gunakan Interface
+	sessionRepo repository.ISessionRepository
+	keys        *jwtkeys.Manager
+	policy      *password.Policy
+	guard       *LoginGuard
+	permService *PermissionService
+	accessTTL   time.Duration
+	refreshTTL  time.Duration
+
+	// Provider per auth_source, provisioner dipakai untuk username yang belum terdaftar (boleh nil)
+	providers   map[string]AuthProvider
+	provisioner AuthProvider
+
+	cacheMu      sync.Mutex
+	sessionCache map[uuid.UUID]sessionCacheEntry
+}
+
+type sessionCacheEntry struct {
+	userID    uuid.UUID
+	role      string // role user saat ini (bukan dari claim token)
+	pending   string // PendingPasswordChange / PendingMFAEnrollment / kosong
+	active    bool
+	checkedAt time.Time
+}
+
+// Info perangkat saat login (ditampilkan di daftar session)
+type ClientInfo struct {
+	UserAgent string
+	IPAddress string
 }
 
 // Constructor terima Interface
-func NewAuthService(userRepo repository.IUserRepository) *AuthService {
-	return &AuthService{userRepo: userRepo}
+func NewAuthService(userRepo repository.IUserRepository, sessionRepo repository.ISessionRepository, keys *jwtkeys.Manager, policy *password.Policy, guard *LoginGuard, permService *PermissionService, cfg *config.Config) *AuthService {
+	s := &AuthService{
+		userRepo:    userRepo,
+		sessionRepo: sessionRepo,
+		keys:        keys,
+		policy:      policy,
+		guard:       guard,
+		permService: permService,
+		accessTTL:   cfg.AccessTokenTTL,
+		refreshTTL:  cfg.RefreshTokenTTL,
+
+		sessionCache: make(map[uuid.UUID]sessionCacheEntry),
+		providers:    make(map[string]AuthProvider),
+	}
+	s.RegisterProvider(localProvider{auth: s}, false)
+	return s
+}
+
+// RegisterProvider: tambah sumber akun (dipanggil saat startup).
+// provision = user yang belum terdaftar diautentikasi provider ini dan dibuat saat login pertama.
+func (s *AuthService) RegisterProvider(provider AuthProvider, provision bool) {
+	s.providers[provider.Name()] = provider
+	if provision {
+		s.provisioner = provider
+	}
+}
+
+func (s *AuthService) HasProvider(name string) bool {
+	_, ok := s.providers[name]
+	return ok
 }
 
-func (s *AuthService) Login(username, password string) (map[string]interface{}, error) {
-	// 1. Cari User
+// VerifyPassword: konfirmasi password untuk aksi sensitif (misal menonaktifkan 2FA)
+func (s *AuthService) VerifyPassword(user *postgre.User, plainPassword string) error {
+	provider := s.providerFor(user)
+	if provider == nil {
+		return ErrInvalidCredentials
+	}
+	_, err := provider.Authenticate(user, user.Username, plainPassword)
+	return err
+}
+
+func (s *AuthService) providerFor(user *postgre.User) AuthProvider {
+	if user == nil {
+		return s.provisioner
+	}
+	if user.AuthSource == "" {
+		return s.providers[postgre.AuthSourceLocal]
+	}
+	return s.providers[user.AuthSource]
+}
+
+func (s *AuthService) Login(username, plainPassword string, client ClientInfo) (map[string]interface{}, error) {
+	// 0. Batasi percobaan gagal per IP
+	if err := s.guard.CheckIP(client.IPAddress); err != nil {
+		s.guard.Failed(nil, username, client, postgre.LoginIPThrottled)
+		return nil, err
+	}
+
+	// 1. Cari User (belum terdaftar: hanya provider dengan provisioning yang bisa membuatnya)
 	user, err := s.userRepo.FindByUsername(username)
 	if err != nil {
-		return nil, errors.New("invalid username or password")
+		user = nil
 	}
 
-	// 2. Cek Password
-	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
-		return nil, errors.New("invalid username or password")
+	// 1a. Akun terkunci: password tidak dicek sama sekali
+	if user != nil {
+		if err := s.guard.CheckAccount(user); err != nil {
+			s.guard.Failed(user, username, client, postgre.LoginAccountLocked)
+			return nil, err
+		}
 	}
 
+	// 2. Cek Password lewat provider sesuai auth_source user
+	provider := s.providerFor(user)
+	if provider == nil {
+		s.guard.Failed(user, username, client, postgre.LoginInvalidCredentials)
+		return nil, ErrInvalidCredentials
+	}
+	authenticated, err := provider.Authenticate(user, username, plainPassword)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidCredentials):
+			s.guard.Failed(user, username, client, postgre.LoginInvalidCredentials)
+		case errors.Is(err, ErrAuthProviderUnavailable):
+			// Gangguan server provider tidak dihitung sebagai percobaan gagal
+		default:
+			s.guard.Failed(user, username, client, postgre.LoginAccountRejected)
+		}
+		return nil, err
+	}
+	user = authenticated
+
 	if !user.IsActive {
+		s.guard.Failed(user, username, client, postgre.LoginAccountInactive)
 		return nil, errors.New("account is inactive")
 	}
 
-	// 3. Generate JWT
-	cfg := config.LoadConfig()
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"user_id":  user.ID,
-		"username": user.Username,
-		"role":     user.Role.Name,
-		"exp":      time.Now().Add(24 * time.Hour).Unix(),
+	// 2a. 2FA aktif: JWT baru diterbitkan setelah kode diverifikasi (MFAService.CompleteLogin)
+	if user.TOTPEnabledAt != nil {
+		return s.mfaChallenge(user)
+	}
+
+	reason := postgre.LoginSuccess
+	if user.AuthSource == postgre.AuthSourceLDAP {
+		reason = postgre.LoginSuccessLDAP
+	}
+	s.guard.Succeeded(user, client, reason)
+	return s.newSession(user, client)
+}
+
+// newSession: buat session + refresh token pertama, lalu terbitkan access token
+func (s *AuthService) newSession(user *postgre.User, client ClientInfo) (map[string]interface{}, error) {
+	// 3. Buat session + refresh token pertama
+	refreshToken, refreshHash, err := newOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	session := &postgre.UserSession{
+		UserID:     user.ID,
+		UserAgent:  truncate(client.UserAgent, 255),
+		IPAddress:  truncate(client.IPAddress, 45),
+		LastUsedAt: now,
+		ExpiresAt:  now.Add(s.refreshTTL),
+	}
+	token := &postgre.RefreshToken{TokenHash: refreshHash, ExpiresAt: session.ExpiresAt}
+	if err := s.sessionRepo.Create(session, token); err != nil {
+		return nil, err
+	}
+
+	// 4. Generate JWT (access token berumur pendek)
+	return s.tokenResponse(user, session.ID, refreshToken)
+}
+
+// Refresh: tukar refresh token dengan pasangan token baru (rotasi).
+// Refresh token yang sudah pernah dirotasi lalu dipakai lagi mencabut seluruh session.
+func (s *AuthService) Refresh(refreshToken string) (map[string]interface{}, error) {
+	token, err := s.sessionRepo.FindRefreshToken(hashToken(refreshToken))
+	if err != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+	session := token.Session
+	if session.RevokedAt != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+	if token.UsedAt != nil {
+		s.RevokeSession(session.ID, "refresh token reuse")
+		return nil, ErrRefreshTokenReused
+	}
+	if time.Now().After(token.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	// Role & status diambil ulang agar perubahan oleh admin langsung berlaku
+	user, err := s.userRepo.FindByID(session.UserID)
+	if err != nil || !user.IsActive {
+		s.RevokeSession(session.ID, "account inactive")
+		return nil, ErrInvalidRefreshToken
+	}
+
+	newToken, newHash, err := newOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	rotated, err := s.sessionRepo.Rotate(token.ID, &postgre.RefreshToken{
+		SessionID: session.ID,
+		TokenHash: newHash,
+		ExpiresAt: time.Now().Add(s.refreshTTL),
 	})
+	if err != nil {
+		return nil, err
+	}
+	if !rotated {
+		// Token yang sama dipakai dua request bersamaan
+		s.RevokeSession(session.ID, "refresh token reuse")
+		return nil, ErrRefreshTokenReused
+	}
+
+	return s.tokenResponse(user, session.ID, newToken)
+}
+
+// Logout: cabut session milik access token yang sedang dipakai.
+// Access token & refresh token dari session ini langsung ditolak.
+func (s *AuthService) Logout(sessionID uuid.UUID) error {
+	return s.RevokeSession(sessionID, "logout")
+}
+
+// LogoutAll: cabut semua session user (logout dari semua perangkat)
+func (s *AuthService) LogoutAll(userID uuid.UUID) error {
+	return s.RevokeAllSessions(userID, "logout all")
+}
+
+// RevokeSession: cabut satu session dan buang status cache-nya
+func (s *AuthService) RevokeSession(sessionID uuid.UUID, reason string) error {
+	err := s.sessionRepo.Revoke(sessionID, reason)
+	s.cacheMu.Lock()
+	delete(s.sessionCache, sessionID)
+	s.cacheMu.Unlock()
+	return err
+}
+
+// RevokeAllSessions: dipanggil saat logout semua perangkat, akun dinonaktifkan atau dihapus
+func (s *AuthService) RevokeAllSessions(userID uuid.UUID, reason string) error {
+	err := s.sessionRepo.RevokeAllForUser(userID, reason)
+	s.InvalidateUserSessions(userID)
+	return err
+}
+
+// RevokeOtherSessions: cabut semua session user kecuali yang sedang dipakai
+func (s *AuthService) RevokeOtherSessions(userID, keepSessionID uuid.UUID, reason string) error {
+	err := s.sessionRepo.RevokeOthers(userID, keepSessionID, reason)
+	s.InvalidateUserSessions(userID)
+	return err
+}
+
+// SessionInfo: satu perangkat yang sedang login (GET /auth/sessions & admin)
+type SessionInfo struct {
+	ID         uuid.UUID `json:"id"`
+	Device     string    `json:"device"` // contoh: "Chrome on Windows"
+	UserAgent  string    `json:"user_agent"`
+	IPAddress  string    `json:"ip_address"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"` // session milik token yang sedang dipakai
+}
 
-	tokenString, err := token.SignedString([]byte(cfg.JWTSecret))
+// ListSessions: session aktif milik user, terbaru dipakai lebih dulu.
+// currentSessionID boleh uuid.Nil (tampilan admin).
+func (s *AuthService) ListSessions(userID, currentSessionID uuid.UUID) ([]SessionInfo, error) {
+	sessions, err := s.sessionRepo.FindActiveByUser(userID)
 	if err != nil {
 		return nil, err
 	}
+	result := make([]SessionInfo, 0, len(sessions))
+	for _, session := range sessions {
+		result = append(result, SessionInfo{
+			ID:         session.ID,
+			Device:     describeDevice(session.UserAgent),
+			UserAgent:  session.UserAgent,
+			IPAddress:  session.IPAddress,
+			CreatedAt:  session.CreatedAt,
+			LastSeenAt: session.LastUsedAt,
+			ExpiresAt:  session.ExpiresAt,
+			Current:    session.ID == currentSessionID,
+		})
+	}
+	return result, nil
+}
+
+// RevokeUserSession: cabut satu session milik userID (sign-out perangkat lain dari jarak jauh).
+// Session milik user lain atau yang sudah tidak aktif dianggap tidak ada.
+func (s *AuthService) RevokeUserSession(userID, sessionID uuid.UUID, reason string) error {
+	session, err := s.sessionRepo.FindByID(sessionID)
+	if err != nil || session.UserID != userID || session.RevokedAt != nil || time.Now().After(session.ExpiresAt) {
+		return ErrSessionNotFound
+	}
+	return s.RevokeSession(sessionID, reason)
+}
 
+// InvalidateUserSessions: buang cache session user, misal setelah role diganti
+// agar token yang sudah beredar langsung memakai role baru
+func (s *AuthService) InvalidateUserSessions(userID uuid.UUID) {
+	s.cacheMu.Lock()
+	for id, entry := range s.sessionCache {
+		if entry.userID == userID {
+			delete(s.sessionCache, id)
+		}
+	}
+	s.cacheMu.Unlock()
+}
+
+// InvalidateAllSessions: buang seluruh cache session (misal nama role diubah)
+func (s *AuthService) InvalidateAllSessions() {
+	s.cacheMu.Lock()
+	s.sessionCache = make(map[uuid.UUID]sessionCacheEntry)
+	s.cacheMu.Unlock()
+}
+
+// SessionStatus: dipakai middleware.Protected. Mengembalikan role user saat ini dan langkah akun
+// yang masih harus diselesaikan, ok=false jika session sudah dicabut/kedaluwarsa atau akun nonaktif.
+func (s *AuthService) SessionStatus(sessionID string) (role string, pending string, ok bool) {
+	id, err := uuid.Parse(sessionID)
+	if err != nil {
+		return "", "", false
+	}
+
+	now := time.Now()
+	s.cacheMu.Lock()
+	entry, cached := s.sessionCache[id]
+	s.cacheMu.Unlock()
+	if cached && now.Sub(entry.checkedAt) < sessionCacheTTL {
+		return entry.role, entry.pending, entry.active
+	}
+
+	session, err := s.sessionRepo.FindByID(id)
+	if err != nil {
+		return "", "", false // tidak ditemukan (user dihapus) atau DB error: tolak
+	}
+	entry = sessionCacheEntry{
+		userID:    session.UserID,
+		role:      session.User.Role.Name,
+		pending:   s.pendingAction(&session.User),
+		active:    session.RevokedAt == nil && now.Before(session.ExpiresAt) && session.User.IsActive,
+		checkedAt: now,
+	}
+	// Cache habis paling cepat tiap TTL, jadi last_used_at tidak ditulis di setiap request
+	if entry.active && now.Sub(session.LastUsedAt) >= sessionTouchInterval {
+		if err := s.sessionRepo.Touch(id, now); err != nil {
+			log.Println("⚠️ Gagal update last_used_at session:", err)
+		}
+	}
+
+	s.cacheMu.Lock()
+	// Buang entry kedaluwarsa agar map tidak tumbuh terus
+	for k, v := range s.sessionCache {
+		if now.Sub(v.checkedAt) >= sessionCacheTTL {
+			delete(s.sessionCache, k)
+		}
+	}
+	s.sessionCache[id] = entry
+	s.cacheMu.Unlock()
+	return entry.role, entry.pending, entry.active
+}
+
+// MFARequired: role wajib memakai 2FA
+func (s *AuthService) MFARequired(role string) bool {
+	return s.permService != nil && s.permService.HasPermission(role, mfaRequiredPermission)
+}
+
+// pendingAction: ganti password didahulukan, lalu aktivasi 2FA jika diwajibkan role
+func (s *AuthService) pendingAction(user *postgre.User) string {
+	// Password akun LDAP tidak bisa diganti di aplikasi ini
+	if user.MustChangePassword && !isExternalAccount(user) {
+		return PendingPasswordChange
+	}
+	if user.TOTPEnabledAt == nil && s.MFARequired(user.Role.Name) {
+		return PendingMFAEnrollment
+	}
+	return ""
+}
+
+// mfaChallenge: token berumur pendek yang hanya bisa ditukar lewat CompleteLogin (bukan access token)
+func (s *AuthService) mfaChallenge(user *postgre.User) (map[string]interface{}, error) {
+	token, err := s.keys.Sign(jwt.MapClaims{
+		"sub": user.ID.String(),
+		"typ": mfaChallengeType,
+		"iat": time.Now().Unix(),
+		"exp": time.Now().Add(mfaChallengeTTL).Unix(),
+	})
+	if err != nil {
+		return nil, err
+	}
 	return map[string]interface{}{
-		"token": tokenString,
-		"user": map[string]interface{}{
-			"id":       user.ID,
-			"username": user.Username,
-			"role":     user.Role.Name,
-			"fullName": user.FullName,
-		},
+		"mfa_required":    true,
+		"challenge_token": token,
+		"expires_in":      int(mfaChallengeTTL.Seconds()),
 	}, nil
 }
 
+// parseMFAChallenge: ambil user ID dari challenge token
+func (s *AuthService) parseMFAChallenge(token string) (uuid.UUID, error) {
+	claims, err := s.keys.Parse(token)
+	if err != nil {
+		return uuid.Nil, ErrInvalidMFAChallenge
+	}
+	if typ, _ := claims["typ"].(string); typ != mfaChallengeType {
+		return uuid.Nil, ErrInvalidMFAChallenge
+	}
+	sub, _ := claims["sub"].(string)
+	userID, err := uuid.Parse(sub)
+	if err != nil {
+		return uuid.Nil, ErrInvalidMFAChallenge
+	}
+	return userID, nil
+}
+
 // Get Profile ---
 func (s *AuthService) GetProfile(userID uuid.UUID) (*postgre.User, error) {
 	user, err := s.userRepo.FindByID(userID)
@@ -72,3 +471,91 @@ func (s *AuthService) GetProfile(userID uuid.UUID) (*postgre.User, error) {
 	user.PasswordHash = ""
 	return user, nil
 }
+
+func (s *AuthService) tokenResponse(user *postgre.User, sessionID uuid.UUID, refreshToken string) (map[string]interface{}, error) {
+	tokenString, err := s.keys.Sign(jwt.MapClaims{
+		"user_id":  user.ID,
+		"username": user.Username,
+		"role":     user.Role.Name,
+		"sid":      sessionID,
+		"iat":      time.Now().Unix(),
+		"exp":      time.Now().Add(s.accessTTL).Unix(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"token":         tokenString,
+		"token_type":    "Bearer",
+		"expires_in":    int(s.accessTTL.Seconds()),
+		"refresh_token": refreshToken,
+		"user": map[string]interface{}{
+			"id":       user.ID,
+			"username": user.Username,
+			"role":     user.Role.Name,
+			"fullName": user.FullName,
+		},
+		// Client mengarahkan ke halaman ganti password / setup 2FA, API lain ditolak sampai selesai
+		"must_change_password":    user.MustChangePassword && !isExternalAccount(user),
+		"mfa_enrollment_required": user.TOTPEnabledAt == nil && s.MFARequired(user.Role.Name),
+	}, nil
+}
+
+// upgradePassword: dipanggil setelah password login terverifikasi.
+// Hash dengan bcrypt cost lama di-hash ulang, password yang tidak lagi memenuhi policy
+// (misal ada di blocklist) membuat user wajib menggantinya.
+func (s *AuthService) upgradePassword(user *postgre.User, plainPassword string) {
+	if s.policy == nil {
+		return
+	}
+	hash := user.PasswordHash
+	if s.policy.NeedsRehash(hash) {
+		if rehashed, err := s.policy.Hash(plainPassword); err == nil {
+			hash = rehashed
+		}
+	}
+	mustChange := user.MustChangePassword || s.policy.Validate(plainPassword, user.Username, user.Email) != nil
+	if hash == user.PasswordHash && mustChange == user.MustChangePassword {
+		return
+	}
+
+	// Gagal menyimpan tidak menggagalkan login, dicoba lagi di login berikutnya
+	if err := s.userRepo.UpdatePassword(user.ID, hash, mustChange); err != nil {
+		log.Println("⚠️ Gagal memperbarui hash password", user.Username, err)
+		return
+	}
+	if mustChange != user.MustChangePassword {
+		s.InvalidateUserSessions(user.ID)
+	}
+	user.PasswordHash = hash
+	user.MustChangePassword = mustChange
+}
+
+// newOpaqueToken: token acak (dikirim ke client) dan hash-nya (disimpan di DB), untuk refresh token & reset password
+func newOpaqueToken() (string, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	return token, hashToken(token), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// truncate: potong maksimal max byte di batas karakter, byte UTF-8 tidak valid (misal dari
+// header User-Agent) diganti karena Postgres menolak "invalid byte sequence"
+func truncate(s string, max int) string {
+	s = strings.ToValidUTF8(s, "\uFFFD")
+	if len(s) <= max {
+		return s
+	}
+	for max > 0 && !utf8.RuneStart(s[max]) {
+		max--
+	}
+	return s[:max]
+}
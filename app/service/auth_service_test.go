@@ -0,0 +1,37 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		max  int
+		want string
+	}{
+		{"lebih pendek", "budi", 10, "budi"},
+		{"ascii dipotong", "budi santoso", 4, "budi"},
+		{"tidak memotong karakter 2 byte", "ñandú", 2, "ñ"},
+		{"tidak memotong karakter 3 byte", "日本語", 4, "日"},
+		{"tidak memotong emoji", "ok😀", 5, "ok"},
+		{"tepat di batas karakter", "日本語", 6, "日本"},
+		{"byte tidak valid diganti", "Mozilla\xff", 20, "Mozilla�"},
+		{"max nol", "日本", 0, ""},
+	}
+	for _, tt := range tests {
+		got := truncate(tt.in, tt.max)
+		assert.Equal(t, tt.want, got, tt.name)
+		assert.True(t, utf8.ValidString(got), tt.name)
+		assert.LessOrEqual(t, len(got), tt.max, tt.name)
+	}
+
+	// User-Agent panjang dengan karakter multi-byte di sekitar batas 255 byte
+	ua := strings.Repeat("a", 254) + "é" + strings.Repeat("b", 10)
+	assert.Equal(t, strings.Repeat("a", 254), truncate(ua, 255))
+}
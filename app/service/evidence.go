@@ -0,0 +1,163 @@
+package service
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"reportachievement/config"
+)
+
+// Error validasi upload (dipetakan ke 4xx oleh handler)
+var (
+	ErrEmptyFile           = errors.New("file is empty")
+	ErrFileTooLarge        = errors.New("file exceeds the maximum allowed size")
+	ErrUnsupportedFileType = errors.New("unsupported file type, allowed: PDF, JPEG, PNG, MP4")
+)
+
+// EvidencePolicy: allowlist tipe file (hasil sniffing) beserta batas ukurannya
+type EvidencePolicy struct {
+	MaxSizes map[string]int64 // MIME type -> ukuran maksimum (byte)
+}
+
+// Ekstensi file tersimpan mengikuti hasil sniffing, bukan nama dari client
+var evidenceExtensions = map[string]string{
+	"application/pdf": ".pdf",
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"video/mp4":       ".mp4",
+}
+
+func NewEvidencePolicy(cfg *config.Config) EvidencePolicy {
+	return EvidencePolicy{
+		MaxSizes: map[string]int64{
+			"application/pdf": cfg.UploadMaxPDFSize,
+			"image/jpeg":      cfg.UploadMaxImageSize,
+			"image/png":       cfg.UploadMaxImageSize,
+			"video/mp4":       cfg.UploadMaxVideoSize, // praktis hanya lewat resumable upload (BodyLimit)
+		},
+	}
+}
+
+// MaxSize: ukuran terbesar yang diizinkan dari semua tipe (untuk Tus-Max-Size)
+func (p EvidencePolicy) MaxSize() int64 {
+	var largest int64
+	for _, size := range p.MaxSizes {
+		if size > largest {
+			largest = size
+		}
+	}
+	return largest
+}
+
+// Validate: sniff isi file, cek allowlist & ukuran, lalu kembalikan file yang sudah dinormalisasi
+// (nama disanitasi, Content-Type hasil sniffing, reader dibatasi sesuai ukuran maksimum).
+func (p EvidencePolicy) Validate(file EvidenceFile) (EvidenceFile, error) {
+	if file.Size == 0 {
+		return file, ErrEmptyFile
+	}
+
+	br := bufio.NewReaderSize(file.Content, 512)
+	head, err := br.Peek(512)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return file, err
+	}
+	if len(head) == 0 {
+		return file, ErrEmptyFile
+	}
+
+	// Jangan percaya header Content-Type dari client
+	contentType, maxSize, err := p.checkContent(head, file.Size)
+	if err != nil {
+		return file, err
+	}
+
+	return EvidenceFile{
+		FileName:    SanitizeFileName(file.FileName, evidenceExtensions[contentType]),
+		ContentType: contentType,
+		Size:        file.Size,
+		Content:     &limitedReader{r: br, remaining: maxSize},
+	}, nil
+}
+
+// checkContent: tentukan tipe file dari byte awal (maks 512) lalu cek allowlist & ukuran.
+// Dipakai juga oleh resumable upload untuk menolak file lebih awal sebelum upload selesai.
+func (p EvidencePolicy) checkContent(head []byte, size int64) (string, int64, error) {
+	contentType := http.DetectContentType(head)
+	if i := strings.Index(contentType, ";"); i >= 0 {
+		contentType = contentType[:i]
+	}
+	maxSize, allowed := p.MaxSizes[contentType]
+	if !allowed {
+		return "", 0, ErrUnsupportedFileType
+	}
+	if size > maxSize {
+		return "", 0, fmt.Errorf("%w (%d MB for %s)", ErrFileTooLarge, maxSize/(1024*1024), contentType)
+	}
+	return contentType, maxSize, nil
+}
+
+// SanitizeFileName: ambil base name, buang karakter selain huruf/angka/.-_ dan
+// pastikan ekstensi sesuai tipe hasil sniffing. Hanya dipakai sebagai nama tampilan.
+func SanitizeFileName(name, ext string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	base := strings.TrimSuffix(name, filepath.Ext(name))
+
+	var b strings.Builder
+	lastUnderscore := false
+	for _, r := range base {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '.') {
+			b.WriteRune(r)
+			lastUnderscore = false
+		} else if !lastUnderscore {
+			b.WriteRune('_')
+			lastUnderscore = true
+		}
+	}
+
+	cleaned := strings.Trim(b.String(), "._-")
+	if len(cleaned) > 100 {
+		cleaned = cleaned[:100]
+	}
+	if cleaned == "" {
+		cleaned = "evidence"
+	}
+	return cleaned + ext
+}
+
+// evidenceKey: key content-addressed berdasarkan SHA-256 isi file (tidak memuat nama dari client).
+// Akses tetap lewat endpoint download yang mengecek hak akses.
+func evidenceKey(hash string) string {
+	return "sha256/" + hash[:2] + "/" + hash
+}
+
+// limitedReader: gagal jika isi file melebihi batas (ukuran dari header multipart bisa dimanipulasi)
+type limitedReader struct {
+	r         io.Reader
+	remaining int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	if l.remaining < 0 {
+		return n, ErrFileTooLarge
+	}
+	return n, err
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
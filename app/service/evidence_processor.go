@@ -0,0 +1,420 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/jpeg"
+	_ "image/png" // registrasi decoder PNG
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"golang.org/x/image/draw"
+
+	mongoModel "reportachievement/app/model/mongo"
+	mongoRepo "reportachievement/app/repository/mongo"
+	"reportachievement/config"
+	"reportachievement/metadata"
+	"reportachievement/scanner"
+	"reportachievement/storage"
+)
+
+const (
+	thumbnailMaxSize   = 320        // px, sisi terpanjang
+	thumbnailMaxPixels = 50_000_000 // tolak gambar raksasa (decompression bomb)
+	scanRetryDelay     = time.Minute
+	// File yang belum diproses (job dibuang karena antrean penuh, retry, restart) diantrekan ulang berkala
+	evidenceResyncInterval = 5 * time.Minute
+)
+
+// Status attachment hasil pemrosesan
+const (
+	AttachmentPending  = "pending"
+	AttachmentClean    = "clean"
+	AttachmentRejected = "rejected"
+)
+
+var errPreviewUnsupported = errors.New("preview not supported for this file type")
+
+// EvidenceJob: satu file (per hash) yang perlu diproses setelah upload
+type EvidenceJob struct {
+	Hash        string
+	StorageKey  string
+	ContentType string
+}
+
+// EvidenceProcessor: worker background untuk pemrosesan file evidence setelah upload
+// (scan malware, thumbnail gambar & preview halaman pertama PDF). Hasil disimpan per hash
+// di evidence_files lalu disalin ke semua attachment dengan checksum yang sama.
+type EvidenceProcessor struct {
+	storage      storage.Storage
+	scanner      scanner.Scanner
+	evidenceRepo *mongoRepo.EvidenceFileRepository
+	achMongoRepo *mongoRepo.AchievementRepository
+	links        *FileLinker
+	pdfRenderer  string // command pdftoppm (poppler-utils), kosong = preview PDF dimatikan
+	workers      int
+	jobs         chan EvidenceJob
+}
+
+func NewEvidenceProcessor(
+	cfg *config.Config,
+	fileStorage storage.Storage,
+	fileScanner scanner.Scanner,
+	evidenceRepo *mongoRepo.EvidenceFileRepository,
+	achMongoRepo *mongoRepo.AchievementRepository,
+	links *FileLinker,
+) *EvidenceProcessor {
+	pdfRenderer := ""
+	if cfg.PDFPreviewCommand != "" {
+		if path, err := exec.LookPath(cfg.PDFPreviewCommand); err == nil {
+			pdfRenderer = path
+		} else {
+			log.Println("⚠️ Preview PDF dimatikan,", cfg.PDFPreviewCommand, "tidak ditemukan")
+		}
+	}
+
+	workers := cfg.EvidenceWorkers
+	if workers < 1 {
+		workers = 1
+	}
+
+	return &EvidenceProcessor{
+		storage:      fileStorage,
+		scanner:      fileScanner,
+		evidenceRepo: evidenceRepo,
+		achMongoRepo: achMongoRepo,
+		links:        links,
+		pdfRenderer:  pdfRenderer,
+		workers:      workers,
+		jobs:         make(chan EvidenceJob, 100),
+	}
+}
+
+// Start: jalankan worker dan antrekan ulang file yang belum selesai diproses (saat start & berkala)
+func (p *EvidenceProcessor) Start(ctx context.Context) {
+	for i := 0; i < p.workers; i++ {
+		go p.run(ctx)
+	}
+
+	go func() {
+		ticker := time.NewTicker(evidenceResyncInterval)
+		defer ticker.Stop()
+		for {
+			p.resync(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// resync: antrekan file yang belum diproses. Boleh menunggu antrean kosong karena berjalan di background.
+// Job ganda aman: file yang sudah diproses hanya disalin ulang hasilnya ke attachment.
+func (p *EvidenceProcessor) resync(ctx context.Context) {
+	pending, err := p.evidenceRepo.FindUnprocessed(ctx)
+	if err != nil {
+		log.Println("⚠️ Gagal ambil evidence yang belum diproses:", err)
+		return
+	}
+	for _, file := range pending {
+		select {
+		case p.jobs <- EvidenceJob{Hash: file.Hash, StorageKey: file.StorageKey, ContentType: file.ContentType}:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Enqueue: tidak memblokir request upload. Jika antrean penuh job dibuang,
+// file tetap tercatat belum diproses dan diantrekan lagi oleh resync berikutnya.
+func (p *EvidenceProcessor) Enqueue(job EvidenceJob) {
+	select {
+	case p.jobs <- job:
+	default:
+		log.Println("⚠️ Antrean evidence penuh,", job.Hash, "diproses pada resync berikutnya")
+	}
+}
+
+func (p *EvidenceProcessor) run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case job := <-p.jobs:
+			if err := p.process(ctx, job); err != nil {
+				log.Println("⚠️ Gagal memproses evidence", job.Hash, ":", err, "- dicoba lagi dalam", scanRetryDelay)
+				time.AfterFunc(scanRetryDelay, func() { p.Enqueue(job) })
+			}
+		}
+	}
+}
+
+func (p *EvidenceProcessor) process(ctx context.Context, job EvidenceJob) error {
+	file, err := p.evidenceRepo.FindByHash(ctx, job.Hash)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil // file sudah dihapus (ref_count 0)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Sudah pernah diproses (upload ulang isi yang sama): cukup salin hasil ke attachment baru
+	if file.ProcessedAt != nil {
+		return p.propagate(ctx, file)
+	}
+
+	// 1. Scan malware, jika gagal (clamd mati) job diulang dan attachment tetap pending
+	result, err := p.scan(ctx, file.StorageKey)
+	if err != nil {
+		return err
+	}
+
+	if result.Infected {
+		// 2a. Karantina: pindahkan file agar tidak bisa didownload lagi
+		quarantineKey := "quarantine/" + file.Hash
+		if err := p.move(ctx, file.StorageKey, quarantineKey, file.ContentType); err != nil {
+			return err
+		}
+		log.Println("☣️ Malware terdeteksi pada evidence", file.Hash, ":", result.Signature)
+		file.StorageKey = quarantineKey
+		file.ScanStatus = AttachmentRejected
+		file.ScanMessage = "malware detected: " + result.Signature
+	} else {
+		// 2b. Thumbnail hanya untuk file bersih
+		file.ScanStatus = AttachmentClean
+		thumbnailKey, err := p.generateThumbnail(ctx, job)
+		if err != nil && !errors.Is(err, errPreviewUnsupported) {
+			log.Println("⚠️ Thumbnail gagal dibuat untuk", job.Hash, ":", err)
+		}
+		file.ThumbnailKey = thumbnailKey
+
+		if job.ContentType == "application/pdf" {
+			document, err := p.readPDFMetadata(ctx, job.StorageKey)
+			if err != nil {
+				log.Println("⚠️ Metadata PDF gagal dibaca untuk", job.Hash, ":", err)
+			}
+			file.Document = document
+		}
+	}
+
+	if err := p.evidenceRepo.MarkProcessed(ctx, *file); err != nil {
+		return err
+	}
+	return p.propagate(ctx, file)
+}
+
+func (p *EvidenceProcessor) scan(ctx context.Context, key string) (scanner.Result, error) {
+	r, err := p.storage.Get(ctx, key)
+	if err != nil {
+		return scanner.Result{}, err
+	}
+	defer r.Close()
+	return p.scanner.Scan(ctx, r)
+}
+
+func (p *EvidenceProcessor) move(ctx context.Context, from, to, contentType string) error {
+	r, err := p.storage.Get(ctx, from)
+	if err != nil {
+		return err
+	}
+	err = p.storage.Put(ctx, to, r, -1, contentType)
+	r.Close()
+	if err != nil {
+		return err
+	}
+	return p.storage.Delete(ctx, from)
+}
+
+// propagate: salin hasil pemrosesan ke semua attachment dengan checksum yang sama
+func (p *EvidenceProcessor) propagate(ctx context.Context, file *mongoModel.EvidenceFile) error {
+	fields := map[string]interface{}{
+		"status":           file.ScanStatus,
+		"rejection_reason": file.ScanMessage,
+	}
+	if file.ThumbnailKey != "" {
+		fields["thumbnail_key"] = file.ThumbnailKey
+		fields["thumbnail_url"] = p.links.URL(file.ThumbnailKey)
+	}
+	if file.Document != nil {
+		fields["document"] = file.Document
+	}
+	return p.achMongoRepo.SetAttachmentFields(ctx, file.Hash, fields)
+}
+
+// --- THUMBNAIL ---
+
+func thumbnailKey(hash string) string {
+	return "thumbnails/" + hash[:2] + "/" + hash + ".jpg"
+}
+
+func (p *EvidenceProcessor) generateThumbnail(ctx context.Context, job EvidenceJob) (string, error) {
+	var img image.Image
+	var err error
+
+	switch job.ContentType {
+	case "image/jpeg", "image/png":
+		img, err = p.decodeImage(ctx, job.StorageKey)
+	case "application/pdf":
+		img, err = p.renderPDFFirstPage(ctx, job.StorageKey)
+	default:
+		return "", errPreviewUnsupported
+	}
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, resizeToFit(img, thumbnailMaxSize), &jpeg.Options{Quality: 80}); err != nil {
+		return "", err
+	}
+
+	key := thumbnailKey(job.Hash)
+	if err := p.storage.Put(ctx, key, &buf, int64(buf.Len()), "image/jpeg"); err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+func (p *EvidenceProcessor) decodeImage(ctx context.Context, key string) (image.Image, error) {
+	// Cek dimensi dulu sebelum decode penuh
+	r, err := p.storage.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	imgCfg, _, err := image.DecodeConfig(r)
+	r.Close()
+	if err != nil {
+		return nil, err
+	}
+	if imgCfg.Width*imgCfg.Height > thumbnailMaxPixels {
+		return nil, errors.New("image dimensions too large for thumbnail")
+	}
+
+	r, err = p.storage.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	img, _, err := image.Decode(r)
+	return img, err
+}
+
+// --- METADATA PDF ---
+
+// readPDFMetadata: judul, tanggal dibuat/diubah & jumlah halaman (gambar sudah dibersihkan saat upload)
+func (p *EvidenceProcessor) readPDFMetadata(ctx context.Context, key string) (*mongoModel.DocumentMetadata, error) {
+	dir, err := os.MkdirTemp("", "evidence-meta-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	input := filepath.Join(dir, "input.pdf")
+	if err := p.download(ctx, key, input); err != nil {
+		return nil, err
+	}
+	f, err := os.Open(input)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := metadata.ReadPDF(f, stat.Size())
+	if err != nil {
+		return nil, err
+	}
+	return &mongoModel.DocumentMetadata{
+		Title:      info.Title,
+		Author:     info.Author,
+		Creator:    info.Creator,
+		Producer:   info.Producer,
+		CreatedAt:  info.CreatedAt,
+		ModifiedAt: info.ModifiedAt,
+		PageCount:  info.PageCount,
+	}, nil
+}
+
+// --- PREVIEW PDF ---
+
+// renderPDFFirstPage: render halaman pertama PDF dengan pdftoppm ke JPEG
+func (p *EvidenceProcessor) renderPDFFirstPage(ctx context.Context, key string) (image.Image, error) {
+	if p.pdfRenderer == "" {
+		return nil, errPreviewUnsupported
+	}
+
+	dir, err := os.MkdirTemp("", "evidence-preview-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	input := filepath.Join(dir, "input.pdf")
+	if err := p.download(ctx, key, input); err != nil {
+		return nil, err
+	}
+
+	output := filepath.Join(dir, "page")
+	cmd := exec.CommandContext(ctx, p.pdfRenderer, "-f", "1", "-l", "1", "-singlefile", "-jpeg", "-scale-to", "1024", input, output)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return nil, errors.New(err.Error() + ": " + string(out))
+	}
+
+	f, err := os.Open(output + ".jpg")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return jpeg.Decode(f)
+}
+
+func (p *EvidenceProcessor) download(ctx context.Context, key, dst string) error {
+	r, err := p.storage.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	f, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// resizeToFit: perkecil gambar agar sisi terpanjang <= maxSize (tidak memperbesar),
+// di atas latar putih karena JPEG tidak mendukung transparansi PNG
+func resizeToFit(src image.Image, maxSize int) image.Image {
+	bounds := src.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if w > maxSize || h > maxSize {
+		if w >= h {
+			h = max(h*maxSize/w, 1)
+			w = maxSize
+		} else {
+			w = max(w*maxSize/h, 1)
+			h = maxSize
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(dst, dst.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, bounds, draw.Over, nil)
+	return dst
+}
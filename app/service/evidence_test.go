@@ -0,0 +1,217 @@
+package service
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	pngHeader  = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	jpegHeader = []byte("\xff\xd8\xff\xe0\x00\x10JFIF\x00")
+	pdfHeader  = []byte("%PDF-1.7\n%\xe2\xe3\xcf\xd3\n")
+)
+
+func testEvidencePolicy() EvidencePolicy {
+	return EvidencePolicy{MaxSizes: map[string]int64{
+		"application/pdf": 4096,
+		"image/jpeg":      1024,
+		"image/png":       1024,
+		"video/mp4":       8192,
+	}}
+}
+
+// evidenceContent: header tipe file + padding sampai total size byte
+func evidenceContent(header []byte, size int) []byte {
+	return append(append([]byte{}, header...), bytes.Repeat([]byte{0}, size-len(header))...)
+}
+
+func TestEvidencePolicy_Validate(t *testing.T) {
+	policy := testEvidencePolicy()
+	tests := []struct {
+		name     string
+		file     EvidenceFile
+		content  []byte
+		wantErr  error
+		wantType string
+		wantName string
+	}{
+		{
+			name:     "png",
+			file:     EvidenceFile{FileName: "sertifikat.png", ContentType: "image/png"},
+			content:  evidenceContent(pngHeader, 600),
+			wantType: "image/png",
+			wantName: "sertifikat.png",
+		},
+		{
+			name:     "ekstensi & content type palsu mengikuti hasil sniffing",
+			file:     EvidenceFile{FileName: "sertifikat.pdf", ContentType: "application/pdf"},
+			content:  evidenceContent(pngHeader, 600),
+			wantType: "image/png",
+			wantName: "sertifikat.png",
+		},
+		{
+			name:     "pdf tanpa ekstensi",
+			file:     EvidenceFile{FileName: "piagam", ContentType: "application/octet-stream"},
+			content:  evidenceContent(pdfHeader, 3000),
+			wantType: "application/pdf",
+			wantName: "piagam.pdf",
+		},
+		{
+			name:    "html menyamar sebagai jpg",
+			file:    EvidenceFile{FileName: "foto.jpg", ContentType: "image/jpeg"},
+			content: []byte("<!DOCTYPE html><html><script>alert(1)</script></html>"),
+			wantErr: ErrUnsupportedFileType,
+		},
+		{
+			name:    "svg ditolak",
+			file:    EvidenceFile{FileName: "logo.png", ContentType: "image/png"},
+			content: []byte(`<?xml version="1.0"?><svg xmlns="http://www.w3.org/2000/svg" onload="alert(1)"></svg>`),
+			wantErr: ErrUnsupportedFileType,
+		},
+		{
+			name:    "executable menyamar sebagai pdf",
+			file:    EvidenceFile{FileName: "laporan.pdf", ContentType: "application/pdf"},
+			content: append([]byte("MZ\x90\x00\x03\x00\x00\x00"), bytes.Repeat([]byte{0}, 100)...),
+			wantErr: ErrUnsupportedFileType,
+		},
+		{
+			name:    "gambar melebihi batas gambar",
+			file:    EvidenceFile{FileName: "besar.jpg"},
+			content: evidenceContent(jpegHeader, 2000),
+			wantErr: ErrFileTooLarge,
+		},
+		{
+			name:     "ukuran sama tetap boleh untuk pdf (batas per tipe)",
+			file:     EvidenceFile{FileName: "besar.pdf"},
+			content:  evidenceContent(pdfHeader, 2000),
+			wantType: "application/pdf",
+			wantName: "besar.pdf",
+		},
+		{
+			name:    "file kosong",
+			file:    EvidenceFile{FileName: "kosong.pdf"},
+			content: nil,
+			wantErr: ErrEmptyFile,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.file.Size = int64(len(tt.content))
+			tt.file.Content = bytes.NewReader(tt.content)
+
+			got, err := policy.Validate(tt.file)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, tt.wantType, got.ContentType)
+			assert.Equal(t, tt.wantName, got.FileName)
+
+			// Isi file tetap utuh setelah di-peek untuk sniffing
+			data, err := io.ReadAll(got.Content)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.content, data)
+		})
+	}
+}
+
+func TestEvidencePolicy_Validate_EmptyContentWithSize(t *testing.T) {
+	// Size dari header multipart > 0 tapi isinya kosong
+	_, err := testEvidencePolicy().Validate(EvidenceFile{FileName: "a.pdf", Size: 10, Content: bytes.NewReader(nil)})
+	assert.ErrorIs(t, err, ErrEmptyFile)
+}
+
+func TestEvidencePolicy_Validate_LimitedReader(t *testing.T) {
+	policy := testEvidencePolicy()
+
+	// Size yang dilaporkan client kecil, isi sebenarnya melebihi batas PNG (1024): terpotong saat dibaca
+	oversized := evidenceContent(pngHeader, 1500)
+	got, err := policy.Validate(EvidenceFile{FileName: "bohong.png", Size: 100, Content: bytes.NewReader(oversized)})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	data, err := io.ReadAll(got.Content)
+	assert.ErrorIs(t, err, ErrFileTooLarge)
+	assert.LessOrEqual(t, len(data), len(oversized))
+
+	// Tepat di batas masih diterima
+	exact := evidenceContent(pngHeader, 1024)
+	got, err = policy.Validate(EvidenceFile{FileName: "pas.png", Size: 100, Content: bytes.NewReader(exact)})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	data, err = io.ReadAll(got.Content)
+	assert.NoError(t, err)
+	assert.Len(t, data, 1024)
+}
+
+func TestEvidencePolicy_MaxSize(t *testing.T) {
+	assert.Equal(t, int64(8192), testEvidencePolicy().MaxSize())
+}
+
+func TestSanitizeFileName(t *testing.T) {
+	tests := []struct {
+		name, ext, want string
+	}{
+		{"sertifikat juara.pdf", ".pdf", "sertifikat_juara.pdf"},
+		{"../../etc/passwd", ".pdf", "passwd.pdf"},
+		{`..\..\windows\system32\cmd.exe`, ".png", "cmd.png"},
+		{"/var/www/../../shell.php.jpg", ".jpg", "shell.php.jpg"},
+		{`foto "lomba".png`, ".png", "foto_lomba.png"},
+		{"a'b;c=d.pdf", ".pdf", "a_b_c_d.pdf"},
+		{"laporan\r\nX-Injected: 1.pdf", ".pdf", "laporan_X-Injected_1.pdf"},
+		{"piagam\x00.pdf", ".pdf", "piagam.pdf"},
+		{"sertifikat-ñandú-日本.pdf", ".pdf", "sertifikat-_and.pdf"},
+		{"日本語.pdf", ".pdf", "evidence.pdf"},
+		{"..", ".pdf", "evidence.pdf"},
+		{"", ".jpg", "evidence.jpg"},
+		{strings.Repeat("a", 150) + ".pdf", ".pdf", strings.Repeat("a", 100) + ".pdf"},
+	}
+	for _, tt := range tests {
+		got := SanitizeFileName(tt.name, tt.ext)
+		assert.Equal(t, tt.want, got, "input %q", tt.name)
+		assert.NotContains(t, got, "/")
+		assert.NotContains(t, got, "\\")
+		assert.NotContains(t, got, `"`)
+		assert.NotContains(t, got, "\r")
+		assert.NotContains(t, got, "\n")
+	}
+}
+
+func TestAttachmentFile_Sniff(t *testing.T) {
+	tests := []struct {
+		name       string
+		storedType string // FileType tersimpan (upload lama: Content-Type dari client)
+		content    []byte
+		wantType   string
+		wantInline bool
+	}{
+		{"png", "image/png", evidenceContent(pngHeader, 100), "image/png", true},
+		{"pdf dengan type tersimpan salah", "text/plain", evidenceContent(pdfHeader, 100), "application/pdf", true},
+		{"html lama", "text/html", []byte("<html><body><script>alert(1)</script></body></html>"), "text/html", false},
+		{"svg lama dilabeli image", "image/svg+xml", []byte(`<svg xmlns="http://www.w3.org/2000/svg" onload="alert(1)"/>`), "text/plain", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := &AttachmentFile{Content: io.NopCloser(bytes.NewReader(tt.content))}
+			file.Attachment.FileType = tt.storedType
+
+			contentType, inline := file.Sniff()
+			assert.Equal(t, tt.wantType, contentType)
+			assert.Equal(t, tt.wantInline, inline)
+
+			data, err := io.ReadAll(file.Content)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.content, data)
+			assert.NoError(t, file.Content.Close())
+		})
+	}
+}
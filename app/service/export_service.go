@@ -0,0 +1,454 @@
+package service
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	mongoModel "reportachievement/app/model/mongo"
+	mongoRepo "reportachievement/app/repository/mongo"
+	postgreRepo "reportachievement/app/repository/postgre"
+	"reportachievement/config"
+	"reportachievement/storage"
+)
+
+// Status export job
+const (
+	ExportQueued  = "queued"
+	ExportRunning = "running"
+	ExportDone    = "done"
+	ExportFailed  = "failed"
+	ExportExpired = "expired"
+)
+
+// Semua file hasil export disimpan di bawah prefix ini (dilewati StorageGC,
+// dihapus sendiri oleh ExportService setelah masa simpan)
+const exportKeyPrefix = "exports/"
+
+const exportCleanupInterval = time.Hour
+
+var (
+	ErrExportNotFound = errors.New("export not found")
+	ErrExportNotReady = errors.New("export is not ready for download")
+)
+
+// Request export akreditasi (BAN-PT/LAM): tanggal format YYYY-MM-DD, To inklusif
+type ExportRequest struct {
+	ProgramStudy string `json:"program_study"`
+	From         string `json:"from"`
+	To           string `json:"to"`
+}
+
+type ExportJobResponse struct {
+	mongoModel.ExportJob
+	DownloadURL string `json:"download_url,omitempty"`
+}
+
+// Satu baris index.csv / index.json
+type ExportIndexEntry struct {
+	StudentName  string     `json:"student_name"`
+	NIM          string     `json:"nim"`
+	ProgramStudy string     `json:"program_study"`
+	AcademicYear string     `json:"academic_year"`
+	Title        string     `json:"title"`
+	Type         string     `json:"type"`
+	Level        string     `json:"level"`
+	Points       int        `json:"points"`
+	EventDate    string     `json:"event_date,omitempty"`
+	SubmittedAt  *time.Time `json:"submitted_at"`
+	VerifiedAt   *time.Time `json:"verified_at"`
+	VerifiedBy   string     `json:"verified_by"`
+	Files        []string   `json:"files"`                   // path di dalam zip
+	SkippedFiles []string   `json:"skipped_files,omitempty"` // attachment yang tidak ikut (pending/rejected/hilang)
+}
+
+// ExportService: bundle zip prestasi verified + evidence per prodi & periode.
+// Dibuat satu per satu oleh worker background karena bisa berukuran besar.
+type ExportService struct {
+	achRefRepo   *postgreRepo.AchievementRepository
+	achMongoRepo *mongoRepo.AchievementRepository
+	exportRepo   *mongoRepo.ExportJobRepository
+	storage      storage.Storage
+	baseURL      string
+	retention    time.Duration
+	jobs         chan string
+}
+
+func NewExportService(
+	cfg *config.Config,
+	achRefRepo *postgreRepo.AchievementRepository,
+	achMongoRepo *mongoRepo.AchievementRepository,
+	exportRepo *mongoRepo.ExportJobRepository,
+	fileStorage storage.Storage,
+) *ExportService {
+	return &ExportService{
+		achRefRepo:   achRefRepo,
+		achMongoRepo: achMongoRepo,
+		exportRepo:   exportRepo,
+		storage:      fileStorage,
+		baseURL:      strings.TrimRight(cfg.AppBaseURL, "/"),
+		retention:    cfg.ExportRetention,
+		jobs:         make(chan string, 100),
+	}
+}
+
+// Start: satu worker export, antre ulang job yang terputus (restart) & hapus zip kedaluwarsa
+func (s *ExportService) Start(ctx context.Context) {
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case id := <-s.jobs:
+				s.run(ctx, id)
+			}
+		}
+	}()
+
+	go func() {
+		unfinished, err := s.exportRepo.FindUnfinished(ctx)
+		if err != nil {
+			log.Println("⚠️ Gagal ambil export yang belum selesai:", err)
+		}
+		for _, job := range unfinished {
+			s.enqueue(job.ID.Hex())
+		}
+
+		ticker := time.NewTicker(exportCleanupInterval)
+		defer ticker.Stop()
+		for {
+			s.removeExpired(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// 1. CreateExport: validasi parameter lalu antrekan job
+func (s *ExportService) CreateExport(ctx context.Context, userID uuid.UUID, req ExportRequest) (*ExportJobResponse, error) {
+	req.ProgramStudy = strings.TrimSpace(req.ProgramStudy)
+	if req.ProgramStudy == "" {
+		return nil, errors.New("program_study is required")
+	}
+	from, err := time.ParseInLocation(time.DateOnly, req.From, time.Local)
+	if err != nil {
+		return nil, errors.New("from must be a date (YYYY-MM-DD)")
+	}
+	to, err := time.ParseInLocation(time.DateOnly, req.To, time.Local)
+	if err != nil {
+		return nil, errors.New("to must be a date (YYYY-MM-DD)")
+	}
+	if to.Before(from) {
+		return nil, errors.New("to must not be before from")
+	}
+
+	job := &mongoModel.ExportJob{
+		Status:       ExportQueued,
+		RequestedBy:  userID.String(),
+		ProgramStudy: req.ProgramStudy,
+		From:         from,
+		To:           to.AddDate(0, 0, 1), // tanggal akhir inklusif
+		CreatedAt:    time.Now(),
+	}
+	if err := s.exportRepo.Insert(ctx, job); err != nil {
+		return nil, err
+	}
+	s.enqueue(job.ID.Hex())
+	return s.toResponse(job), nil
+}
+
+// 2. GetExport
+func (s *ExportService) GetExport(ctx context.Context, id string) (*ExportJobResponse, error) {
+	job, err := s.findJob(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.toResponse(job), nil
+}
+
+// 3. ListExports: 50 export terbaru
+func (s *ExportService) ListExports(ctx context.Context) ([]ExportJobResponse, error) {
+	jobs, err := s.exportRepo.FindRecent(ctx, 50)
+	if err != nil {
+		return nil, err
+	}
+	result := []ExportJobResponse{}
+	for i := range jobs {
+		result = append(result, *s.toResponse(&jobs[i]))
+	}
+	return result, nil
+}
+
+// 4. OpenExport: isi zip untuk endpoint download
+func (s *ExportService) OpenExport(ctx context.Context, id string) (*mongoModel.ExportJob, io.ReadCloser, error) {
+	job, err := s.findJob(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if job.Status != ExportDone {
+		return nil, nil, ErrExportNotReady
+	}
+	r, err := s.storage.Get(ctx, job.StorageKey)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil, ErrExportNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return job, r, nil
+}
+
+// ExportFileName: nama file zip untuk Content-Disposition
+func ExportFileName(job *mongoModel.ExportJob) string {
+	return fmt.Sprintf("akreditasi_%s_%s_%s.zip",
+		SanitizeFileName(job.ProgramStudy, ""),
+		job.From.Format("20060102"),
+		job.To.AddDate(0, 0, -1).Format("20060102"))
+}
+
+func (s *ExportService) findJob(ctx context.Context, id string) (*mongoModel.ExportJob, error) {
+	job, err := s.exportRepo.FindByID(ctx, id)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrExportNotFound
+	}
+	return job, err
+}
+
+func (s *ExportService) toResponse(job *mongoModel.ExportJob) *ExportJobResponse {
+	resp := &ExportJobResponse{ExportJob: *job}
+	if job.Status == ExportDone {
+		resp.DownloadURL = s.baseURL + "/api/v1/exports/" + job.ID.Hex() + "/download"
+	}
+	return resp
+}
+
+// enqueue: tidak memblokir request; jika antrean penuh dikirim dari goroutine terpisah
+func (s *ExportService) enqueue(id string) {
+	select {
+	case s.jobs <- id:
+	default:
+		go func() { s.jobs <- id }()
+	}
+}
+
+func (s *ExportService) run(ctx context.Context, id string) {
+	job, err := s.exportRepo.FindByID(ctx, id)
+	if err != nil {
+		log.Println("⚠️ Export", id, "tidak ditemukan:", err)
+		return
+	}
+	if job.Status != ExportQueued && job.Status != ExportRunning {
+		return
+	}
+
+	started := time.Now()
+	s.exportRepo.Update(ctx, job.ID, map[string]interface{}{"status": ExportRunning, "started_at": started})
+
+	if err := s.build(ctx, job); err != nil {
+		log.Println("⚠️ Export", id, "gagal:", err)
+		finished := time.Now()
+		s.exportRepo.Update(ctx, job.ID, map[string]interface{}{"status": ExportFailed, "error": err.Error(), "finished_at": finished})
+		return
+	}
+
+	finished := time.Now()
+	expires := finished.Add(s.retention)
+	s.exportRepo.Update(ctx, job.ID, map[string]interface{}{
+		"status":            ExportDone,
+		"storage_key":       job.StorageKey,
+		"size":              job.Size,
+		"achievement_count": job.AchievementCount,
+		"file_count":        job.FileCount,
+		"finished_at":       finished,
+		"expires_at":        expires,
+	})
+}
+
+// build: tulis zip ke file sementara (index + evidence per mahasiswa) lalu simpan ke storage
+func (s *ExportService) build(ctx context.Context, job *mongoModel.ExportJob) error {
+	refs, err := s.achRefRepo.FindVerifiedByProgram(job.ProgramStudy, job.From, job.To)
+	if err != nil {
+		return err
+	}
+
+	var mongoIDs []string
+	for _, ref := range refs {
+		mongoIDs = append(mongoIDs, ref.MongoAchievementID)
+	}
+	mongoMap := make(map[string]mongoModel.Achievement)
+	if len(mongoIDs) > 0 {
+		docs, err := s.achMongoRepo.FindByIDs(ctx, mongoIDs)
+		if err != nil {
+			return err
+		}
+		for _, doc := range docs {
+			mongoMap[doc.ID.Hex()] = doc
+		}
+	}
+
+	tmp, err := os.CreateTemp("", "export-*.zip")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	zw := zip.NewWriter(tmp)
+	entries := []ExportIndexEntry{}
+	counter := map[string]int{} // folder mahasiswa -> jumlah prestasi
+
+	for _, ref := range refs {
+		doc, exists := mongoMap[ref.MongoAchievementID]
+		if !exists {
+			continue
+		}
+		entry := ExportIndexEntry{
+			StudentName:  ref.Student.User.FullName,
+			NIM:          ref.Student.NIM,
+			ProgramStudy: ref.Student.ProgramStudy,
+			AcademicYear: ref.Student.AcademicYear,
+			Title:        doc.Title,
+			Type:         doc.AchievementType,
+			Level:        detailString(doc.Details, "level", "tingkat"),
+			Points:       doc.Points,
+			EventDate:    detailString(doc.Details, "date", "event_date", "tanggal"),
+			SubmittedAt:  ref.SubmittedAt,
+			VerifiedAt:   ref.VerifiedAt,
+			Files:        []string{},
+		}
+		if ref.Verifier != nil {
+			entry.VerifiedBy = ref.Verifier.FullName
+		}
+
+		// Folder: <NIM>_<Nama>/<No>_<Judul>/
+		studentDir := SanitizeFileName(ref.Student.NIM+" "+ref.Student.User.FullName, "")
+		counter[studentDir]++
+		achievementDir := fmt.Sprintf("%s/%02d_%s", studentDir, counter[studentDir], SanitizeFileName(doc.Title, ""))
+
+		for i, att := range doc.Attachments {
+			if att.Status == AttachmentPending || att.Status == AttachmentRejected {
+				entry.SkippedFiles = append(entry.SkippedFiles, att.FileName+" ("+att.Status+")")
+				continue
+			}
+			fileName := SanitizeFileName(att.FileName, strings.ToLower(path.Ext(att.FileName)))
+			name := fmt.Sprintf("%s/%02d_%s", achievementDir, i+1, fileName)
+			if err := s.addFile(ctx, zw, name, att.StorageKey); err != nil {
+				if errors.Is(err, storage.ErrNotFound) {
+					entry.SkippedFiles = append(entry.SkippedFiles, att.FileName+" (missing)")
+					continue
+				}
+				return err
+			}
+			entry.Files = append(entry.Files, name)
+			job.FileCount++
+		}
+		entries = append(entries, entry)
+	}
+	job.AchievementCount = len(entries)
+
+	if err := writeExportIndex(zw, entries); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+
+	size, err := tmp.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	job.StorageKey = exportKeyPrefix + job.ID.Hex() + ".zip"
+	job.Size = size
+	return s.storage.Put(ctx, job.StorageKey, tmp, size, "application/zip")
+}
+
+func (s *ExportService) addFile(ctx context.Context, zw *zip.Writer, name, key string) error {
+	r, err := s.storage.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	// Evidence sudah terkompresi (PDF/JPEG/PNG/MP4), cukup Store agar cepat
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Store, Modified: time.Now()})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, r)
+	return err
+}
+
+func writeExportIndex(zw *zip.Writer, entries []ExportIndexEntry) error {
+	w, err := zw.Create("index.json")
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(entries); err != nil {
+		return err
+	}
+
+	w, err = zw.Create("index.csv")
+	if err != nil {
+		return err
+	}
+	// BOM agar Excel membaca UTF-8 dengan benar
+	if _, err := w.Write([]byte("\ufeff")); err != nil {
+		return err
+	}
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"student_name", "nim", "program_study", "academic_year", "title", "type", "level", "points",
+		"event_date", "submitted_at", "verified_at", "verified_by", "files", "skipped_files"})
+	for _, e := range entries {
+		cw.Write([]string{
+			e.StudentName, e.NIM, e.ProgramStudy, e.AcademicYear, e.Title, e.Type, e.Level, strconv.Itoa(e.Points),
+			e.EventDate, formatExportTime(e.SubmittedAt), formatExportTime(e.VerifiedAt), e.VerifiedBy,
+			strings.Join(e.Files, "; "), strings.Join(e.SkippedFiles, "; "),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func formatExportTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format("2006-01-02 15:04:05")
+}
+
+// removeExpired: hapus zip yang melewati masa simpan
+func (s *ExportService) removeExpired(ctx context.Context) {
+	jobs, err := s.exportRepo.FindExpired(ctx, time.Now())
+	if err != nil {
+		log.Println("⚠️ Gagal ambil export kedaluwarsa:", err)
+		return
+	}
+	for _, job := range jobs {
+		if err := s.storage.Delete(ctx, job.StorageKey); err != nil {
+			log.Println("⚠️ Gagal hapus file export", job.StorageKey, ":", err)
+			continue
+		}
+		s.exportRepo.Update(ctx, job.ID, map[string]interface{}{"status": ExportExpired})
+	}
+}
@@ -0,0 +1,129 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	mongoModel "reportachievement/app/model/mongo"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// readZipFile: isi satu file di dalam zip
+func readZipFile(t *testing.T, zr *zip.Reader, name string) []byte {
+	t.Helper()
+	f, err := zr.Open(name)
+	if err != nil {
+		t.Fatalf("%s tidak ada di zip: %v", name, err)
+	}
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestWriteExportIndex(t *testing.T) {
+	verified := time.Date(2025, 3, 14, 9, 30, 0, 0, time.UTC)
+	entries := []ExportIndexEntry{{
+		StudentName:  "Ñoño Santoso",
+		NIM:          "187221001",
+		ProgramStudy: "Sistem Informasi",
+		AcademicYear: "2022",
+		Title:        `Juara 1, "Best Paper"`,
+		Type:         "competition",
+		Level:        "nasional",
+		Points:       50,
+		VerifiedAt:   &verified,
+		VerifiedBy:   "Dosen Wali",
+		Files: []string{
+			"187221001_Ono_Santoso/01_Juara_1_Best_Paper/01_sertifikat.pdf",
+			"187221001_Ono_Santoso/01_Juara_1_Best_Paper/02_foto.jpg",
+		},
+		SkippedFiles: []string{"video.mp4 (pending)"},
+	}}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	if !assert.NoError(t, writeExportIndex(zw, entries)) || !assert.NoError(t, zw.Close()) {
+		t.FailNow()
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	// index.json: tanpa BOM, bisa di-decode kembali
+	var decoded []ExportIndexEntry
+	jsonData := readZipFile(t, zr, "index.json")
+	assert.False(t, bytes.HasPrefix(jsonData, []byte("\ufeff")))
+	if assert.NoError(t, json.Unmarshal(jsonData, &decoded)) && assert.Len(t, decoded, 1) {
+		assert.Equal(t, entries[0].StudentName, decoded[0].StudentName)
+		assert.Equal(t, entries[0].Files, decoded[0].Files)
+		assert.Equal(t, entries[0].SkippedFiles, decoded[0].SkippedFiles)
+		assert.Nil(t, decoded[0].SubmittedAt)
+	}
+
+	// index.csv: diawali BOM UTF-8 untuk Excel
+	csvData := readZipFile(t, zr, "index.csv")
+	if !assert.True(t, bytes.HasPrefix(csvData, []byte{0xEF, 0xBB, 0xBF})) {
+		t.FailNow()
+	}
+	records, err := csv.NewReader(bytes.NewReader(csvData[3:])).ReadAll()
+	if !assert.NoError(t, err) || !assert.Len(t, records, 2) {
+		t.FailNow()
+	}
+	assert.Equal(t, "student_name", records[0][0])
+	assert.Len(t, records[0], 14)
+	row := records[1]
+	assert.Equal(t, "Ñoño Santoso", row[0])
+	assert.Equal(t, `Juara 1, "Best Paper"`, row[4])
+	assert.Equal(t, "50", row[7])
+	assert.Equal(t, "", row[9]) // submitted_at kosong
+	assert.Equal(t, "2025-03-14 09:30:00", row[10])
+	assert.Equal(t, "187221001_Ono_Santoso/01_Juara_1_Best_Paper/01_sertifikat.pdf; 187221001_Ono_Santoso/01_Juara_1_Best_Paper/02_foto.jpg", row[12])
+	assert.Equal(t, "video.mp4 (pending)", row[13])
+}
+
+func TestWriteExportIndex_Empty(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	assert.NoError(t, writeExportIndex(zw, []ExportIndexEntry{}))
+	assert.NoError(t, zw.Close())
+	zr, _ := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+
+	assert.JSONEq(t, "[]", string(readZipFile(t, zr, "index.json")))
+	records, err := csv.NewReader(bytes.NewReader(readZipFile(t, zr, "index.csv")[3:])).ReadAll()
+	assert.NoError(t, err)
+	assert.Len(t, records, 1) // hanya header
+}
+
+func TestExportFileName(t *testing.T) {
+	tests := []struct {
+		program, want string
+	}{
+		{"Sistem Informasi", "akreditasi_Sistem_Informasi_20250101_20250630.zip"},
+		{`../../etc/"prodi"`, "akreditasi_prodi_20250101_20250630.zip"},
+		{"Teknik\r\nX-Injected: 1", "akreditasi_Teknik_X-Injected_1_20250101_20250630.zip"},
+		{"", "akreditasi_evidence_20250101_20250630.zip"},
+	}
+	for _, tt := range tests {
+		// To eksklusif: nama file memakai hari terakhir periode
+		job := &mongoModel.ExportJob{
+			ProgramStudy: tt.program,
+			From:         time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+			To:           time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC),
+		}
+		got := ExportFileName(job)
+		assert.Equal(t, tt.want, got, "program %q", tt.program)
+		assert.NotContains(t, got, "/")
+		assert.NotContains(t, got, `"`)
+		assert.NotContains(t, got, "\n")
+	}
+}
@@ -0,0 +1,85 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"reportachievement/config"
+)
+
+var ErrInvalidSignature = errors.New("invalid or expired file link")
+
+// Endpoint download evidence (lihat route/postgre/file.go)
+const fileDownloadPath = "/api/v1/files/"
+
+// FileLinker: membentuk URL download evidence (butuh token) dan signed URL berumur pendek
+// untuk embed (<img>, <iframe>) tanpa header Authorization.
+type FileLinker struct {
+	baseURL string
+	secret  []byte
+	ttl     time.Duration
+}
+
+func NewFileLinker(cfg *config.Config) *FileLinker {
+	secret := []byte(cfg.FileURLSecret)
+	if len(secret) == 0 {
+		// Turunan JWT_SECRET, bukan secret yang sama: signed URL tidak bisa dipakai untuk memalsukan token
+		mac := hmac.New(sha256.New, []byte(cfg.JWTSecret))
+		mac.Write([]byte("file-url"))
+		secret = mac.Sum(nil)
+	}
+	return &FileLinker{
+		baseURL: strings.TrimRight(cfg.AppBaseURL, "/"),
+		secret:  secret,
+		ttl:     cfg.SignedURLTTL,
+	}
+}
+
+// URL: link download yang membutuhkan Bearer token
+func (l *FileLinker) URL(key string) string {
+	return l.baseURL + fileDownloadPath + escapeKey(key)
+}
+
+// SignedURL: link download yang berlaku sampai expiresAt tanpa Bearer token
+func (l *FileLinker) SignedURL(key string) (string, time.Time) {
+	expiresAt := time.Now().Add(l.ttl)
+	expires := strconv.FormatInt(expiresAt.Unix(), 10)
+
+	query := url.Values{}
+	query.Set("expires", expires)
+	query.Set("signature", l.sign(key, expires))
+	return l.URL(key) + "?" + query.Encode(), expiresAt
+}
+
+// Verify: cek signature dan masa berlaku signed URL
+func (l *FileLinker) Verify(key, expires, signature string) error {
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > expiresAt {
+		return ErrInvalidSignature
+	}
+	expected := l.sign(key, expires)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+func (l *FileLinker) sign(key, expires string) string {
+	mac := hmac.New(sha256.New, l.secret)
+	mac.Write([]byte(key + "\n" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func escapeKey(key string) string {
+	segments := strings.Split(key, "/")
+	for i, seg := range segments {
+		segments[i] = url.PathEscape(seg)
+	}
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"reportachievement/config"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testFileLinker(ttl time.Duration) *FileLinker {
+	return &FileLinker{baseURL: "http://localhost:3000", secret: []byte("test-secret"), ttl: ttl}
+}
+
+// signedQuery: ambil expires & signature dari signed URL
+func signedQuery(t *testing.T, signedURL string) (string, string) {
+	u, err := url.Parse(signedURL)
+	if err != nil {
+		t.Fatalf("signed URL tidak valid: %v", err)
+	}
+	return u.Query().Get("expires"), u.Query().Get("signature")
+}
+
+func TestFileLinker_SignVerify(t *testing.T) {
+	linker := testFileLinker(5 * time.Minute)
+	key := "sha256/ab/ab12cd34"
+
+	signedURL, expiresAt := linker.SignedURL(key)
+	assert.True(t, strings.HasPrefix(signedURL, "http://localhost:3000/api/v1/files/sha256/ab/ab12cd34?"))
+	assert.WithinDuration(t, time.Now().Add(5*time.Minute), expiresAt, time.Second)
+
+	expires, signature := signedQuery(t, signedURL)
+	t.Run("Signature Valid", func(t *testing.T) {
+		assert.NoError(t, linker.Verify(key, expires, signature))
+	})
+	t.Run("Key Diubah", func(t *testing.T) {
+		assert.ErrorIs(t, linker.Verify("sha256/ab/ab12cd35", expires, signature), ErrInvalidSignature)
+	})
+	t.Run("Expires Diperpanjang", func(t *testing.T) {
+		later, _ := strconv.ParseInt(expires, 10, 64)
+		assert.ErrorIs(t, linker.Verify(key, strconv.FormatInt(later+3600, 10), signature), ErrInvalidSignature)
+	})
+	t.Run("Signature Diubah", func(t *testing.T) {
+		tampered := "0" + signature[1:]
+		if tampered == signature {
+			tampered = "1" + signature[1:]
+		}
+		assert.ErrorIs(t, linker.Verify(key, expires, tampered), ErrInvalidSignature)
+		assert.ErrorIs(t, linker.Verify(key, expires, ""), ErrInvalidSignature)
+	})
+	t.Run("Secret Lain", func(t *testing.T) {
+		other := &FileLinker{baseURL: linker.baseURL, secret: []byte("secret-lain"), ttl: linker.ttl}
+		assert.ErrorIs(t, other.Verify(key, expires, signature), ErrInvalidSignature)
+	})
+	t.Run("Expires Tidak Valid", func(t *testing.T) {
+		assert.ErrorIs(t, linker.Verify(key, "besok", signature), ErrInvalidSignature)
+		assert.ErrorIs(t, linker.Verify(key, "", signature), ErrInvalidSignature)
+	})
+}
+
+func TestFileLinker_Expired(t *testing.T) {
+	linker := testFileLinker(-time.Minute) // sudah kedaluwarsa saat dibuat
+	key := "sha256/ab/ab12cd34"
+
+	expires, signature := signedQuery(t, func() string { u, _ := linker.SignedURL(key); return u }())
+	assert.ErrorIs(t, linker.Verify(key, expires, signature), ErrInvalidSignature)
+}
+
+func TestFileLinker_URLEscapesKey(t *testing.T) {
+	linker := testFileLinker(time.Minute)
+	assert.Equal(t, "http://localhost:3000/api/v1/files/legacy/bukti%20lomba%3F.pdf", linker.URL("legacy/bukti lomba?.pdf"))
+}
+
+func TestNewFileLinker_Secret(t *testing.T) {
+	derived := NewFileLinker(&config.Config{JWTSecret: "jwt-secret"})
+	assert.NotEqual(t, []byte("jwt-secret"), derived.secret) // bukan secret penandatangan token
+	assert.Len(t, derived.secret, 32)
+	assert.Equal(t, derived.secret, NewFileLinker(&config.Config{JWTSecret: "jwt-secret"}).secret)
+	assert.NotEqual(t, derived.secret, NewFileLinker(&config.Config{JWTSecret: "jwt-lain"}).secret)
+
+	explicit := NewFileLinker(&config.Config{JWTSecret: "jwt-secret", FileURLSecret: "file-secret"})
+	assert.Equal(t, []byte("file-secret"), explicit.secret)
+}
@@ -0,0 +1,209 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"strings"
+
+	"reportachievement/app/model/postgre"
+	repo "reportachievement/app/repository/postgre"
+	"reportachievement/config"
+	"reportachievement/ldap"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrLDAPNoRole       = errors.New("your directory account is not in a group that may use this application")
+	ErrLDAPProvisioning = errors.New("cannot create an account from the directory entry, contact the administrator")
+)
+
+// groupRole: satu entri LDAP_GROUP_ROLES
+type groupRole struct {
+	group string // DN lengkap atau nilai RDN pertama (cn)
+	role  string
+}
+
+// LDAPProvider: login dengan bind ke LDAP / Active Directory.
+// Role diambil dari grup (LDAP_GROUP_ROLES) setiap login, email & nama ikut disinkronkan.
+type LDAPProvider struct {
+	directory   *ldap.Directory
+	userRepo    *repo.UserRepository
+	authService *AuthService
+
+	groupRoles  []groupRole
+	defaultRole string
+	profileAttr string
+}
+
+func NewLDAPProvider(directory *ldap.Directory, userRepo *repo.UserRepository, authService *AuthService, cfg *config.Config) *LDAPProvider {
+	return &LDAPProvider{
+		directory:   directory,
+		userRepo:    userRepo,
+		authService: authService,
+		groupRoles:  parseGroupRoles(cfg.LDAPGroupRoles),
+		defaultRole: cfg.LDAPDefaultRole,
+		profileAttr: cfg.LDAPProfileIDAttribute,
+	}
+}
+
+func (p *LDAPProvider) Name() string {
+	return postgre.AuthSourceLDAP
+}
+
+func (p *LDAPProvider) Authenticate(user *postgre.User, username, plainPassword string) (*postgre.User, error) {
+	identity, err := p.directory.Authenticate(username, plainPassword)
+	switch {
+	case errors.Is(err, ldap.ErrInvalidCredentials), errors.Is(err, ldap.ErrUserNotFound):
+		return nil, ErrInvalidCredentials
+	case err != nil:
+		log.Println("❌ Login LDAP gagal:", err)
+		return nil, ErrAuthProviderUnavailable
+	}
+
+	roleName := p.mapRole(identity.Groups)
+	if user == nil {
+		return p.provision(identity, roleName)
+	}
+	p.sync(user, identity, roleName)
+	return user, nil
+}
+
+// mapRole: entri pertama yang cocok dengan salah satu grup user, selain itu LDAP_DEFAULT_ROLE
+func (p *LDAPProvider) mapRole(groups []string) string {
+	for _, mapping := range p.groupRoles {
+		for _, group := range groups {
+			if strings.EqualFold(mapping.group, group) || strings.EqualFold(mapping.group, ldap.FirstRDNValue(group)) {
+				return mapping.role
+			}
+		}
+	}
+	return p.defaultRole
+}
+
+// sync: samakan role, email & nama user lokal dengan direktori.
+// Tanpa grup yang cocok (dan tanpa default role) role yang diatur admin dipertahankan.
+func (p *LDAPProvider) sync(user *postgre.User, identity *ldap.Identity, roleName string) {
+	if roleName != "" && roleName != user.Role.Name {
+		role, err := p.userRepo.FindRoleByName(roleName)
+		if err != nil {
+			log.Println("⚠️ LDAP: role tidak ditemukan:", roleName)
+		} else if err := p.userRepo.UpdateRole(user.ID, role.ID); err != nil {
+			log.Println("⚠️ LDAP: gagal memperbarui role", user.Username, err)
+		} else {
+			log.Printf("✅ LDAP: role %s diubah %s -> %s", user.Username, user.Role.Name, role.Name)
+			user.RoleID = role.ID
+			user.Role = *role
+			p.authService.InvalidateUserSessions(user.ID)
+		}
+	}
+
+	email, fullName := user.Email, user.FullName
+	if identity.Email != "" {
+		email = truncate(identity.Email, 100)
+	}
+	if identity.FullName != "" {
+		fullName = truncate(identity.FullName, 100)
+	}
+	if email == user.Email && fullName == user.FullName {
+		return
+	}
+	if err := p.userRepo.SyncProfile(user.ID, email, fullName); err != nil {
+		log.Println("⚠️ LDAP: gagal sinkronisasi profil", user.Username, err)
+		return
+	}
+	user.Email = email
+	user.FullName = fullName
+}
+
+// provision: buat user saat login pertama (LDAP_AUTO_PROVISION), lengkap dengan profil Mahasiswa / Dosen Wali
+func (p *LDAPProvider) provision(identity *ldap.Identity, roleName string) (*postgre.User, error) {
+	if roleName == "" {
+		return nil, ErrLDAPNoRole
+	}
+
+	// Username di direktori bisa beda huruf besar/kecil dengan yang diketik saat login
+	if existing, err := p.userRepo.FindByUsername(identity.Username); err == nil {
+		if existing.AuthSource != postgre.AuthSourceLDAP {
+			log.Println("⚠️ LDAP: username sudah dipakai akun lokal:", identity.Username)
+			return nil, ErrLDAPProvisioning
+		}
+		p.sync(existing, identity, roleName)
+		return existing, nil
+	}
+	if identity.Email == "" {
+		log.Println("⚠️ LDAP: entry tanpa email:", identity.DN)
+		return nil, ErrLDAPProvisioning
+	}
+	if _, err := p.userRepo.FindByEmail(identity.Email); err == nil {
+		log.Println("⚠️ LDAP: email sudah dipakai akun lain:", identity.Email)
+		return nil, ErrLDAPProvisioning
+	}
+	role, err := p.userRepo.FindRoleByName(roleName)
+	if err != nil {
+		log.Println("⚠️ LDAP: role tidak ditemukan:", roleName)
+		return nil, ErrLDAPProvisioning
+	}
+
+	fullName := identity.FullName
+	if fullName == "" {
+		fullName = identity.Username
+	}
+	user := &postgre.User{
+		Username:     truncate(identity.Username, 50),
+		Email:        truncate(identity.Email, 100),
+		PasswordHash: unusablePasswordHash,
+		FullName:     truncate(fullName, 100),
+		RoleID:       role.ID,
+		IsActive:     true,
+		AuthSource:   postgre.AuthSourceLDAP,
+	}
+
+	// Mahasiswa & Dosen Wali butuh NIM / NIP (LDAP_PROFILE_ID_ATTRIBUTE)
+	var profile interface{}
+	profileID := ""
+	if p.profileAttr != "" {
+		profileID = truncate(identity.Entry.Get(p.profileAttr), 20)
+	}
+	switch role.Name {
+	case "Mahasiswa":
+		profile = &postgre.Student{ID: uuid.New(), NIM: profileID}
+	case "Dosen Wali":
+		profile = &postgre.Lecturer{ID: uuid.New(), LecturerID: profileID}
+	}
+	if profile != nil && profileID == "" {
+		log.Printf("⚠️ LDAP: entry %s tanpa atribut %s untuk profil %s", identity.DN, p.profileAttr, role.Name)
+		return nil, ErrLDAPProvisioning
+	}
+
+	if profile != nil {
+		err = p.userRepo.CreateWithProfile(user, profile)
+	} else {
+		err = p.userRepo.Create(user)
+	}
+	if err != nil {
+		log.Println("⚠️ LDAP: gagal membuat user", identity.Username, err)
+		return nil, ErrLDAPProvisioning
+	}
+	user.Role = *role
+	log.Printf("✅ User %s dibuat dari LDAP (role %s)", user.Username, role.Name)
+	return user, nil
+}
+
+// parseGroupRoles: "dosen=Dosen Wali;cn=admin,ou=groups,dc=x=Admin".
+// Dipisah di '=' terakhir karena DN grup sendiri mengandung '='.
+func parseGroupRoles(s string) []groupRole {
+	var out []groupRole
+	for _, entry := range strings.Split(s, ";") {
+		i := strings.LastIndex(entry, "=")
+		if i <= 0 {
+			continue
+		}
+		group, role := strings.TrimSpace(entry[:i]), strings.TrimSpace(entry[i+1:])
+		if group == "" || role == "" {
+			continue
+		}
+		out = append(out, groupRole{group: group, role: role})
+	}
+	return out
+}
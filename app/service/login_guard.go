@@ -0,0 +1,187 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"reportachievement/app/model/postgre"
+	repo "reportachievement/app/repository/postgre"
+	"reportachievement/config"
+
+	"github.com/google/uuid"
+)
+
+// Batas atas lockout akun, seberapa pun banyak kegagalan berikutnya
+const maxLockout = 24 * time.Hour
+
+// LoginThrottledError: login ditolak sebelum password dicek, client harus menunggu RetryAfter
+type LoginThrottledError struct {
+	RetryAfter time.Duration
+	Account    bool // true = akun dikunci, false = IP dibatasi
+}
+
+func (e *LoginThrottledError) Error() string {
+	if e.Account {
+		return "account temporarily locked due to too many failed login attempts"
+	}
+	return "too many failed login attempts, try again later"
+}
+
+// LoginGuard: proteksi brute-force.
+//   - Per akun: setelah MaxAttempts gagal berturut-turut akun dikunci Lockout,
+//     setiap kegagalan berikutnya setelah kunci berakhir menggandakan durasinya (maks 24 jam).
+//   - Per IP: maksimal IPMaxAttempts gagal (termasuk username tidak dikenal) dalam IPWindow.
+//
+// Semua percobaan dicatat di login_attempts.
+type LoginGuard struct {
+	attemptRepo *repo.LoginAttemptRepository
+	userRepo    *repo.UserRepository
+
+	maxAttempts   int
+	lockout       time.Duration
+	ipMaxAttempts int
+	ipWindow      time.Duration
+	retention     time.Duration
+}
+
+func NewLoginGuard(attemptRepo *repo.LoginAttemptRepository, userRepo *repo.UserRepository, cfg *config.Config) *LoginGuard {
+	return &LoginGuard{
+		attemptRepo:   attemptRepo,
+		userRepo:      userRepo,
+		maxAttempts:   cfg.LoginMaxAttempts,
+		lockout:       cfg.LoginLockout,
+		ipMaxAttempts: cfg.LoginIPMaxAttempts,
+		ipWindow:      cfg.LoginIPWindow,
+		retention:     cfg.LoginAttemptRetention,
+	}
+}
+
+// 1. CheckIP: dipanggil sebelum mencari user
+func (g *LoginGuard) CheckIP(ip string) error {
+	if g.ipMaxAttempts <= 0 || ip == "" {
+		return nil
+	}
+	failures, err := g.attemptRepo.RecentFailuresByIP(ip, time.Now().Add(-g.ipWindow), g.ipMaxAttempts)
+	if err != nil {
+		log.Println("⚠️ Gagal cek percobaan login per IP:", err)
+		return nil
+	}
+	if len(failures) < g.ipMaxAttempts {
+		return nil
+	}
+	// Terbuka lagi saat kegagalan ke-N (terlama dari N terakhir) keluar dari window
+	retry := time.Until(failures[len(failures)-1].Add(g.ipWindow))
+	return &LoginThrottledError{RetryAfter: max(retry, time.Second)}
+}
+
+// 2. CheckAccount: dipanggil sebelum password dicek, akun terkunci tidak bisa ditebak passwordnya
+func (g *LoginGuard) CheckAccount(user *postgre.User) error {
+	if user.LockedUntil == nil {
+		return nil
+	}
+	if retry := time.Until(*user.LockedUntil); retry > 0 {
+		return &LoginThrottledError{RetryAfter: retry, Account: true}
+	}
+	return nil
+}
+
+// 3. Failed: catat percobaan gagal. Password / kode 2FA salah menambah hitungan & bisa mengunci akun.
+func (g *LoginGuard) Failed(user *postgre.User, username string, client ClientInfo, reason string) {
+	g.record(user, username, client, false, reason)
+	counted := reason == postgre.LoginInvalidCredentials || reason == postgre.LoginInvalidMFACode
+	if user == nil || !counted || g.maxAttempts <= 0 {
+		return
+	}
+
+	count, err := g.userRepo.IncrementFailedLogin(user.ID)
+	if err != nil {
+		log.Println("⚠️ Gagal mencatat login gagal", user.Username, err)
+		return
+	}
+	if count < g.maxAttempts {
+		return
+	}
+	duration := g.lockoutDuration(count)
+	if err := g.userRepo.LockUntil(user.ID, time.Now().Add(duration)); err != nil {
+		log.Println("⚠️ Gagal mengunci akun", user.Username, err)
+		return
+	}
+	log.Printf("🔒 Akun %s dikunci %s setelah %d login gagal (IP %s)", user.Username, duration, count, client.IPAddress)
+}
+
+// 4. Succeeded: catat login sukses (reason: password / 2FA / SSO) & reset hitungan gagal
+func (g *LoginGuard) Succeeded(user *postgre.User, client ClientInfo, reason string) {
+	g.record(user, user.Username, client, true, reason)
+	if user.FailedLoginCount > 0 || user.LockedUntil != nil {
+		if err := g.userRepo.ResetLoginFailures(user.ID); err != nil {
+			log.Println("⚠️ Gagal reset hitungan login gagal", user.Username, err)
+		}
+	}
+}
+
+// 5. Unlock: admin membuka kunci akun sebelum waktunya
+func (g *LoginGuard) Unlock(userID uuid.UUID) error {
+	if _, err := g.userRepo.FindByID(userID); err != nil {
+		return errors.New("user not found")
+	}
+	return g.userRepo.ResetLoginFailures(userID)
+}
+
+// 6. ListAttempts: log percobaan login untuk admin
+func (g *LoginGuard) ListAttempts(filter repo.LoginAttemptFilter) ([]postgre.LoginAttempt, int64, error) {
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.Limit < 1 || filter.Limit > 100 {
+		filter.Limit = 20
+	}
+	return g.attemptRepo.FindAll(filter)
+}
+
+// StartCleanup: hapus log percobaan login yang melewati masa retensi, sekali sehari
+func (g *LoginGuard) StartCleanup(ctx context.Context) {
+	if g.retention <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(24 * time.Hour)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := g.attemptRepo.DeleteBefore(time.Now().Add(-g.retention)); err != nil {
+					log.Println("⚠️ Gagal membersihkan log percobaan login:", err)
+				}
+			}
+		}
+	}()
+}
+
+// lockoutDuration: Lockout untuk kegagalan ke-MaxAttempts, lalu berlipat dua setiap kegagalan berikutnya
+func (g *LoginGuard) lockoutDuration(failures int) time.Duration {
+	duration := g.lockout
+	for i := g.maxAttempts; i < failures && duration < maxLockout; i++ {
+		duration *= 2
+	}
+	return min(duration, maxLockout)
+}
+
+func (g *LoginGuard) record(user *postgre.User, username string, client ClientInfo, success bool, reason string) {
+	attempt := &postgre.LoginAttempt{
+		Username:  truncate(username, 100),
+		IPAddress: truncate(client.IPAddress, 45),
+		UserAgent: truncate(client.UserAgent, 255),
+		Success:   success,
+		Reason:    reason,
+	}
+	if user != nil {
+		attempt.UserID = &user.ID
+	}
+	if err := g.attemptRepo.Create(attempt); err != nil {
+		log.Println("⚠️ Gagal mencatat percobaan login:", err)
+	}
+}
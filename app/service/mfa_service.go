@@ -0,0 +1,329 @@
+package service
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/base64"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"reportachievement/app/model/postgre"
+	repo "reportachievement/app/repository/postgre"
+	"reportachievement/config"
+	"reportachievement/totp"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrMFANotEnrolled    = errors.New("2FA setup has not been started")
+	ErrMFAAlreadyEnabled = errors.New("2FA is already enabled")
+	ErrMFANotEnabled     = errors.New("2FA is not enabled")
+	ErrMFARequired       = errors.New("2FA is required for your role and cannot be disabled")
+	ErrInvalidMFACode    = errors.New("invalid 2FA code")
+)
+
+const (
+	recoveryCodeCount = 10
+	// Toleransi satu step (30 detik) sebelum/sesudah untuk jam HP yang tidak sinkron
+	totpSkew = 1
+)
+
+type MFAService struct {
+	userRepo    *repo.UserRepository
+	mfaRepo     *repo.MFARepository
+	authService *AuthService // Menerbitkan session setelah kode valid & membuang cache status session
+	guard       *LoginGuard
+	issuer      string
+	key         [32]byte // AES-256-GCM untuk secret TOTP di database
+}
+
+func NewMFAService(userRepo *repo.UserRepository, mfaRepo *repo.MFARepository, authService *AuthService, guard *LoginGuard, cfg *config.Config) *MFAService {
+	// Kunci dari repo berarti secret TOTP di database bisa didekripsi siapa pun
+	if cfg.MFAEncryptionKey == "" || cfg.MFAEncryptionKey == config.DefaultMFAEncryptionKey {
+		if cfg.AppEnv != "development" {
+			log.Fatal("❌ MFA_ENCRYPTION_KEY kosong atau masih default, isi dengan kunci acak sebelum menjalankan aplikasi")
+		}
+		log.Println("⚠️ MFA_ENCRYPTION_KEY kosong atau masih default, jangan dipakai selain development")
+	}
+	return &MFAService{
+		userRepo:    userRepo,
+		mfaRepo:     mfaRepo,
+		authService: authService,
+		guard:       guard,
+		issuer:      cfg.MFAIssuer,
+		key:         sha256.Sum256([]byte(cfg.MFAEncryptionKey)),
+	}
+}
+
+type MFAStatus struct {
+	Enabled                bool       `json:"enabled"`
+	EnabledAt              *time.Time `json:"enabled_at,omitempty"`
+	Required               bool       `json:"required"` // diwajibkan role (permission auth:require_2fa)
+	RecoveryCodesRemaining int64      `json:"recovery_codes_remaining"`
+}
+
+type MFASetup struct {
+	Secret          string `json:"secret"`           // untuk input manual di aplikasi authenticator
+	ProvisioningURI string `json:"provisioning_uri"` // otpauth://, ditampilkan sebagai QR code
+}
+
+// 1. Status
+func (s *MFAService) Status(userID uuid.UUID) (*MFAStatus, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+	status := &MFAStatus{
+		Enabled:   user.TOTPEnabledAt != nil,
+		EnabledAt: user.TOTPEnabledAt,
+		Required:  s.authService.MFARequired(user.Role.Name),
+	}
+	if status.Enabled {
+		if status.RecoveryCodesRemaining, err = s.mfaRepo.CountUnused(user.ID); err != nil {
+			return nil, err
+		}
+	}
+	return status, nil
+}
+
+// 2. Setup: buat secret baru (belum aktif sampai Enable). Setup ulang mengganti secret sebelumnya.
+func (s *MFAService) Setup(userID uuid.UUID) (*MFASetup, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+	if user.TOTPEnabledAt != nil {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := s.seal(secret)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.userRepo.SetTOTP(user.ID, sealed, nil); err != nil {
+		return nil, err
+	}
+	return &MFASetup{Secret: secret, ProvisioningURI: totp.ProvisioningURI(s.issuer, user.Username, secret)}, nil
+}
+
+// 3. Enable: konfirmasi kode pertama dari aplikasi authenticator.
+// Recovery codes dikembalikan di sini dan tidak bisa dilihat lagi.
+func (s *MFAService) Enable(userID uuid.UUID, code string) ([]string, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+	if user.TOTPEnabledAt != nil {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	if user.TOTPSecret == "" {
+		return nil, ErrMFANotEnrolled
+	}
+	secret, err := s.open(user.TOTPSecret)
+	if err != nil {
+		return nil, err
+	}
+	step, ok := totp.Validate(secret, code, time.Now(), totpSkew)
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	now := time.Now()
+	if err := s.userRepo.SetTOTP(user.ID, user.TOTPSecret, &now); err != nil {
+		return nil, err
+	}
+	// Kode konfirmasi tidak bisa dipakai lagi untuk login
+	if _, err := s.userRepo.UseTOTPStep(user.ID, step); err != nil {
+		return nil, err
+	}
+	codes, err := s.newRecoveryCodes(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	s.authService.InvalidateUserSessions(user.ID)
+	return codes, nil
+}
+
+// 4. Disable: butuh password & kode 2FA (atau recovery code), ditolak jika role mewajibkan 2FA
+func (s *MFAService) Disable(userID uuid.UUID, currentPassword, code string) error {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return errors.New("user not found")
+	}
+	if user.TOTPEnabledAt == nil {
+		return ErrMFANotEnabled
+	}
+	if s.authService.MFARequired(user.Role.Name) {
+		return ErrMFARequired
+	}
+	// Password dicek lewat provider akun (bcrypt lokal / bind LDAP)
+	if err := s.authService.VerifyPassword(user, currentPassword); err != nil {
+		if errors.Is(err, ErrInvalidCredentials) {
+			return ErrInvalidCurrentPassword
+		}
+		return err
+	}
+	if !s.verify(user, code) {
+		return ErrInvalidMFACode
+	}
+	return s.clear(user.ID)
+}
+
+// 5. RegenerateRecoveryCodes: set lama tidak berlaku lagi, butuh kode TOTP
+func (s *MFAService) RegenerateRecoveryCodes(userID uuid.UUID, code string) ([]string, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+	if user.TOTPEnabledAt == nil {
+		return nil, ErrMFANotEnabled
+	}
+	if !s.verifyTOTP(user, code) {
+		return nil, ErrInvalidMFACode
+	}
+	return s.newRecoveryCodes(user.ID)
+}
+
+// 6. Reset: admin menghapus 2FA user (misal HP hilang).
+// Jika role mewajibkan 2FA, user harus setup ulang setelah login berikutnya.
+func (s *MFAService) Reset(userID uuid.UUID) error {
+	if _, err := s.userRepo.FindByID(userID); err != nil {
+		return errors.New("user not found")
+	}
+	return s.clear(userID)
+}
+
+// 7. CompleteLogin: langkah kedua login, tukar challenge token + kode TOTP / recovery code dengan JWT
+func (s *MFAService) CompleteLogin(challengeToken, code string, client ClientInfo) (map[string]interface{}, error) {
+	userID, err := s.authService.parseMFAChallenge(challengeToken)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil || !user.IsActive || user.TOTPEnabledAt == nil {
+		return nil, ErrInvalidMFAChallenge
+	}
+
+	// Kode 2FA ikut dibatasi seperti password (lockout akun & throttling IP)
+	if err := s.guard.CheckIP(client.IPAddress); err != nil {
+		s.guard.Failed(user, user.Username, client, postgre.LoginIPThrottled)
+		return nil, err
+	}
+	if err := s.guard.CheckAccount(user); err != nil {
+		s.guard.Failed(user, user.Username, client, postgre.LoginAccountLocked)
+		return nil, err
+	}
+	if !s.verify(user, code) {
+		s.guard.Failed(user, user.Username, client, postgre.LoginInvalidMFACode)
+		return nil, ErrInvalidMFACode
+	}
+
+	s.guard.Succeeded(user, client, postgre.LoginSuccessMFA)
+	return s.authService.newSession(user, client)
+}
+
+// verify: kode TOTP 6 digit atau recovery code (sekali pakai)
+func (s *MFAService) verify(user *postgre.User, code string) bool {
+	code = strings.TrimSpace(code)
+	if len(code) == totp.Digits {
+		return s.verifyTOTP(user, code)
+	}
+	used, err := s.mfaRepo.UseRecoveryCode(user.ID, hashToken(normalizeRecoveryCode(code)))
+	return err == nil && used
+}
+
+// verifyTOTP: kode valid dan step-nya belum pernah dipakai (cegah replay)
+func (s *MFAService) verifyTOTP(user *postgre.User, code string) bool {
+	secret, err := s.open(user.TOTPSecret)
+	if err != nil {
+		return false
+	}
+	step, ok := totp.Validate(secret, code, time.Now(), totpSkew)
+	if !ok {
+		return false
+	}
+	used, err := s.userRepo.UseTOTPStep(user.ID, step)
+	return err == nil && used
+}
+
+func (s *MFAService) clear(userID uuid.UUID) error {
+	if err := s.userRepo.SetTOTP(userID, "", nil); err != nil {
+		return err
+	}
+	if err := s.mfaRepo.DeleteByUser(userID); err != nil {
+		return err
+	}
+	s.authService.InvalidateUserSessions(userID)
+	return nil
+}
+
+// newRecoveryCodes: format "xxxxx-xxxxx" (50 bit), yang disimpan hanya hash-nya
+func (s *MFAService) newRecoveryCodes(userID uuid.UUID) ([]string, error) {
+	encoding := base32.StdEncoding.WithPadding(base32.NoPadding)
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		b := make([]byte, 7)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		raw := strings.ToLower(encoding.EncodeToString(b))[:10]
+		codes = append(codes, raw[:5]+"-"+raw[5:])
+		hashes = append(hashes, hashToken(raw))
+	}
+	if err := s.mfaRepo.ReplaceRecoveryCodes(userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
+
+// seal: AES-GCM, hasil base64(nonce || ciphertext)
+func (s *MFAService) seal(plaintext string) (string, error) {
+	gcm, err := s.cipher()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, []byte(plaintext), nil)), nil
+}
+
+func (s *MFAService) open(sealed string) (string, error) {
+	gcm, err := s.cipher()
+	if err != nil {
+		return "", err
+	}
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil || len(data) < gcm.NonceSize() {
+		return "", errors.New("invalid TOTP secret")
+	}
+	plaintext, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", errors.New("cannot decrypt TOTP secret (MFA_ENCRYPTION_KEY changed?)")
+	}
+	return string(plaintext), nil
+}
+
+func (s *MFAService) cipher() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(s.key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,212 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"reportachievement/app/model/postgre"
+	repo "reportachievement/app/repository/postgre"
+	"reportachievement/config"
+	"reportachievement/oidc"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrOIDCDisabled     = errors.New("SSO login is not configured")
+	ErrOIDCState        = errors.New("invalid or expired SSO login state, please try again")
+	ErrOIDCUserNotFound = errors.New("no account is linked to this SSO identity")
+)
+
+const (
+	// State login SSO disimpan di cookie sebagai token bertanda tangan (tanpa state di server)
+	oidcStateTTL  = 10 * time.Minute
+	oidcStateType = "oidc_state"
+	// Bukan hash bcrypt: user hasil provisioning tidak bisa login dengan password
+	unusablePasswordHash = "!"
+)
+
+// OIDCService: login lewat SSO kampus (authorization code + PKCE).
+// Lockout akun & 2FA lokal tetap berlaku seperti login password, kecuali OIDC_TRUST_PROVIDER_MFA
+// menyatakan MFA sudah diwajibkan provider.
+type OIDCService struct {
+	provider    *oidc.Provider
+	userRepo    *repo.UserRepository
+	authService *AuthService
+	guard       *LoginGuard
+
+	usernameClaim    string
+	autoProvision    bool
+	defaultRole      string
+	trustProviderMFA bool
+}
+
+func NewOIDCService(provider *oidc.Provider, userRepo *repo.UserRepository, authService *AuthService, guard *LoginGuard, cfg *config.Config) *OIDCService {
+	return &OIDCService{
+		provider:         provider,
+		userRepo:         userRepo,
+		authService:      authService,
+		guard:            guard,
+		usernameClaim:    cfg.OIDCUsernameClaim,
+		autoProvision:    cfg.OIDCAutoProvision,
+		defaultRole:      cfg.OIDCDefaultRole,
+		trustProviderMFA: cfg.OIDCTrustProviderMFA,
+	}
+}
+
+func (s *OIDCService) Enabled() bool {
+	return s.provider != nil
+}
+
+// 1. Start: URL login provider + state token (disimpan client sebagai cookie sampai callback)
+func (s *OIDCService) Start(ctx context.Context) (authURL, stateToken string, err error) {
+	if !s.Enabled() {
+		return "", "", ErrOIDCDisabled
+	}
+	state, err := oidc.RandomString()
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := oidc.RandomString()
+	if err != nil {
+		return "", "", err
+	}
+	verifier, challenge, err := oidc.NewPKCE()
+	if err != nil {
+		return "", "", err
+	}
+
+	authURL, err = s.provider.AuthCodeURL(ctx, state, nonce, challenge)
+	if err != nil {
+		return "", "", err
+	}
+	stateToken, err = s.authService.keys.Sign(jwt.MapClaims{
+		"typ":      oidcStateType,
+		"state":    state,
+		"nonce":    nonce,
+		"verifier": verifier,
+		"iat":      time.Now().Unix(),
+		"exp":      time.Now().Add(oidcStateTTL).Unix(),
+	})
+	return authURL, stateToken, err
+}
+
+// 2. Callback: cocokkan state, tukar code, petakan identitas SSO ke user lalu terbitkan JWT kita
+func (s *OIDCService) Callback(ctx context.Context, stateToken, state, code string, client ClientInfo) (map[string]interface{}, error) {
+	if !s.Enabled() {
+		return nil, ErrOIDCDisabled
+	}
+	saved, err := s.authService.keys.Parse(stateToken)
+	if err != nil {
+		return nil, ErrOIDCState
+	}
+	savedState, _ := saved["state"].(string)
+	if typ, _ := saved["typ"].(string); typ != oidcStateType || savedState == "" ||
+		subtle.ConstantTimeCompare([]byte(savedState), []byte(state)) != 1 {
+		return nil, ErrOIDCState
+	}
+	verifier, _ := saved["verifier"].(string)
+	nonce, _ := saved["nonce"].(string)
+
+	identity, err := s.provider.Exchange(ctx, code, verifier, nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.resolveUser(identity)
+	if err != nil {
+		s.guard.Failed(nil, identityLabel(identity), client, postgre.LoginOIDCNoAccount)
+		return nil, err
+	}
+	return s.login(user, client)
+}
+
+// login: pemeriksaan setelah identitas SSO cocok dengan user, sama seperti login password
+func (s *OIDCService) login(user *postgre.User, client ClientInfo) (map[string]interface{}, error) {
+	if !user.IsActive {
+		s.guard.Failed(user, user.Username, client, postgre.LoginAccountInactive)
+		return nil, errors.New("account is inactive")
+	}
+	// Akun yang dikunci (percobaan gagal / admin) juga tertutup untuk SSO
+	if err := s.guard.CheckAccount(user); err != nil {
+		s.guard.Failed(user, user.Username, client, postgre.LoginAccountLocked)
+		return nil, err
+	}
+
+	// 2FA aktif: sama seperti Login, JWT baru diterbitkan setelah kode diverifikasi (MFAService.CompleteLogin)
+	if user.TOTPEnabledAt != nil && !s.trustProviderMFA {
+		return s.authService.mfaChallenge(user)
+	}
+
+	s.guard.Succeeded(user, client, postgre.LoginSuccessOIDC)
+	return s.authService.newSession(user, client)
+}
+
+// resolveUser: email terverifikasi -> username (OIDC_USERNAME_CLAIM) -> provisioning (jika diaktifkan)
+func (s *OIDCService) resolveUser(identity *oidc.Claims) (*postgre.User, error) {
+	if identity.Email != "" && identity.EmailVerified {
+		if user, err := s.userRepo.FindByEmail(identity.Email); err == nil {
+			return user, nil
+		}
+	}
+	username := s.claimUsername(identity)
+	if username != "" {
+		if user, err := s.userRepo.FindByUsername(username); err == nil {
+			return user, nil
+		}
+	}
+
+	// User baru butuh email unik yang terverifikasi
+	if !s.autoProvision || identity.Email == "" || !identity.EmailVerified {
+		return nil, ErrOIDCUserNotFound
+	}
+	role, err := s.userRepo.FindRoleByName(s.defaultRole)
+	if err != nil {
+		return nil, errors.New("invalid OIDC_DEFAULT_ROLE: " + s.defaultRole)
+	}
+	if username == "" {
+		username, _, _ = strings.Cut(identity.Email, "@")
+	}
+	fullName := identity.Name
+	if fullName == "" {
+		fullName = username
+	}
+
+	user := &postgre.User{
+		Username:     truncate(username, 50),
+		Email:        truncate(identity.Email, 100),
+		PasswordHash: unusablePasswordHash,
+		FullName:     truncate(fullName, 100),
+		RoleID:       role.ID,
+		IsActive:     true,
+	}
+	if err := s.userRepo.Create(user); err != nil {
+		return nil, err
+	}
+	user.Role = *role
+	log.Printf("✅ User %s dibuat dari SSO (role %s)", user.Username, role.Name)
+	return user, nil
+}
+
+func (s *OIDCService) claimUsername(identity *oidc.Claims) string {
+	if s.usernameClaim == "" {
+		return ""
+	}
+	username, _ := identity.Raw[s.usernameClaim].(string)
+	return strings.TrimSpace(username)
+}
+
+// identityLabel: identitas yang dicatat di log percobaan login jika tidak ada user yang cocok
+func identityLabel(identity *oidc.Claims) string {
+	if identity.Email != "" {
+		return identity.Email
+	}
+	if identity.PreferredUsername != "" {
+		return identity.PreferredUsername
+	}
+	return identity.Subject
+}
@@ -0,0 +1,231 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"reportachievement/app/model/postgre"
+	repo "reportachievement/app/repository/postgre"
+	"reportachievement/config"
+	"reportachievement/mailer"
+	"reportachievement/password"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrInvalidCurrentPassword = errors.New("current password is incorrect")
+	ErrInvalidResetToken      = errors.New("invalid or expired reset token")
+	ErrPasswordUnchanged      = errors.New("new password must be different from the current password")
+	ErrExternalPassword       = errors.New("the password of this account is managed by the campus directory (LDAP), change it there")
+)
+
+// Permintaan reset berulang untuk user yang sama diabaikan selama cooldown (cegah spam email)
+const passwordResetCooldown = time.Minute
+
+type PasswordService struct {
+	userRepo    *repo.UserRepository
+	resetRepo   *repo.PasswordResetRepository
+	authService *AuthService
+	mailer      mailer.Mailer
+	policy      *password.Policy
+	resetURL    string
+	resetTTL    time.Duration
+}
+
+func NewPasswordService(userRepo *repo.UserRepository, resetRepo *repo.PasswordResetRepository, authService *AuthService, mail mailer.Mailer, policy *password.Policy, cfg *config.Config) *PasswordService {
+	return &PasswordService{
+		userRepo:    userRepo,
+		resetRepo:   resetRepo,
+		authService: authService,
+		mailer:      mail,
+		policy:      policy,
+		resetURL:    cfg.PasswordResetURL,
+		resetTTL:    cfg.PasswordResetTTL,
+	}
+}
+
+// 1. ChangePassword: user login mengganti password sendiri.
+// Session lain dicabut, session yang sedang dipakai tetap login dan status wajib ganti password dihapus.
+func (s *PasswordService) ChangePassword(userID, sessionID uuid.UUID, currentPassword, newPassword string) error {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return errors.New("user not found")
+	}
+	if isExternalAccount(user) {
+		return ErrExternalPassword
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(currentPassword)); err != nil {
+		return ErrInvalidCurrentPassword
+	}
+	if newPassword == currentPassword {
+		return ErrPasswordUnchanged
+	}
+	if err := s.setPassword(user, newPassword); err != nil {
+		return err
+	}
+	// Link reset yang masih beredar tidak boleh menimpa password baru
+	if err := s.resetRepo.InvalidateByUser(user.ID); err != nil {
+		return err
+	}
+	return s.authService.RevokeOtherSessions(user.ID, sessionID, "password changed")
+}
+
+// 2. RequestReset: lupa password. Selalu sukses dari sisi client agar email terdaftar tidak bisa ditebak,
+// email dikirim di background.
+func (s *PasswordService) RequestReset(email, requestIP string) error {
+	user, err := s.userRepo.FindByEmail(strings.TrimSpace(email))
+	// Akun LDAP: tetap diam agar email terdaftar tidak bisa ditebak
+	if err != nil || !user.IsActive || isExternalAccount(user) {
+		return nil
+	}
+	if latest, err := s.resetRepo.FindLatestByUser(user.ID); err == nil && time.Since(latest.CreatedAt) < passwordResetCooldown {
+		return nil
+	}
+
+	msg, err := s.issueResetToken(user, requestIP)
+	if err != nil {
+		return err
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		if err := s.mailer.Send(ctx, msg); err != nil {
+			log.Println("⚠️ Gagal mengirim email reset password ke", user.Email, err)
+		}
+	}()
+	return nil
+}
+
+// 3. SendResetLink: admin mengirim link reset ke email user (error pengiriman dikembalikan)
+func (s *PasswordService) SendResetLink(ctx context.Context, userID uuid.UUID, requestIP string) error {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return errors.New("user not found")
+	}
+	if !user.IsActive {
+		return errors.New("account is inactive")
+	}
+	if isExternalAccount(user) {
+		return ErrExternalPassword
+	}
+	msg, err := s.issueResetToken(user, requestIP)
+	if err != nil {
+		return err
+	}
+	return s.mailer.Send(ctx, msg)
+}
+
+// 4. ResetPassword: tukar token dari email dengan password baru, semua session dicabut
+func (s *PasswordService) ResetPassword(token, newPassword string) error {
+	reset, err := s.resetRepo.FindByHash(hashToken(token))
+	if err != nil || reset.UsedAt != nil || time.Now().After(reset.ExpiresAt) {
+		return ErrInvalidResetToken
+	}
+	user, err := s.userRepo.FindByID(reset.UserID)
+	if err != nil {
+		return ErrInvalidResetToken
+	}
+	// Token terbit sebelum akun dipindah ke LDAP
+	if isExternalAccount(user) {
+		return ErrExternalPassword
+	}
+	// Validasi sebelum token dipakai, agar user bisa mencoba password lain dengan link yang sama
+	if err := s.policy.Validate(newPassword, user.Username, user.Email); err != nil {
+		return err
+	}
+	used, err := s.resetRepo.MarkUsed(reset.ID)
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidResetToken
+	}
+
+	if err := s.setPassword(user, newPassword); err != nil {
+		return err
+	}
+	// Link lain yang terbit bersamaan (misal dikirim admin) ikut dibatalkan
+	if err := s.resetRepo.InvalidateByUser(user.ID); err != nil {
+		return err
+	}
+	// Pemilik email terbukti, kunci akun karena login gagal ikut dibuka
+	if err := s.userRepo.ResetLoginFailures(user.ID); err != nil {
+		return err
+	}
+	return s.authService.RevokeAllSessions(reset.UserID, "password reset")
+}
+
+// StartCleanup: hapus token kedaluwarsa setiap jam
+func (s *PasswordService) StartCleanup(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := s.resetRepo.DeleteExpired(time.Now()); err != nil {
+					log.Println("⚠️ Gagal membersihkan token reset password:", err)
+				}
+			}
+		}
+	}()
+}
+
+// setPassword: validasi policy, hash dengan cost dari config, hapus status wajib ganti password
+func (s *PasswordService) setPassword(user *postgre.User, newPassword string) error {
+	if err := s.policy.Validate(newPassword, user.Username, user.Email); err != nil {
+		return err
+	}
+	hashed, err := s.policy.Hash(newPassword)
+	if err != nil {
+		return err
+	}
+	return s.userRepo.UpdatePassword(user.ID, hashed, false)
+}
+
+// issueResetToken: simpan hash token baru dan siapkan email berisi link reset
+func (s *PasswordService) issueResetToken(user *postgre.User, requestIP string) (mailer.Message, error) {
+	token, tokenHash, err := newOpaqueToken()
+	if err != nil {
+		return mailer.Message{}, err
+	}
+	reset := &postgre.PasswordResetToken{
+		UserID:      user.ID,
+		TokenHash:   tokenHash,
+		RequestedIP: truncate(requestIP, 45),
+		ExpiresAt:   time.Now().Add(s.resetTTL),
+	}
+	if err := s.resetRepo.Create(reset); err != nil {
+		return mailer.Message{}, err
+	}
+
+	separator := "?"
+	if strings.Contains(s.resetURL, "?") {
+		separator = "&"
+	}
+	link := s.resetURL + separator + "token=" + url.QueryEscape(token)
+
+	return mailer.Message{
+		To:      user.Email,
+		Subject: "Reset Password - Sistem Pelaporan Prestasi",
+		Body: fmt.Sprintf("Halo %s,\n\n"+
+			"Kami menerima permintaan untuk mengatur ulang password akun %s.\n"+
+			"Buka link berikut dalam %d menit:\n\n%s\n\n"+
+			"Link hanya dapat dipakai sekali. Abaikan email ini jika Anda tidak memintanya.\n",
+			user.FullName, user.Username, int(s.resetTTL.Minutes()), link),
+	}, nil
+}
+
+// isExternalAccount: password diverifikasi provider lain (LDAP), tidak bisa diganti/di-reset di sini
+func isExternalAccount(user *postgre.User) bool {
+	return user.AuthSource != "" && user.AuthSource != postgre.AuthSourceLocal
+}
@@ -0,0 +1,102 @@
+package service
+
+import (
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	repo "reportachievement/app/repository/postgre"
+)
+
+// AccessScope: cakupan data prestasi/SKPI yang boleh dilihat, ditentukan dari permission achievement:read_*
+type AccessScope int
+
+const (
+	ScopeNone    AccessScope = iota
+	ScopeOwn                 // achievement:read_own, milik mahasiswa sendiri
+	ScopeAdvisee             // achievement:read_advisee, mahasiswa bimbingan dosen wali
+	ScopeAll                 // achievement:read_all
+)
+
+// ScopeFromPermissions: ambil cakupan terluas yang dimiliki user
+func ScopeFromPermissions(has func(permission string) bool) AccessScope {
+	switch {
+	case has("achievement:read_all"):
+		return ScopeAll
+	case has("achievement:read_advisee"):
+		return ScopeAdvisee
+	case has("achievement:read_own"):
+		return ScopeOwn
+	}
+	return ScopeNone
+}
+
+// Mapping role -> permission jarang berubah, dimuat ulang paling lambat setiap TTL
+// (atau langsung lewat Invalidate saat diubah dari aplikasi ini).
+const permissionCacheTTL = time.Minute
+
+type PermissionService struct {
+	permRepo *repo.PermissionRepository
+
+	mu       sync.RWMutex
+	byRole   map[string]map[string]bool
+	loadedAt time.Time
+}
+
+func NewPermissionService(permRepo *repo.PermissionRepository) *PermissionService {
+	return &PermissionService{permRepo: permRepo}
+}
+
+// HasPermission: dipakai middleware.RequirePermission
+func (s *PermissionService) HasPermission(role, permission string) bool {
+	return s.roleMap()[role][permission]
+}
+
+// Permissions: daftar permission milik role (urut nama), misal untuk profil user di frontend
+func (s *PermissionService) Permissions(role string) []string {
+	names := make([]string, 0)
+	for name := range s.roleMap()[role] {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Invalidate: paksa muat ulang pada pengecekan berikutnya
+func (s *PermissionService) Invalidate() {
+	s.mu.Lock()
+	s.loadedAt = time.Time{}
+	s.mu.Unlock()
+}
+
+func (s *PermissionService) roleMap() map[string]map[string]bool {
+	s.mu.RLock()
+	byRole, fresh := s.byRole, time.Since(s.loadedAt) < permissionCacheTTL
+	s.mu.RUnlock()
+	if fresh {
+		return byRole
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if time.Since(s.loadedAt) < permissionCacheTTL {
+		return s.byRole // sudah dimuat goroutine lain
+	}
+
+	rows, err := s.permRepo.FindAllRolePermissions()
+	if err != nil {
+		// DB bermasalah: pakai data lama jika ada (kosong = semua ditolak), coba lagi request berikutnya
+		log.Println("⚠️ Gagal memuat permission:", err)
+		return s.byRole
+	}
+	loaded := make(map[string]map[string]bool)
+	for _, row := range rows {
+		if loaded[row.RoleName] == nil {
+			loaded[row.RoleName] = make(map[string]bool)
+		}
+		loaded[row.RoleName][row.PermissionName] = true
+	}
+	s.byRole, s.loadedAt = loaded, time.Now()
+	return loaded
+}
@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	postgreModel "reportachievement/app/model/postgre"
 	mongoRepo "reportachievement/app/repository/mongo"
 	postgreRepo "reportachievement/app/repository/postgre"
 
@@ -96,3 +97,63 @@ func (s *ReportService) GetDashboardStats(ctx context.Context) (*DashboardStats,
 		AchievementsByType: typeMap,
 	}, nil
 }
+
+// --- DUPLICATE EVIDENCE ---
+
+type DuplicateEvidenceDTO struct {
+	Hash         string                    `json:"hash"` // SHA-256 isi file
+	Achievements []DuplicateAchievementDTO `json:"achievements"`
+}
+
+type DuplicateAchievementDTO struct {
+	MongoID     string `json:"mongo_id"`
+	Title       string `json:"title"`
+	FileName    string `json:"file_name"`
+	StudentName string `json:"student_name"`
+	NIM         string `json:"nim"`
+}
+
+// GetDuplicateEvidence: file bukti identik (hash sama) yang dipakai lebih dari satu mahasiswa
+func (s *ReportService) GetDuplicateEvidence(ctx context.Context) ([]DuplicateEvidenceDTO, error) {
+	duplicates, err := s.mongoRepo.GetDuplicateEvidence(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var studentIDs []uuid.UUID
+	for _, dup := range duplicates {
+		for _, id := range dup.StudentIDs {
+			if parsed, err := uuid.Parse(id); err == nil {
+				studentIDs = append(studentIDs, parsed)
+			}
+		}
+	}
+
+	studentMap := make(map[string]postgreModel.Student)
+	if len(studentIDs) > 0 {
+		students, err := s.studentRepo.FindByIDs(studentIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, stu := range students {
+			studentMap[stu.ID.String()] = stu
+		}
+	}
+
+	result := []DuplicateEvidenceDTO{}
+	for _, dup := range duplicates {
+		item := DuplicateEvidenceDTO{Hash: dup.Hash}
+		for _, ach := range dup.Achievements {
+			stu := studentMap[ach.StudentPostgresID]
+			item.Achievements = append(item.Achievements, DuplicateAchievementDTO{
+				MongoID:     ach.ID.Hex(),
+				Title:       ach.Title,
+				FileName:    ach.FileName,
+				StudentName: stu.User.FullName,
+				NIM:         stu.NIM,
+			})
+		}
+		result = append(result, item)
+	}
+	return result, nil
+}
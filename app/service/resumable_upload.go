@@ -0,0 +1,394 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"reportachievement/config"
+)
+
+// Error resumable upload (dipetakan ke status tus oleh handler)
+var (
+	ErrUploadNotFound       = errors.New("upload not found")
+	ErrUploadOffsetMismatch = errors.New("upload offset does not match the current offset")
+	ErrUploadLocked         = errors.New("upload is being written by another request")
+	ErrUploadExceedsLength  = errors.New("chunk exceeds the declared upload length")
+	ErrUploadCompleted      = errors.New("upload already completed")
+)
+
+const uploadCleanupInterval = time.Hour
+
+var uploadIDPattern = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+// ResumableUpload: status satu upload tus. Isi file ada di <dir>/<id>.bin,
+// metadata ini di <dir>/<id>.info sehingga upload tetap bisa dilanjutkan setelah restart.
+type ResumableUpload struct {
+	ID            string         `json:"id"`
+	AchievementID uuid.UUID      `json:"achievement_id"`
+	UserID        uuid.UUID      `json:"user_id"`
+	FileName      string         `json:"file_name"`
+	Length        int64          `json:"length"`
+	Offset        int64          `json:"offset"`
+	ContentType   string         `json:"content_type,omitempty"` // hasil sniffing chunk pertama
+	CreatedAt     time.Time      `json:"created_at"`
+	ExpiresAt     time.Time      `json:"expires_at"`
+	Attachment    *AttachmentDTO `json:"attachment,omitempty"` // terisi setelah upload selesai
+}
+
+// ResumableUploadService: upload evidence bertahap (protokol tus 1.0.0) untuk file besar
+// dan koneksi tidak stabil. Setelah semua byte diterima file diteruskan ke
+// AchievementService.UploadEvidence sehingga validasi & cek kepemilikan tetap sama.
+type ResumableUploadService struct {
+	achService *AchievementService
+	policy     EvidencePolicy
+	dir        string
+	ttl        time.Duration
+
+	mu    sync.Mutex
+	locks map[string]*sync.Mutex
+}
+
+func NewResumableUploadService(cfg *config.Config, achService *AchievementService, policy EvidencePolicy) (*ResumableUploadService, error) {
+	if err := os.MkdirAll(cfg.TusUploadDir, 0o750); err != nil {
+		return nil, err
+	}
+	return &ResumableUploadService{
+		achService: achService,
+		policy:     policy,
+		dir:        cfg.TusUploadDir,
+		ttl:        cfg.TusUploadTTL,
+		locks:      make(map[string]*sync.Mutex),
+	}, nil
+}
+
+// MaxSize: batas Upload-Length (tipe file belum diketahui saat upload dibuat)
+func (s *ResumableUploadService) MaxSize() int64 {
+	return s.policy.MaxSize()
+}
+
+// 1. Create: daftarkan upload baru, kepemilikan & status prestasi dicek di awal
+// agar mahasiswa tidak mengirim ratusan MB lalu baru ditolak
+func (s *ResumableUploadService) Create(userID uuid.UUID, achievementID uuid.UUID, length int64, fileName string) (*ResumableUpload, error) {
+	if _, err := s.achService.findEditableAchievement(userID, achievementID, "upload evidence"); err != nil {
+		return nil, err
+	}
+	if length <= 0 {
+		return nil, ErrEmptyFile
+	}
+	if length > s.MaxSize() {
+		return nil, ErrFileTooLarge
+	}
+
+	id, err := newUploadID()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	upload := &ResumableUpload{
+		ID:            id,
+		AchievementID: achievementID,
+		UserID:        userID,
+		FileName:      fileName,
+		Length:        length,
+		CreatedAt:     now,
+		ExpiresAt:     now.Add(s.ttl),
+	}
+
+	f, err := os.OpenFile(s.dataPath(id), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o640)
+	if err != nil {
+		return nil, err
+	}
+	f.Close()
+	if err := s.saveInfo(upload); err != nil {
+		os.Remove(s.dataPath(id))
+		return nil, err
+	}
+	return upload, nil
+}
+
+// 2. Get: status upload (untuk HEAD), hanya pemilik upload yang boleh melihat
+func (s *ResumableUploadService) Get(userID uuid.UUID, achievementID uuid.UUID, uploadID string) (*ResumableUpload, error) {
+	upload, err := s.load(uploadID)
+	if err != nil {
+		return nil, err
+	}
+	if upload.UserID != userID || upload.AchievementID != achievementID {
+		return nil, ErrUploadNotFound
+	}
+	return upload, nil
+}
+
+// 3. Append: tulis satu chunk mulai dari offset. Byte yang sudah diterima tetap disimpan
+// walau koneksi putus di tengah chunk, client melanjutkan dari offset terakhir (HEAD).
+func (s *ResumableUploadService) Append(ctx context.Context, userID uuid.UUID, achievementID uuid.UUID, uploadID string, offset int64, chunk io.Reader) (*ResumableUpload, error) {
+	lock := s.lock(uploadID)
+	if lock == nil {
+		return nil, ErrUploadNotFound
+	}
+	if !lock.TryLock() {
+		return nil, ErrUploadLocked
+	}
+	defer lock.Unlock()
+
+	upload, err := s.Get(userID, achievementID, uploadID)
+	if err != nil {
+		return nil, err
+	}
+	if upload.Attachment != nil {
+		return upload, ErrUploadCompleted
+	}
+	if offset != upload.Offset {
+		return upload, ErrUploadOffsetMismatch
+	}
+
+	f, err := os.OpenFile(s.dataPath(uploadID), os.O_WRONLY|os.O_APPEND, 0o640)
+	if err != nil {
+		return nil, err
+	}
+	remaining := upload.Length - upload.Offset
+	written, copyErr := io.Copy(f, io.LimitReader(chunk, remaining))
+	closeErr := f.Close()
+	upload.Offset += written
+
+	// Sisa body melebihi Upload-Length
+	if copyErr == nil && written == remaining {
+		if n, _ := chunk.Read(make([]byte, 1)); n > 0 {
+			copyErr = ErrUploadExceedsLength
+		}
+	}
+	if copyErr == nil {
+		copyErr = closeErr
+	}
+
+	// Cek tipe & ukuran dari chunk pertama, tidak perlu menunggu upload selesai
+	if upload.ContentType == "" && upload.Offset >= min(upload.Length, 512) {
+		contentType, err := s.sniff(upload)
+		if err != nil {
+			s.remove(uploadID)
+			return nil, err
+		}
+		upload.ContentType = contentType
+	}
+
+	if err := s.saveInfo(upload); err != nil {
+		return nil, err
+	}
+	if copyErr != nil {
+		return upload, copyErr
+	}
+
+	if upload.Offset == upload.Length {
+		return s.complete(ctx, upload)
+	}
+	return upload, nil
+}
+
+// 4. Terminate: batalkan upload & hapus file sementara
+func (s *ResumableUploadService) Terminate(userID uuid.UUID, achievementID uuid.UUID, uploadID string) error {
+	lock := s.lock(uploadID)
+	if lock == nil {
+		return ErrUploadNotFound
+	}
+	if !lock.TryLock() {
+		return ErrUploadLocked
+	}
+	defer lock.Unlock()
+
+	if _, err := s.Get(userID, achievementID, uploadID); err != nil {
+		return err
+	}
+	s.remove(uploadID)
+	return nil
+}
+
+// StartCleanup: hapus upload yang kedaluwarsa (ditinggal client) secara berkala
+func (s *ResumableUploadService) StartCleanup(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(uploadCleanupInterval)
+		defer ticker.Stop()
+		for {
+			s.removeExpired()
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// complete: teruskan file utuh ke UploadEvidence (validasi, hash, storage, scan)
+func (s *ResumableUploadService) complete(ctx context.Context, upload *ResumableUpload) (*ResumableUpload, error) {
+	f, err := os.Open(s.dataPath(upload.ID))
+	if err != nil {
+		return nil, err
+	}
+	attachment, err := s.achService.UploadEvidence(ctx, upload.UserID, upload.AchievementID, EvidenceFile{
+		FileName: upload.FileName,
+		Size:     upload.Length,
+		Content:  f,
+	})
+	f.Close()
+	if err != nil {
+		// File tetap disimpan sampai expired: client bisa mengulang finalisasi dengan
+		// PATCH kosong di offset terakhir (misal saat MongoDB sempat tidak tersedia)
+		return upload, err
+	}
+
+	// Info tetap disimpan sampai expired agar client yang kehilangan response
+	// masih bisa melihat upload sudah selesai (HEAD / GET)
+	upload.Attachment = attachment
+	os.Remove(s.dataPath(upload.ID))
+	if err := s.saveInfo(upload); err != nil {
+		log.Println("⚠️ Gagal simpan status upload", upload.ID, ":", err)
+	}
+	// Tidak ada lagi yang ditulis, mutex tidak perlu disimpan sampai expired
+	s.forget(upload.ID)
+	return upload, nil
+}
+
+func (s *ResumableUploadService) sniff(upload *ResumableUpload) (string, error) {
+	f, err := os.Open(s.dataPath(upload.ID))
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(f, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return "", err
+	}
+	contentType, _, err := s.policy.checkContent(head[:n], upload.Length)
+	return contentType, err
+}
+
+func (s *ResumableUploadService) removeExpired() {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		log.Println("⚠️ Gagal membaca direktori upload:", err)
+		return
+	}
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".info")
+		if !ok {
+			continue
+		}
+		lock := s.lock(id)
+		if lock == nil || !lock.TryLock() {
+			continue // sedang ditulis, berarti masih aktif
+		}
+		upload, err := s.load(id)
+		if errors.Is(err, ErrUploadNotFound) {
+			s.remove(id)
+		} else if err == nil && upload.Attachment != nil {
+			s.forget(id) // selesai, info disimpan hanya untuk HEAD/GET
+		}
+		lock.Unlock()
+	}
+}
+
+// load: baca info upload, upload yang kedaluwarsa dianggap tidak ada
+func (s *ResumableUploadService) load(uploadID string) (*ResumableUpload, error) {
+	if !uploadIDPattern.MatchString(uploadID) {
+		return nil, ErrUploadNotFound
+	}
+	data, err := os.ReadFile(s.infoPath(uploadID))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrUploadNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var upload ResumableUpload
+	if err := json.Unmarshal(data, &upload); err != nil {
+		return nil, err
+	}
+	if time.Now().After(upload.ExpiresAt) {
+		return nil, ErrUploadNotFound
+	}
+
+	// Offset mengikuti ukuran file sebenarnya (info bisa tertinggal jika proses mati di tengah chunk)
+	if upload.Attachment == nil {
+		stat, err := os.Stat(s.dataPath(uploadID))
+		if err != nil {
+			return nil, ErrUploadNotFound
+		}
+		upload.Offset = stat.Size()
+	}
+	return &upload, nil
+}
+
+// saveInfo: tulis ke file sementara lalu rename agar info tidak pernah setengah tertulis
+func (s *ResumableUploadService) saveInfo(upload *ResumableUpload) error {
+	data, err := json.Marshal(upload)
+	if err != nil {
+		return err
+	}
+	tmp := s.infoPath(upload.ID) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o640); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.infoPath(upload.ID))
+}
+
+func (s *ResumableUploadService) remove(uploadID string) {
+	os.Remove(s.dataPath(uploadID))
+	os.Remove(s.infoPath(uploadID))
+	s.forget(uploadID)
+}
+
+// lock: satu mutex per upload, PATCH paralel ke upload yang sama ditolak (423).
+// nil jika upload tidak ada, agar ID acak dari client tidak menambah isi map.
+func (s *ResumableUploadService) lock(uploadID string) *sync.Mutex {
+	if !uploadIDPattern.MatchString(uploadID) {
+		return nil
+	}
+	if _, err := os.Stat(s.infoPath(uploadID)); err != nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	l, ok := s.locks[uploadID]
+	if !ok {
+		l = &sync.Mutex{}
+		s.locks[uploadID] = l
+	}
+	return l
+}
+
+func (s *ResumableUploadService) forget(uploadID string) {
+	s.mu.Lock()
+	delete(s.locks, uploadID)
+	s.mu.Unlock()
+}
+
+func (s *ResumableUploadService) dataPath(uploadID string) string {
+	return filepath.Join(s.dir, uploadID+".bin")
+}
+
+func (s *ResumableUploadService) infoPath(uploadID string) string {
+	return filepath.Join(s.dir, uploadID+".info")
+}
+
+func newUploadID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
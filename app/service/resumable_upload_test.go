@@ -0,0 +1,172 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestUploadService(t *testing.T) *ResumableUploadService {
+	return &ResumableUploadService{
+		policy: testEvidencePolicy(),
+		dir:    t.TempDir(),
+		ttl:    time.Hour,
+		locks:  map[string]*sync.Mutex{},
+	}
+}
+
+// newTestUpload: upload terdaftar tanpa lewat Create (yang butuh database untuk cek prestasi)
+func newTestUpload(t *testing.T, s *ResumableUploadService, length int64) *ResumableUpload {
+	id, err := newUploadID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	upload := &ResumableUpload{
+		ID:            id,
+		AchievementID: uuid.New(),
+		UserID:        uuid.New(),
+		FileName:      "bukti.pdf",
+		Length:        length,
+		CreatedAt:     time.Now(),
+		ExpiresAt:     time.Now().Add(s.ttl),
+	}
+	if err := os.WriteFile(s.dataPath(id), nil, 0o640); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.saveInfo(upload); err != nil {
+		t.Fatal(err)
+	}
+	return upload
+}
+
+func TestResumableUpload_OffsetMismatch(t *testing.T) {
+	s := newTestUploadService(t)
+	upload := newTestUpload(t, s, 2048)
+	ctx := context.Background()
+	content := evidenceContent(pdfHeader, 2048)
+
+	got, err := s.Append(ctx, upload.UserID, upload.AchievementID, upload.ID, 0, bytes.NewReader(content[:1000]))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, int64(1000), got.Offset)
+	assert.Equal(t, "application/pdf", got.ContentType)
+
+	// Client mengulang chunk lama / melompati byte: ditolak, offset server tidak berubah
+	for _, offset := range []int64{0, 500, 1500} {
+		got, err = s.Append(ctx, upload.UserID, upload.AchievementID, upload.ID, offset, bytes.NewReader(content[offset:]))
+		assert.ErrorIs(t, err, ErrUploadOffsetMismatch)
+		if assert.NotNil(t, got) {
+			assert.Equal(t, int64(1000), got.Offset)
+		}
+	}
+
+	// Upload milik user / prestasi lain dianggap tidak ada
+	_, err = s.Append(ctx, uuid.New(), upload.AchievementID, upload.ID, 1000, bytes.NewReader(content[1000:]))
+	assert.ErrorIs(t, err, ErrUploadNotFound)
+	_, err = s.Append(ctx, upload.UserID, uuid.New(), upload.ID, 1000, bytes.NewReader(content[1000:]))
+	assert.ErrorIs(t, err, ErrUploadNotFound)
+	_, err = s.Append(ctx, upload.UserID, upload.AchievementID, "../../etc/passwd", 0, bytes.NewReader(nil))
+	assert.ErrorIs(t, err, ErrUploadNotFound)
+}
+
+func TestResumableUpload_ExceedsLength(t *testing.T) {
+	s := newTestUploadService(t)
+	upload := newTestUpload(t, s, 1000)
+
+	content := evidenceContent(pdfHeader, 1200)
+	got, err := s.Append(context.Background(), upload.UserID, upload.AchievementID, upload.ID, 0, bytes.NewReader(content))
+	assert.ErrorIs(t, err, ErrUploadExceedsLength)
+	if assert.NotNil(t, got) {
+		// Hanya sampai Upload-Length yang ditulis, tidak diteruskan ke UploadEvidence
+		assert.Equal(t, int64(1000), got.Offset)
+		assert.Nil(t, got.Attachment)
+	}
+	stat, err := os.Stat(s.dataPath(upload.ID))
+	if assert.NoError(t, err) {
+		assert.Equal(t, int64(1000), stat.Size())
+	}
+}
+
+func TestResumableUpload_SniffFirstChunk(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Tipe Ditolak Dari Chunk Pertama", func(t *testing.T) {
+		s := newTestUploadService(t)
+		upload := newTestUpload(t, s, 2048)
+		html := append([]byte("<!DOCTYPE html><html><body>"), bytes.Repeat([]byte("a"), 600)...)
+
+		_, err := s.Append(ctx, upload.UserID, upload.AchievementID, upload.ID, 0, bytes.NewReader(html))
+		assert.ErrorIs(t, err, ErrUnsupportedFileType)
+
+		// Upload dibuang, tidak bisa dilanjutkan
+		_, err = s.Get(upload.UserID, upload.AchievementID, upload.ID)
+		assert.ErrorIs(t, err, ErrUploadNotFound)
+		_, err = os.Stat(s.dataPath(upload.ID))
+		assert.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("Menunggu 512 Byte Pertama", func(t *testing.T) {
+		s := newTestUploadService(t)
+		upload := newTestUpload(t, s, 2048)
+
+		got, err := s.Append(ctx, upload.UserID, upload.AchievementID, upload.ID, 0, bytes.NewReader([]byte("<html>")))
+		assert.NoError(t, err)
+		assert.Empty(t, got.ContentType)
+
+		_, err = s.Append(ctx, upload.UserID, upload.AchievementID, upload.ID, got.Offset, bytes.NewReader(bytes.Repeat([]byte("a"), 600)))
+		assert.ErrorIs(t, err, ErrUnsupportedFileType)
+	})
+
+	t.Run("Ukuran Melebihi Batas Tipe", func(t *testing.T) {
+		s := newTestUploadService(t)
+		upload := newTestUpload(t, s, 2048) // PNG maks 1024 di testEvidencePolicy
+
+		_, err := s.Append(ctx, upload.UserID, upload.AchievementID, upload.ID, 0, bytes.NewReader(evidenceContent(pngHeader, 600)))
+		assert.ErrorIs(t, err, ErrFileTooLarge)
+	})
+}
+
+func TestResumableUpload_RemoveExpired(t *testing.T) {
+	s := newTestUploadService(t)
+	active := newTestUpload(t, s, 100)
+	expired := newTestUpload(t, s, 100)
+	expired.ExpiresAt = time.Now().Add(-time.Minute)
+	if err := s.saveInfo(expired); err != nil {
+		t.Fatal(err)
+	}
+
+	s.removeExpired()
+	_, err := os.Stat(s.infoPath(expired.ID))
+	assert.True(t, os.IsNotExist(err))
+	_, err = s.Get(active.UserID, active.AchievementID, active.ID)
+	assert.NoError(t, err)
+}
+
+func TestResumableUpload_LocksOnlyKnownUploads(t *testing.T) {
+	s := newTestUploadService(t)
+	upload := newTestUpload(t, s, 100)
+	ctx := context.Background()
+
+	// ID acak (format valid maupun tidak) tidak menambah isi map
+	for _, id := range []string{strings.Repeat("a", 32), "0123456789abcdef0123456789abcdef", "../../etc/passwd", "bukan-id"} {
+		_, err := s.Append(ctx, upload.UserID, upload.AchievementID, id, 0, bytes.NewReader(nil))
+		assert.ErrorIs(t, err, ErrUploadNotFound)
+		assert.ErrorIs(t, s.Terminate(upload.UserID, upload.AchievementID, id), ErrUploadNotFound)
+	}
+	assert.Empty(t, s.locks)
+
+	_, err := s.Append(ctx, upload.UserID, upload.AchievementID, upload.ID, 0, bytes.NewReader(evidenceContent(pdfHeader, 50)))
+	assert.NoError(t, err)
+	assert.Len(t, s.locks, 1)
+
+	assert.NoError(t, s.Terminate(upload.UserID, upload.AchievementID, upload.ID))
+	assert.Empty(t, s.locks)
+}
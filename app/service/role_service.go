@@ -0,0 +1,253 @@
+package service
+
+import (
+	"errors"
+	"strings"
+
+	"reportachievement/app/model/postgre"
+	repo "reportachievement/app/repository/postgre"
+
+	"github.com/google/uuid"
+)
+
+// adminPermission: pemegang permission ini bisa mengatur role, minimal harus tersisa satu user aktif
+const adminPermission = "role:manage"
+
+var (
+	ErrRoleNotFound       = errors.New("role not found")
+	ErrPermissionNotFound = errors.New("permission not found")
+	ErrBuiltinRole        = errors.New("built-in role cannot be renamed or deleted")
+	ErrRoleInUse          = errors.New("role is still assigned to users")
+	ErrLastAdmin          = errors.New("at least one active user must keep the " + adminPermission + " permission")
+)
+
+// Role bawaan dipakai langsung di kode (UserService.Create membuat profil berdasarkan nama role)
+var builtinRoles = map[string]bool{"Admin": true, "Dosen Wali": true, "Mahasiswa": true}
+
+type RoleService struct {
+	permRepo    *repo.PermissionRepository
+	userRepo    *repo.UserRepository
+	permService *PermissionService
+	authService *AuthService
+}
+
+func NewRoleService(permRepo *repo.PermissionRepository, userRepo *repo.UserRepository, permService *PermissionService, authService *AuthService) *RoleService {
+	return &RoleService{permRepo: permRepo, userRepo: userRepo, permService: permService, authService: authService}
+}
+
+// DTO: Input Create/Update Role
+type RoleRequest struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions,omitempty"` // hanya dipakai saat create
+}
+
+type RoleResponse struct {
+	ID          uuid.UUID `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Builtin     bool      `json:"builtin"`
+	Permissions []string  `json:"permissions"`
+	UserCount   int64     `json:"user_count"`
+}
+
+// 1. List Roles beserta permission & jumlah user
+func (s *RoleService) ListRoles() ([]RoleResponse, error) {
+	roles, err := s.permRepo.FindAllRoles()
+	if err != nil {
+		return nil, err
+	}
+	counts, err := s.permRepo.CountUsersByRole()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]RoleResponse, 0, len(roles))
+	for _, role := range roles {
+		result = append(result, s.toResponse(role, counts[role.ID]))
+	}
+	return result, nil
+}
+
+// 2. List Permissions (katalog untuk UI)
+func (s *RoleService) ListPermissions() ([]postgre.Permission, error) {
+	return s.permRepo.FindAllPermissions()
+}
+
+// 3. Create Role
+func (s *RoleService) CreateRole(req RoleRequest) (*RoleResponse, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > 50 {
+		return nil, errors.New("role name is required (max 50 characters)")
+	}
+	if _, err := s.userRepo.FindRoleByName(name); err == nil {
+		return nil, errors.New("role already exists: " + name)
+	}
+
+	var permissionIDs []uuid.UUID
+	for _, permName := range req.Permissions {
+		permission, err := s.permRepo.FindPermissionByName(permName)
+		if err != nil {
+			return nil, errors.New(ErrPermissionNotFound.Error() + ": " + permName)
+		}
+		permissionIDs = append(permissionIDs, permission.ID)
+	}
+
+	role := postgre.Role{Name: name, Description: req.Description}
+	if err := s.permRepo.CreateRole(&role, permissionIDs); err != nil {
+		return nil, err
+	}
+	s.permService.Invalidate()
+
+	resp := s.toResponse(role, 0)
+	return &resp, nil
+}
+
+// 4. Update Role (nama & deskripsi)
+func (s *RoleService) UpdateRole(id uuid.UUID, req RoleRequest) error {
+	role, err := s.permRepo.FindRoleByID(id)
+	if err != nil {
+		return ErrRoleNotFound
+	}
+
+	name := strings.TrimSpace(req.Name)
+	renamed := name != "" && name != role.Name
+	if renamed {
+		if builtinRoles[role.Name] {
+			return ErrBuiltinRole
+		}
+		if len(name) > 50 {
+			return errors.New("role name is too long (max 50 characters)")
+		}
+		if _, err := s.userRepo.FindRoleByName(name); err == nil {
+			return errors.New("role already exists: " + name)
+		}
+		role.Name = name
+	}
+	if req.Description != "" {
+		role.Description = req.Description
+	}
+
+	if err := s.permRepo.UpdateRole(role); err != nil {
+		return err
+	}
+	if renamed {
+		// Cache permission & session memakai nama role
+		s.permService.Invalidate()
+		s.authService.InvalidateAllSessions()
+	}
+	return nil
+}
+
+// 5. Delete Role (hanya jika tidak dipakai user)
+func (s *RoleService) DeleteRole(id uuid.UUID) error {
+	role, err := s.permRepo.FindRoleByID(id)
+	if err != nil {
+		return ErrRoleNotFound
+	}
+	if builtinRoles[role.Name] {
+		return ErrBuiltinRole
+	}
+	counts, err := s.permRepo.CountUsersByRole()
+	if err != nil {
+		return err
+	}
+	if counts[id] > 0 {
+		return ErrRoleInUse
+	}
+
+	if err := s.permRepo.DeleteRole(id); err != nil {
+		return err
+	}
+	s.permService.Invalidate()
+	return nil
+}
+
+// 6. Attach Permission ke Role
+func (s *RoleService) AttachPermission(roleID uuid.UUID, permissionName string) error {
+	if _, err := s.permRepo.FindRoleByID(roleID); err != nil {
+		return ErrRoleNotFound
+	}
+	permission, err := s.permRepo.FindPermissionByName(permissionName)
+	if err != nil {
+		return ErrPermissionNotFound
+	}
+	if err := s.permRepo.AttachPermission(roleID, permission.ID); err != nil {
+		return err
+	}
+	s.permService.Invalidate()
+	s.authService.InvalidateAllSessions() // status session ikut permission (misal auth:require_2fa)
+	return nil
+}
+
+// 7. Detach Permission dari Role
+func (s *RoleService) DetachPermission(roleID uuid.UUID, permissionName string) error {
+	if _, err := s.permRepo.FindRoleByID(roleID); err != nil {
+		return ErrRoleNotFound
+	}
+	permission, err := s.permRepo.FindPermissionByName(permissionName)
+	if err != nil {
+		return ErrPermissionNotFound
+	}
+
+	// Jangan sampai tidak ada lagi yang bisa mengatur role
+	if permissionName == adminPermission {
+		remaining, err := s.permRepo.CountActiveUsersWithPermission(adminPermission, uuid.Nil, roleID)
+		if err != nil {
+			return err
+		}
+		if remaining == 0 {
+			return ErrLastAdmin
+		}
+	}
+
+	if err := s.permRepo.DetachPermission(roleID, permission.ID); err != nil {
+		return err
+	}
+	s.permService.Invalidate()
+	s.authService.InvalidateAllSessions()
+	return nil
+}
+
+// 8. Change User Role, berlaku langsung untuk token yang sudah beredar
+func (s *RoleService) ChangeUserRole(userID uuid.UUID, roleName string) error {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return errors.New("user not found")
+	}
+	role, err := s.userRepo.FindRoleByName(roleName)
+	if err != nil {
+		return ErrRoleNotFound
+	}
+	if role.ID == user.RoleID {
+		return nil
+	}
+
+	// Admin terakhir tidak boleh diturunkan
+	if !s.permService.HasPermission(role.Name, adminPermission) {
+		remaining, err := s.permRepo.CountActiveUsersWithPermission(adminPermission, userID, uuid.Nil)
+		if err != nil {
+			return err
+		}
+		if remaining == 0 {
+			return ErrLastAdmin
+		}
+	}
+
+	if err := s.userRepo.UpdateRole(userID, role.ID); err != nil {
+		return err
+	}
+	s.authService.InvalidateUserSessions(userID)
+	return nil
+}
+
+func (s *RoleService) toResponse(role postgre.Role, userCount int64) RoleResponse {
+	return RoleResponse{
+		ID:          role.ID,
+		Name:        role.Name,
+		Description: role.Description,
+		Builtin:     builtinRoles[role.Name],
+		Permissions: s.permService.Permissions(role.Name),
+		UserCount:   userCount,
+	}
+}
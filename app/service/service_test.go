@@ -1,21 +1,38 @@
 package service
 
 import (
+	"archive/zip"
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
 	"log"
+	"net/url"
 	"os"
+	"strings"
 	"testing"
+	"time"
 
+	mongoModel "reportachievement/app/model/mongo"
 	"reportachievement/app/model/postgre"
 	repoMongo "reportachievement/app/repository/mongo"
 	repoPostgre "reportachievement/app/repository/postgre"
 	"reportachievement/config"
 	"reportachievement/database/mongo"
 	"reportachievement/database/postgres"
+	"reportachievement/jwtkeys"
+	"reportachievement/mailer"
+	"reportachievement/password"
+	"reportachievement/storage"
+	"reportachievement/totp"
 
 	"github.com/google/uuid"
 	"github.com/joho/godotenv"
 	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
@@ -31,6 +48,10 @@ var (
 	lecturerRepo *repoPostgre.LecturerRepository
 	achRefRepo   *repoPostgre.AchievementRepository
 	achMongoRepo *repoMongo.AchievementRepository
+	pwdPolicy    *password.Policy
+	loginGuard   *LoginGuard
+	testStorage  storage.Storage
+	evidenceRepo *repoMongo.EvidenceFileRepository
 )
 
 // setup() berjalan sekali sebelum semua test dimulai
@@ -48,10 +69,14 @@ func TestMain(m *testing.M) {
 	// --- HARD RESET DATABASE (SOLUSI FINAL) ---
 	// Kita gunakan Raw SQL 'CASCADE' untuk memaksa hapus tabel lama yang nyangkut.
 	// Ini akan menghapus tabel bersih-bersih sebelum membuatnya lagi.
+	testDB.Exec("DROP TABLE IF EXISTS password_reset_tokens CASCADE")
+	testDB.Exec("DROP TABLE IF EXISTS refresh_tokens CASCADE")
+	testDB.Exec("DROP TABLE IF EXISTS user_sessions CASCADE")
 	testDB.Exec("DROP TABLE IF EXISTS achievement_references CASCADE")
 	testDB.Exec("DROP TABLE IF EXISTS students CASCADE")
 	testDB.Exec("DROP TABLE IF EXISTS lecturers CASCADE")
 	testDB.Exec("DROP TABLE IF EXISTS role_permissions CASCADE") // jika ada
+	testDB.Exec("DROP TABLE IF EXISTS permissions CASCADE")
 	testDB.Exec("DROP TABLE IF EXISTS users CASCADE")
 	testDB.Exec("DROP TABLE IF EXISTS roles CASCADE")
 
@@ -59,9 +84,18 @@ func TestMain(m *testing.M) {
 	err := testDB.AutoMigrate(
 		&postgre.Role{},
 		&postgre.User{},
+		&postgre.Permission{},
+		&postgre.RolePermission{},
 		&postgre.Student{},
 		&postgre.Lecturer{},
 		&postgre.AchievementReference{},
+		&postgre.UserSession{},
+		&postgre.RefreshToken{},
+		&postgre.PasswordResetToken{},
+		&postgre.LoginAttempt{},
+		&postgre.MFARecoveryCode{},
+		&postgre.ServiceAccount{},
+		&postgre.APIKey{},
 	)
 	if err != nil {
 		log.Fatal("Gagal Migrasi Database Test:", err)
@@ -73,15 +107,22 @@ func TestMain(m *testing.M) {
 	lecturerRepo = repoPostgre.NewLecturerRepository(testDB)
 	achRefRepo = repoPostgre.NewAchievementRepository(testDB)
 	achMongoRepo = repoMongo.NewAchievementRepository(testMongo.Db)
+	evidenceRepo = repoMongo.NewEvidenceFileRepository(testMongo.Db)
 
-	authService = NewAuthService(userRepo)
-	achService = NewAchievementService(studentRepo, lecturerRepo, achRefRepo, achMongoRepo)
+	pwdPolicy, _ = password.NewPolicy(password.Options{MinLength: 8, Cost: bcrypt.DefaultCost})
+	loginGuard = NewLoginGuard(repoPostgre.NewLoginAttemptRepository(testDB), userRepo, cfg)
+	authService = NewAuthService(userRepo, repoPostgre.NewSessionRepository(testDB), jwtkeys.New(cfg), pwdPolicy, loginGuard, NewPermissionService(repoPostgre.NewPermissionRepository(testDB)), cfg)
+	uploadDir, _ := os.MkdirTemp("", "evidence-test-*")
+	fileStorage, _ := storage.NewLocalStorage(uploadDir, "http://localhost:3000/uploads")
+	testStorage = fileStorage
+	achService = NewAchievementService(studentRepo, lecturerRepo, achRefRepo, achMongoRepo, evidenceRepo, fileStorage, NewEvidencePolicy(cfg), NewFileLinker(cfg), nil)
 
 	// 5. Jalankan Test
 	code := m.Run()
 
 	// 6. Cleanup Disconnect Mongo
 	testMongo.Client.Disconnect(context.TODO())
+	os.RemoveAll(uploadDir)
 	os.Exit(code)
 }
 
@@ -97,6 +138,38 @@ func getOrCreateRole(name string) uuid.UUID {
 	return newRole.ID
 }
 
+// createTestStudent: user Mahasiswa + profil student, cleanup dipanggil via defer
+func createTestStudent(t *testing.T, username string) (postgre.User, func()) {
+	passHash, _ := bcrypt.GenerateFromPassword([]byte("123456"), bcrypt.MinCost)
+	user := postgre.User{
+		ID: uuid.New(), Username: username, Email: username + "@test.com",
+		PasswordHash: string(passHash), RoleID: getOrCreateRole("Mahasiswa"), IsActive: true,
+	}
+	if err := testDB.Create(&user).Error; err != nil {
+		t.Fatalf("Gagal buat Mhs: %v", err)
+	}
+	profile := postgre.Student{ID: uuid.New(), UserID: user.ID, NIM: "NIM_" + username}
+	if err := testDB.Create(&profile).Error; err != nil {
+		t.Fatalf("Gagal buat Profil Mhs: %v", err)
+	}
+	return user, func() {
+		testDB.Unscoped().Where("student_id = ?", profile.ID).Delete(&postgre.AchievementReference{})
+		testDB.Unscoped().Delete(&profile)
+		testDB.Unscoped().Delete(&user)
+	}
+}
+
+func newTestUserService() *UserService {
+	permRepo := repoPostgre.NewPermissionRepository(testDB)
+	return NewUserService(userRepo, permRepo, NewPermissionService(permRepo), authService, pwdPolicy)
+}
+
+// testPDF: file PDF kecil, isi berbeda untuk tag berbeda (hash SHA-256 berbeda)
+func testPDF(tag string) EvidenceFile {
+	content := evidenceContent(append(append([]byte{}, pdfHeader...), tag...), 256)
+	return EvidenceFile{FileName: tag + ".pdf", ContentType: "application/pdf", Size: int64(len(content)), Content: bytes.NewReader(content)}
+}
+
 // --- TEST 1: LOGIN (Auth Service) ---
 
 func TestLogin_Integration(t *testing.T) {
@@ -124,7 +197,7 @@ func TestLogin_Integration(t *testing.T) {
 
 	// B. Eksekusi Login (Happy Path)
 	t.Run("Login Sukses", func(t *testing.T) {
-		resp, err := authService.Login("test_login_user", password)
+		resp, err := authService.Login("test_login_user", password, ClientInfo{})
 
 		assert.NoError(t, err)
 		if err != nil {
@@ -143,10 +216,603 @@ func TestLogin_Integration(t *testing.T) {
 
 	// C. Eksekusi Login (Wrong Password)
 	t.Run("Password Salah", func(t *testing.T) {
-		_, err := authService.Login("test_login_user", "salah_pass")
+		_, err := authService.Login("test_login_user", "salah_pass", ClientInfo{})
 		assert.Error(t, err)
 		assert.Equal(t, "invalid username or password", err.Error())
 	})
+
+	// C2. Lockout setelah gagal berturut-turut, password benar pun ditolak sampai dibuka
+	t.Run("Lockout Akun", func(t *testing.T) {
+		_, err := authService.Login("test_login_user", password, ClientInfo{}) // reset hitungan
+		assert.NoError(t, err)
+		for i := 0; i < loginGuard.maxAttempts; i++ {
+			_, err = authService.Login("test_login_user", "salah_pass", ClientInfo{})
+			assert.ErrorIs(t, err, ErrInvalidCredentials)
+		}
+
+		_, err = authService.Login("test_login_user", password, ClientInfo{})
+		var throttled *LoginThrottledError
+		if assert.ErrorAs(t, err, &throttled) {
+			assert.True(t, throttled.Account)
+			assert.Greater(t, throttled.RetryAfter, time.Duration(0))
+		}
+
+		assert.NoError(t, loginGuard.Unlock(dummyUser.ID))
+		_, err = authService.Login("test_login_user", password, ClientInfo{})
+		assert.NoError(t, err)
+
+		locked := false
+		attempts, _, err := loginGuard.ListAttempts(repoPostgre.LoginAttemptFilter{UserID: &dummyUser.ID, Success: &locked})
+		assert.NoError(t, err)
+		if assert.NotEmpty(t, attempts) {
+			assert.Equal(t, postgre.LoginAccountLocked, attempts[0].Reason)
+		}
+	})
+
+	// D. Refresh Token (Rotasi & Reuse Detection)
+	t.Run("Refresh Rotasi", func(t *testing.T) {
+		login, err := authService.Login("test_login_user", password, ClientInfo{UserAgent: "go-test"})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		first := login["refresh_token"].(string)
+
+		refreshed, err := authService.Refresh(first)
+		assert.NoError(t, err)
+		second := refreshed["refresh_token"].(string)
+		assert.NotEqual(t, first, second)
+
+		// Token lama dipakai lagi: session dicabut, token terbaru ikut tidak berlaku
+		_, err = authService.Refresh(first)
+		assert.ErrorIs(t, err, ErrRefreshTokenReused)
+		_, err = authService.Refresh(second)
+		assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+	})
+
+	// E. Logout & nonaktifkan akun mencabut session
+	t.Run("Logout Cabut Session", func(t *testing.T) {
+		first, err := authService.Login("test_login_user", password, ClientInfo{})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		second, _ := authService.Login("test_login_user", password, ClientInfo{})
+
+		var sessions []postgre.UserSession
+		testDB.Where("user_id = ? AND revoked_at IS NULL", dummyUser.ID).Order("created_at").Find(&sessions)
+		if !assert.GreaterOrEqual(t, len(sessions), 2) {
+			t.FailNow()
+		}
+		sid := sessions[len(sessions)-2].ID
+		role, _, active := authService.SessionStatus(sid.String())
+		assert.True(t, active)
+		assert.Equal(t, "Mahasiswa", role)
+
+		assert.NoError(t, authService.Logout(sid))
+		_, _, active = authService.SessionStatus(sid.String())
+		assert.False(t, active)
+		_, err = authService.Refresh(first["refresh_token"].(string))
+		assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+
+		// Sisa session ikut dicabut saat akun dinonaktifkan
+		inactive := false
+		assert.NoError(t, newTestUserService().Update(dummyUser.ID, UpdateUserRequest{IsActive: &inactive}))
+		_, err = authService.Refresh(second["refresh_token"].(string))
+		assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+	})
+}
+
+// --- TEST 1a: PASSWORD (Ganti & Reset via Email) ---
+
+// captureMailer: simpan email terakhir (pengganti SMTP di test)
+type captureMailer struct{ last mailer.Message }
+
+func (m *captureMailer) Send(ctx context.Context, msg mailer.Message) error {
+	m.last = msg
+	return nil
+}
+
+func (m *captureMailer) Name() string { return "capture" }
+
+func TestPassword_Integration(t *testing.T) {
+	// Hash lama dengan cost rendah & password yang tidak memenuhi policy
+	hashed, _ := bcrypt.GenerateFromPassword([]byte("123456"), bcrypt.MinCost)
+	user := postgre.User{
+		ID: uuid.New(), Username: "test_pwd_user", Email: "pwd@test.com",
+		PasswordHash: string(hashed), FullName: "Tester Password", RoleID: getOrCreateRole("Mahasiswa"), IsActive: true,
+	}
+	if err := testDB.Create(&user).Error; err != nil {
+		t.Fatalf("Gagal insert user dummy: %v", err)
+	}
+	defer testDB.Unscoped().Delete(&user)
+
+	mail := &captureMailer{}
+	cfg := config.LoadConfig()
+	passwordService := NewPasswordService(userRepo, repoPostgre.NewPasswordResetRepository(testDB), authService, mail, pwdPolicy, cfg)
+
+	t.Run("Wajib Ganti Password & Upgrade Hash", func(t *testing.T) {
+		login, err := authService.Login("test_pwd_user", "123456", ClientInfo{})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		assert.Equal(t, true, login["must_change_password"])
+
+		stored, _ := userRepo.FindByID(user.ID)
+		assert.True(t, stored.MustChangePassword)
+		cost, _ := bcrypt.Cost([]byte(stored.PasswordHash))
+		assert.Equal(t, bcrypt.DefaultCost, cost)
+
+		var session postgre.UserSession
+		testDB.Where("user_id = ?", user.ID).Order("created_at desc").First(&session)
+		_, pending, active := authService.SessionStatus(session.ID.String())
+		assert.True(t, active)
+		assert.Equal(t, PendingPasswordChange, pending)
+
+		assert.ErrorIs(t, passwordService.ChangePassword(user.ID, session.ID, "salah", "password-baru-1"), ErrInvalidCurrentPassword)
+		assert.ErrorIs(t, passwordService.ChangePassword(user.ID, session.ID, "123456", "pendek"), password.ErrTooShort)
+		assert.ErrorIs(t, passwordService.ChangePassword(user.ID, session.ID, "123456", "test_pwd_user-1"), password.ErrContainsIdentity)
+		assert.NoError(t, passwordService.ChangePassword(user.ID, session.ID, "123456", "password-baru-1"))
+
+		_, pending, active = authService.SessionStatus(session.ID.String())
+		assert.True(t, active)
+		assert.Empty(t, pending)
+	})
+
+	t.Run("Ganti Password", func(t *testing.T) {
+		assert.ErrorIs(t, passwordService.ChangePassword(user.ID, uuid.Nil, "password-baru-1", "password-baru-1"), ErrPasswordUnchanged)
+		login, err := authService.Login("test_pwd_user", "password-baru-1", ClientInfo{})
+		assert.NoError(t, err)
+		assert.Equal(t, false, login["must_change_password"])
+	})
+
+	t.Run("Reset via Email", func(t *testing.T) {
+		login, _ := authService.Login("test_pwd_user", "password-baru-1", ClientInfo{})
+		assert.NoError(t, passwordService.SendResetLink(context.Background(), user.ID, "127.0.0.1"))
+		assert.Equal(t, "pwd@test.com", mail.last.To)
+
+		_, link, found := strings.Cut(mail.last.Body, "token=")
+		if !assert.True(t, found) {
+			t.FailNow()
+		}
+		token, _ := url.QueryUnescape(strings.Fields(link)[0])
+
+		assert.NoError(t, passwordService.ResetPassword(token, "password-reset-2"))
+		assert.ErrorIs(t, passwordService.ResetPassword(token, "password-reset-3"), ErrInvalidResetToken) // sekali pakai
+
+		_, err := authService.Refresh(login["refresh_token"].(string))
+		assert.ErrorIs(t, err, ErrInvalidRefreshToken) // semua session dicabut
+		_, err = authService.Login("test_pwd_user", "password-reset-2", ClientInfo{})
+		assert.NoError(t, err)
+	})
+
+	t.Run("Ganti Password Membatalkan Link Reset", func(t *testing.T) {
+		resetToken := func() string {
+			assert.NoError(t, passwordService.SendResetLink(context.Background(), user.ID, "127.0.0.1"))
+			_, link, _ := strings.Cut(mail.last.Body, "token=")
+			token, _ := url.QueryUnescape(strings.Fields(link)[0])
+			return token
+		}
+
+		token := resetToken()
+		assert.NoError(t, passwordService.ChangePassword(user.ID, uuid.Nil, "password-reset-2", "password-ganti-3"))
+		assert.ErrorIs(t, passwordService.ResetPassword(token, "password-reset-4"), ErrInvalidResetToken)
+
+		// Token lain yang terbit bersamaan (tidak lewat Create) ikut batal setelah reset berhasil
+		token = resetToken()
+		otherToken := "token-lain-" + uuid.NewString()
+		other := postgre.PasswordResetToken{UserID: user.ID, TokenHash: hashToken(otherToken), ExpiresAt: time.Now().Add(time.Hour)}
+		assert.NoError(t, testDB.Create(&other).Error)
+		assert.NoError(t, passwordService.ResetPassword(token, "password-reset-5"))
+		assert.ErrorIs(t, passwordService.ResetPassword(otherToken, "password-reset-6"), ErrInvalidResetToken)
+	})
+}
+
+// --- TEST 1a2: 2FA (TOTP, Recovery Code, Login 2 Langkah) ---
+
+func TestMFA_Integration(t *testing.T) {
+	hashed, _ := bcrypt.GenerateFromPassword([]byte("rahasia-mfa-1"), bcrypt.DefaultCost)
+	user := postgre.User{
+		ID: uuid.New(), Username: "test_mfa_user", Email: "mfa@test.com",
+		PasswordHash: string(hashed), FullName: "Tester 2FA", RoleID: getOrCreateRole("Mahasiswa"), IsActive: true,
+	}
+	if err := testDB.Create(&user).Error; err != nil {
+		t.Fatalf("Gagal insert user dummy: %v", err)
+	}
+	defer testDB.Unscoped().Delete(&user)
+
+	mfaService := NewMFAService(userRepo, repoPostgre.NewMFARepository(testDB), authService, loginGuard, config.LoadConfig())
+
+	setup, err := mfaService.Setup(user.ID)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Contains(t, setup.ProvisioningURI, "otpauth://totp/")
+
+	code, _ := totp.Code(setup.Secret, time.Now())
+	recovery, err := mfaService.Enable(user.ID, code)
+	assert.NoError(t, err)
+	assert.Len(t, recovery, recoveryCodeCount)
+
+	// Login langkah pertama hanya menghasilkan challenge
+	first, err := authService.Login("test_mfa_user", "rahasia-mfa-1", ClientInfo{})
+	assert.NoError(t, err)
+	assert.Equal(t, true, first["mfa_required"])
+	assert.Nil(t, first["token"])
+	challenge := first["challenge_token"].(string)
+
+	// Kode konfirmasi tidak bisa dipakai ulang, kode step berikutnya bisa
+	_, err = mfaService.CompleteLogin(challenge, code, ClientInfo{})
+	assert.ErrorIs(t, err, ErrInvalidMFACode)
+	next, _ := totp.Code(setup.Secret, time.Now().Add(totp.Period))
+	resp, err := mfaService.CompleteLogin(challenge, next, ClientInfo{})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, resp["token"])
+
+	// Recovery code sekali pakai
+	_, err = mfaService.CompleteLogin(challenge, strings.ToUpper(recovery[0]), ClientInfo{})
+	assert.NoError(t, err)
+	_, err = mfaService.CompleteLogin(challenge, recovery[0], ClientInfo{})
+	assert.ErrorIs(t, err, ErrInvalidMFACode)
+
+	status, _ := mfaService.Status(user.ID)
+	assert.True(t, status.Enabled)
+	assert.Equal(t, int64(recoveryCodeCount-1), status.RecoveryCodesRemaining)
+
+	// Reset oleh admin: login kembali satu langkah
+	assert.NoError(t, mfaService.Reset(user.ID))
+	resp, err = authService.Login("test_mfa_user", "rahasia-mfa-1", ClientInfo{})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, resp["token"])
+}
+
+// stubDirectory: pengganti LDAPProvider, user baru dibuat seperti provisioning LDAP
+type stubDirectory struct {
+	password string
+	down     bool
+}
+
+func (d *stubDirectory) Name() string { return postgre.AuthSourceLDAP }
+
+func (d *stubDirectory) Authenticate(user *postgre.User, username, plainPassword string) (*postgre.User, error) {
+	if d.down {
+		return nil, ErrAuthProviderUnavailable
+	}
+	if plainPassword != d.password {
+		return nil, ErrInvalidCredentials
+	}
+	if user != nil {
+		return user, nil
+	}
+	created := &postgre.User{
+		Username: username, Email: username + "@ldap.test", PasswordHash: unusablePasswordHash,
+		FullName: "Dari LDAP", RoleID: getOrCreateRole("Mahasiswa"), IsActive: true, AuthSource: postgre.AuthSourceLDAP,
+	}
+	if err := userRepo.Create(created); err != nil {
+		return nil, err
+	}
+	return userRepo.FindByID(created.ID)
+}
+
+func TestOIDCLogin_Integration(t *testing.T) {
+	user := postgre.User{
+		ID: uuid.New(), Username: "test_oidc_user", Email: "oidc@test.com",
+		PasswordHash: unusablePasswordHash, FullName: "Tester SSO", RoleID: getOrCreateRole("Dosen Wali"), IsActive: true,
+	}
+	if err := testDB.Create(&user).Error; err != nil {
+		t.Fatalf("Gagal insert user dummy: %v", err)
+	}
+	defer testDB.Unscoped().Delete(&user)
+	sso := NewOIDCService(nil, userRepo, authService, loginGuard, &config.Config{})
+	trusted := NewOIDCService(nil, userRepo, authService, loginGuard, &config.Config{OIDCTrustProviderMFA: true})
+
+	t.Run("Tanpa 2FA Langsung Dapat Token", func(t *testing.T) {
+		resp, err := sso.login(&user, ClientInfo{})
+		if assert.NoError(t, err) {
+			assert.NotEmpty(t, resp["token"])
+		}
+	})
+
+	t.Run("2FA Aktif Diminta Kode", func(t *testing.T) {
+		enabled := time.Now()
+		user.TOTPEnabledAt = &enabled
+		defer func() { user.TOTPEnabledAt = nil }()
+
+		resp, err := sso.login(&user, ClientInfo{})
+		if assert.NoError(t, err) {
+			assert.Equal(t, true, resp["mfa_required"])
+			assert.NotEmpty(t, resp["challenge_token"])
+			assert.Nil(t, resp["token"])
+		}
+
+		// Provider yang sudah mewajibkan MFA dipercaya jika dikonfigurasi
+		resp, err = trusted.login(&user, ClientInfo{})
+		if assert.NoError(t, err) {
+			assert.NotEmpty(t, resp["token"])
+		}
+	})
+
+	t.Run("Akun Terkunci Ditolak", func(t *testing.T) {
+		lockedUntil := time.Now().Add(10 * time.Minute)
+		user.LockedUntil = &lockedUntil
+		defer func() { user.LockedUntil = nil }()
+
+		for _, svc := range []*OIDCService{sso, trusted} {
+			_, err := svc.login(&user, ClientInfo{})
+			var throttled *LoginThrottledError
+			if assert.ErrorAs(t, err, &throttled) {
+				assert.True(t, throttled.Account)
+			}
+		}
+	})
+
+	t.Run("Akun Nonaktif Ditolak", func(t *testing.T) {
+		inactive := user
+		inactive.IsActive = false
+		_, err := sso.login(&inactive, ClientInfo{})
+		assert.EqualError(t, err, "account is inactive")
+	})
+}
+
+func TestAuthProvider_Integration(t *testing.T) {
+	cfg := config.LoadConfig()
+	directory := &stubDirectory{password: "rahasia-ldap-1"}
+	auth := NewAuthService(userRepo, repoPostgre.NewSessionRepository(testDB), jwtkeys.New(cfg), pwdPolicy, loginGuard, NewPermissionService(repoPostgre.NewPermissionRepository(testDB)), cfg)
+	auth.RegisterProvider(directory, true)
+	defer testDB.Exec("DELETE FROM users WHERE username = ?", "test_ldap_user")
+
+	// Login pertama membuat user (just-in-time) dengan auth_source ldap
+	resp, err := auth.Login("test_ldap_user", "rahasia-ldap-1", ClientInfo{})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.NotEmpty(t, resp["token"])
+	assert.Equal(t, false, resp["must_change_password"])
+	user, _ := userRepo.FindByUsername("test_ldap_user")
+	assert.Equal(t, postgre.AuthSourceLDAP, user.AuthSource)
+
+	// Password lokal tidak berlaku untuk akun LDAP
+	_, err = auth.Login("test_ldap_user", "salah", ClientInfo{})
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+	assert.NoError(t, auth.VerifyPassword(user, "rahasia-ldap-1"))
+
+	// Server LDAP mati: bukan percobaan gagal (tidak mendekatkan ke lockout)
+	before, _ := userRepo.FindByID(user.ID)
+	directory.down = true
+	_, err = auth.Login("test_ldap_user", "rahasia-ldap-1", ClientInfo{})
+	assert.ErrorIs(t, err, ErrAuthProviderUnavailable)
+	after, _ := userRepo.FindByID(user.ID)
+	assert.Equal(t, before.FailedLoginCount, after.FailedLoginCount)
+
+	// Tanpa provider provisioning username tak dikenal tetap ditolak
+	_, err = authService.Login("test_ldap_lain", "rahasia-ldap-1", ClientInfo{})
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+}
+
+func TestLDAPProvider_MapRole(t *testing.T) {
+	provider := &LDAPProvider{
+		groupRoles:  parseGroupRoles("cn=admin-prestasi,ou=groups,dc=unair,dc=ac,dc=id=Admin; dosen-wali=Dosen Wali;invalid;=x"),
+		defaultRole: "Mahasiswa",
+	}
+	assert.Len(t, provider.groupRoles, 2)
+	assert.Equal(t, "Dosen Wali", provider.mapRole([]string{"CN=Dosen-Wali,OU=Groups,DC=unair,DC=ac,DC=id"}))
+	// Urutan konfigurasi = prioritas
+	assert.Equal(t, "Admin", provider.mapRole([]string{"cn=dosen-wali,ou=groups", "cn=admin-prestasi,ou=groups,dc=unair,dc=ac,dc=id"}))
+	assert.Equal(t, "Mahasiswa", provider.mapRole(nil))
+}
+
+func TestDescribeDevice(t *testing.T) {
+	assert.Equal(t, "Chrome on Windows", describeDevice("Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/129.0.0.0 Safari/537.36"))
+	assert.Equal(t, "Edge on Windows", describeDevice("Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/129.0.0.0 Safari/537.36 Edg/129.0.0.0"))
+	assert.Equal(t, "Safari on iOS", describeDevice("Mozilla/5.0 (iPhone; CPU iPhone OS 17_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.5 Mobile/15E148 Safari/604.1"))
+	assert.Equal(t, "curl", describeDevice("curl/8.5.0"))
+	assert.Equal(t, "Unknown device", describeDevice(""))
+}
+
+// --- TEST 1a2: SESSION (Daftar Perangkat & Sign-out Jarak Jauh) ---
+
+func TestSessions_Integration(t *testing.T) {
+	password := "rahasia123"
+	hashed, _ := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	owner := postgre.User{ID: uuid.New(), Username: "test_session_user", Email: "session@test.com", PasswordHash: string(hashed), FullName: "Tester Session", RoleID: getOrCreateRole("Mahasiswa"), IsActive: true}
+	other := postgre.User{ID: uuid.New(), Username: "test_session_other", Email: "session2@test.com", PasswordHash: string(hashed), FullName: "Tester Lain", RoleID: getOrCreateRole("Mahasiswa"), IsActive: true}
+	for _, u := range []*postgre.User{&owner, &other} {
+		if err := testDB.Create(u).Error; err != nil {
+			t.Fatalf("Gagal insert user dummy: %v", err)
+		}
+		defer testDB.Unscoped().Delete(u)
+	}
+
+	laptop := ClientInfo{UserAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/129.0.0.0 Safari/537.36", IPAddress: "10.0.0.1"}
+	phone := ClientInfo{UserAgent: "Mozilla/5.0 (Linux; Android 14) AppleWebKit/537.36 (KHTML, like Gecko) Firefox/131.0", IPAddress: "10.0.0.2"}
+	_, err := authService.Login(owner.Username, password, laptop)
+	assert.NoError(t, err)
+	phoneLogin, err := authService.Login(owner.Username, password, phone)
+	assert.NoError(t, err)
+	_, err = authService.Login(other.Username, password, ClientInfo{})
+	assert.NoError(t, err)
+
+	var current postgre.UserSession
+	testDB.Where("user_id = ? AND ip_address = ?", owner.ID, laptop.IPAddress).First(&current)
+
+	sessions, err := authService.ListSessions(owner.ID, current.ID)
+	if !assert.NoError(t, err) || !assert.Len(t, sessions, 2) {
+		t.FailNow()
+	}
+	devices := map[string]SessionInfo{}
+	for _, s := range sessions {
+		devices[s.Device] = s
+	}
+	assert.True(t, devices["Chrome on Windows"].Current)
+	assert.Equal(t, "10.0.0.1", devices["Chrome on Windows"].IPAddress)
+	phoneSession := devices["Firefox on Android"]
+	assert.False(t, phoneSession.Current)
+
+	// Session user lain tidak bisa dicabut lewat akun ini
+	otherSessions, _ := authService.ListSessions(other.ID, uuid.Nil)
+	if assert.Len(t, otherSessions, 1) {
+		assert.ErrorIs(t, authService.RevokeUserSession(owner.ID, otherSessions[0].ID, "revoked by user"), ErrSessionNotFound)
+	}
+
+	// Sign-out HP dari laptop: refresh token HP langsung ditolak
+	assert.NoError(t, authService.RevokeUserSession(owner.ID, phoneSession.ID, "revoked by user"))
+	_, _, active := authService.SessionStatus(phoneSession.ID.String())
+	assert.False(t, active)
+	_, err = authService.Refresh(phoneLogin["refresh_token"].(string))
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+	assert.ErrorIs(t, authService.RevokeUserSession(owner.ID, phoneSession.ID, "revoked by user"), ErrSessionNotFound)
+
+	// Tampilan admin
+	userService := newTestUserService()
+	sessions, err = userService.ListSessions(owner.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, sessions, 1) {
+		assert.False(t, sessions[0].Current)
+		assert.NoError(t, userService.RevokeSession(owner.ID, sessions[0].ID))
+	}
+	sessions, _ = authService.ListSessions(owner.ID, uuid.Nil)
+	assert.Empty(t, sessions)
+}
+
+// --- TEST 1b: PERMISSION (Seeder & Cache) ---
+
+func TestPermission_Integration(t *testing.T) {
+	postgres.SeedPermissions(testDB)
+	postgres.SeedPermissions(testDB) // idempotent
+
+	var count int64
+	testDB.Model(&postgre.Permission{}).Count(&count)
+	assert.Equal(t, int64(len(postgres.DefaultPermissions)), count)
+
+	permService := NewPermissionService(repoPostgre.NewPermissionRepository(testDB))
+	assert.True(t, permService.HasPermission("Dosen Wali", "achievement:verify"))
+	assert.False(t, permService.HasPermission("Mahasiswa", "achievement:verify"))
+	assert.Contains(t, permService.Permissions("Admin"), "user:manage")
+	for _, p := range postgres.DefaultPermissions {
+		if p.Name == mfaRequiredPermission {
+			assert.Empty(t, p.Roles) // opt-in lewat endpoint role
+		}
+	}
+
+	scope := ScopeFromPermissions(func(p string) bool { return permService.HasPermission("Mahasiswa", p) })
+	assert.Equal(t, ScopeOwn, scope)
+
+	t.Run("Admin Terakhir Tidak Bisa Diturunkan", func(t *testing.T) {
+		hashed, _ := bcrypt.GenerateFromPassword([]byte("admin123"), bcrypt.DefaultCost)
+		admin := postgre.User{
+			ID: uuid.New(), Username: "test_last_admin", Email: "last@admin.com",
+			PasswordHash: string(hashed), FullName: "Last Admin", RoleID: getOrCreateRole("Admin"), IsActive: true,
+		}
+		if err := testDB.Create(&admin).Error; err != nil {
+			t.Fatalf("Gagal insert admin: %v", err)
+		}
+		defer testDB.Unscoped().Delete(&admin)
+
+		permRepo := repoPostgre.NewPermissionRepository(testDB)
+		roleService := NewRoleService(permRepo, userRepo, permService, authService)
+		assert.ErrorIs(t, roleService.ChangeUserRole(admin.ID, "Mahasiswa"), ErrLastAdmin)
+
+		var adminRole postgre.Role
+		testDB.Where("name = ?", "Admin").First(&adminRole)
+		assert.ErrorIs(t, roleService.DetachPermission(adminRole.ID, "role:manage"), ErrLastAdmin)
+		assert.ErrorIs(t, roleService.DeleteRole(adminRole.ID), ErrBuiltinRole)
+
+		// Role baru dengan role:manage: admin lama boleh diturunkan
+		_, err := roleService.CreateRole(RoleRequest{Name: "Kaprodi", Permissions: []string{"role:manage", "achievement:read_all"}})
+		assert.NoError(t, err)
+		assert.NoError(t, roleService.ChangeUserRole(admin.ID, "Kaprodi"))
+		assert.ErrorIs(t, roleService.ChangeUserRole(admin.ID, "Mahasiswa"), ErrLastAdmin)
+	})
+
+	t.Run("Admin Terakhir Tidak Bisa Dinonaktifkan Atau Dihapus", func(t *testing.T) {
+		hashed, _ := bcrypt.GenerateFromPassword([]byte("admin123"), bcrypt.MinCost)
+		newAdmin := func(username string) postgre.User {
+			admin := postgre.User{
+				ID: uuid.New(), Username: username, Email: username + "@admin.com",
+				PasswordHash: string(hashed), FullName: "Admin " + username, RoleID: getOrCreateRole("Admin"), IsActive: true,
+			}
+			if err := testDB.Create(&admin).Error; err != nil {
+				t.Fatalf("Gagal insert admin: %v", err)
+			}
+			return admin
+		}
+		first := newAdmin("test_last_admin_a")
+		defer testDB.Unscoped().Delete(&first)
+
+		userService := newTestUserService()
+		inactive := false
+		assert.ErrorIs(t, userService.Update(first.ID, UpdateUserRequest{IsActive: &inactive}), ErrLastAdmin)
+		assert.ErrorIs(t, userService.Delete(first.ID), ErrLastAdmin)
+		stored, err := userRepo.FindByID(first.ID)
+		if assert.NoError(t, err) {
+			assert.True(t, stored.IsActive)
+		}
+		// Field lain tetap bisa diubah
+		assert.NoError(t, userService.Update(first.ID, UpdateUserRequest{FullName: "Admin Utama"}))
+
+		// Ada admin aktif lain: admin pertama boleh dinonaktifkan, sekarang admin kedua yang terakhir
+		second := newAdmin("test_last_admin_b")
+		defer testDB.Unscoped().Delete(&second)
+		assert.NoError(t, userService.Update(first.ID, UpdateUserRequest{IsActive: &inactive}))
+		assert.ErrorIs(t, userService.Update(second.ID, UpdateUserRequest{IsActive: &inactive}), ErrLastAdmin)
+		assert.ErrorIs(t, userService.Delete(second.ID), ErrLastAdmin)
+
+		// Admin nonaktif tidak dihitung, boleh dihapus
+		assert.NoError(t, userService.Delete(first.ID))
+	})
+}
+
+// --- TEST 1c: API KEY SERVICE ACCOUNT ---
+
+func TestAPIKey_Integration(t *testing.T) {
+	postgres.SeedPermissions(testDB)
+	apiKeyRepo := repoPostgre.NewAPIKeyRepository(testDB)
+	keys := NewAPIKeyService(apiKeyRepo, repoPostgre.NewPermissionRepository(testDB), &config.Config{APIKeyDefaultScopes: "achievement:read_all report:read"})
+
+	account, err := keys.CreateAccount(ServiceAccountRequest{Name: "test-website-fakultas"}, uuid.New())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer apiKeyRepo.DeleteAccount(account.ID)
+
+	// Tanpa scope = default read-only
+	created, err := keys.CreateKey(account.ID, CreateAPIKeyRequest{Name: "produksi"}, uuid.New())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.True(t, strings.HasPrefix(created.Key, APIKeyPrefix))
+	assert.Equal(t, []string{"achievement:read_all", "report:read"}, created.Scopes)
+
+	subject, scopes, ok := keys.Authenticate(created.Key, "10.0.0.1")
+	assert.True(t, ok)
+	assert.Equal(t, account.ID.String(), subject)
+	assert.Contains(t, scopes, "achievement:read_all")
+	_, _, ok = keys.Authenticate(created.Key+"x", "10.0.0.1")
+	assert.False(t, ok)
+
+	list, _ := keys.ListKeys(account.ID)
+	if assert.Len(t, list, 1) {
+		assert.NotNil(t, list[0].LastUsedAt)
+		assert.Equal(t, "10.0.0.1", list[0].LastUsedIP)
+	}
+
+	// Scope tulis butuh allow_write, administrasi akun tidak pernah boleh
+	_, err = keys.CreateKey(account.ID, CreateAPIKeyRequest{Name: "tulis", Scopes: []string{"achievement:verify"}}, uuid.New())
+	assert.ErrorIs(t, err, ErrAPIKeyScope)
+	_, err = keys.CreateKey(account.ID, CreateAPIKeyRequest{Name: "tulis", Scopes: []string{"achievement:verify"}, AllowWrite: true}, uuid.New())
+	assert.NoError(t, err)
+	_, err = keys.CreateKey(account.ID, CreateAPIKeyRequest{Name: "admin", Scopes: []string{"user:manage"}, AllowWrite: true}, uuid.New())
+	assert.ErrorIs(t, err, ErrAPIKeyScope)
+
+	// Revoke & nonaktifkan akun berlaku langsung
+	assert.NoError(t, keys.RevokeKey(account.ID, created.ID))
+	_, _, ok = keys.Authenticate(created.Key, "10.0.0.1")
+	assert.False(t, ok)
+	assert.ErrorIs(t, keys.RevokeKey(account.ID, created.ID), ErrAPIKeyNotFound)
+
+	other, _ := keys.CreateKey(account.ID, CreateAPIKeyRequest{Name: "cadangan"}, uuid.New())
+	inactive := false
+	assert.NoError(t, keys.UpdateAccount(account.ID, ServiceAccountRequest{IsActive: &inactive}))
+	_, _, ok = keys.Authenticate(other.Key, "10.0.0.1")
+	assert.False(t, ok)
 }
 
 // --- TEST 2: ACHIEVEMENT FLOW (Create & Verify) ---
@@ -246,3 +912,507 @@ func TestAchievementFlow_Integration(t *testing.T) {
 		assert.Equal(t, dosenUser.ID, *check.VerifiedBy)
 	})
 }
+
+// storedFileExists: file masih ada di storage test
+func storedFileExists(key string) bool {
+	r, err := testStorage.Get(context.Background(), key)
+	if err != nil {
+		return false
+	}
+	r.Close()
+	return true
+}
+
+// --- TEST 3: SIGNED URL EVIDENCE ---
+
+func TestSignedAttachment_Integration(t *testing.T) {
+	ctx := context.Background()
+	mhsUser, cleanup := createTestStudent(t, "mhs_signed_test")
+	defer cleanup()
+
+	ach, err := achService.Create(ctx, mhsUser.ID, CreateAchievementRequest{Title: "Lomba Signed URL", Type: "competition", Points: 10})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	uploaded, err := achService.UploadEvidence(ctx, mhsUser.ID, ach.ID, testPDF("signed-url"))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	key := evidenceKey(uploaded.Checksum)
+	signedURL, _ := achService.links.SignedURL(key)
+	u, _ := url.Parse(signedURL)
+	expires, signature := u.Query().Get("expires"), u.Query().Get("signature")
+
+	// Signature valid: sampai ke pengecekan status scan (processor tidak jalan di test)
+	_, err = achService.OpenSignedAttachment(ctx, key, expires, signature)
+	assert.ErrorIs(t, err, ErrAttachmentPending)
+
+	// Setelah prestasi dihapus, signed URL yang sudah terbit ikut tidak berlaku
+	assert.NoError(t, achService.Delete(ctx, mhsUser.ID, ach.ID))
+	_, err = achService.OpenSignedAttachment(ctx, key, expires, signature)
+	assert.ErrorIs(t, err, ErrAttachmentNotFound)
+}
+
+// --- TEST 4: EDIT ATTACHMENT (Hapus & Ganti) ---
+
+func TestAttachmentEdit_Integration(t *testing.T) {
+	ctx := context.Background()
+	mhsUser, cleanup := createTestStudent(t, "mhs_attach_edit")
+	defer cleanup()
+	otherUser, cleanupOther := createTestStudent(t, "mhs_attach_other")
+	defer cleanupOther()
+
+	ach, err := achService.Create(ctx, mhsUser.ID, CreateAchievementRequest{Title: "Lomba Edit Evidence", Type: "competition", Points: 10})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	first, err := achService.UploadEvidence(ctx, mhsUser.ID, ach.ID, testPDF("edit-first"))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	second, err := achService.UploadEvidence(ctx, mhsUser.ID, ach.ID, testPDF("edit-second"))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	t.Run("Ganti Attachment (ID Tetap)", func(t *testing.T) {
+		replaced, err := achService.ReplaceAttachment(ctx, mhsUser.ID, ach.ID, first.ID, testPDF("edit-replaced"))
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		assert.Equal(t, first.ID, replaced.ID)
+		assert.NotEqual(t, first.Checksum, replaced.Checksum)
+		assert.Equal(t, "edit-replaced.pdf", replaced.FileName)
+	})
+
+	t.Run("Bukan Pemilik Ditolak", func(t *testing.T) {
+		assert.Error(t, achService.DeleteAttachment(ctx, otherUser.ID, ach.ID, second.ID))
+		_, err := achService.ReplaceAttachment(ctx, otherUser.ID, ach.ID, second.ID, testPDF("edit-other"))
+		assert.Error(t, err)
+	})
+
+	t.Run("Attachment Tidak Ada", func(t *testing.T) {
+		assert.ErrorIs(t, achService.DeleteAttachment(ctx, mhsUser.ID, ach.ID, primitive.NewObjectID().Hex()), ErrAttachmentNotFound)
+		assert.ErrorIs(t, achService.DeleteAttachment(ctx, mhsUser.ID, ach.ID, "bukan-object-id"), ErrAttachmentNotFound)
+	})
+
+	t.Run("Tidak Bisa Diedit Setelah Submit", func(t *testing.T) {
+		if !assert.NoError(t, achService.Submit(ctx, mhsUser.ID, ach.ID)) {
+			t.FailNow()
+		}
+		_, err := achService.UploadEvidence(ctx, mhsUser.ID, ach.ID, testPDF("edit-after-submit"))
+		assert.EqualError(t, err, "cannot upload evidence for status: submitted")
+		err = achService.DeleteAttachment(ctx, mhsUser.ID, ach.ID, second.ID)
+		assert.EqualError(t, err, "cannot delete evidence for status: submitted")
+		_, err = achService.ReplaceAttachment(ctx, mhsUser.ID, ach.ID, second.ID, testPDF("edit-after-submit"))
+		assert.EqualError(t, err, "cannot replace evidence for status: submitted")
+
+		// File tetap ada
+		assert.True(t, storedFileExists(evidenceKey(second.Checksum)))
+	})
+}
+
+// --- TEST 5: EVIDENCE CONTENT-ADDRESSED (Reference Counting) ---
+
+func TestEvidenceRefCount_Integration(t *testing.T) {
+	ctx := context.Background()
+	mhsUser, cleanup := createTestStudent(t, "mhs_refcount")
+	defer cleanup()
+
+	achA, err := achService.Create(ctx, mhsUser.ID, CreateAchievementRequest{Title: "Lomba A", Type: "competition", Points: 10})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	achB, err := achService.Create(ctx, mhsUser.ID, CreateAchievementRequest{Title: "Lomba B", Type: "competition", Points: 10})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	refCount := func(hash string) int {
+		file, err := evidenceRepo.FindByHash(ctx, hash)
+		if err != nil {
+			return 0
+		}
+		return file.RefCount
+	}
+
+	t.Run("Hash Sama Dipakai Bersama", func(t *testing.T) {
+		a, err := achService.UploadEvidence(ctx, mhsUser.ID, achA.ID, testPDF("refcount-shared"))
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		b, err := achService.UploadEvidence(ctx, mhsUser.ID, achB.ID, testPDF("refcount-shared"))
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		key := evidenceKey(a.Checksum)
+		assert.Equal(t, a.Checksum, b.Checksum)
+		assert.Equal(t, a.FileURL, b.FileURL)
+		assert.Equal(t, 2, refCount(a.Checksum))
+
+		// Hapus salah satu: file tetap ada untuk attachment lainnya
+		assert.NoError(t, achService.DeleteAttachment(ctx, mhsUser.ID, achA.ID, a.ID))
+		assert.Equal(t, 1, refCount(a.Checksum))
+		assert.True(t, storedFileExists(key))
+
+		// Referensi terakhir: file ikut dihapus
+		assert.NoError(t, achService.DeleteAttachment(ctx, mhsUser.ID, achB.ID, b.ID))
+		assert.Equal(t, 0, refCount(a.Checksum))
+		assert.False(t, storedFileExists(key))
+	})
+
+	t.Run("Referensi Tanpa File Ditulis Ulang", func(t *testing.T) {
+		// Pengupload pertama sudah Acquire tapi Put-nya belum selesai / gagal
+		file := testPDF("refcount-unstored")
+		content, _ := io.ReadAll(file.Content)
+		sum := sha256.Sum256(content)
+		hash := hex.EncodeToString(sum[:])
+		created, err := evidenceRepo.Acquire(ctx, mongoModel.EvidenceFile{Hash: hash, StorageKey: evidenceKey(hash), ContentType: "application/pdf", Size: int64(len(content))})
+		if !assert.NoError(t, err) || !assert.True(t, created) {
+			t.FailNow()
+		}
+		assert.False(t, storedFileExists(evidenceKey(hash)))
+
+		att, err := achService.UploadEvidence(ctx, mhsUser.ID, achA.ID, testPDF("refcount-unstored"))
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		assert.Equal(t, hash, att.Checksum)
+		assert.Equal(t, 2, refCount(hash))
+		assert.True(t, storedFileExists(evidenceKey(hash)))
+
+		// Pengupload pertama batal: file tetap ada untuk attachment yang sudah dibuat
+		remaining, err := evidenceRepo.Release(ctx, hash)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, remaining)
+		assert.NoError(t, achService.DeleteAttachment(ctx, mhsUser.ID, achA.ID, att.ID))
+		assert.False(t, storedFileExists(evidenceKey(hash)))
+	})
+
+	t.Run("Ganti Attachment Melepas Hash Lama", func(t *testing.T) {
+		old, err := achService.UploadEvidence(ctx, mhsUser.ID, achA.ID, testPDF("refcount-old"))
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		replaced, err := achService.ReplaceAttachment(ctx, mhsUser.ID, achA.ID, old.ID, testPDF("refcount-new"))
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		assert.Equal(t, 0, refCount(old.Checksum))
+		assert.False(t, storedFileExists(evidenceKey(old.Checksum)))
+		assert.Equal(t, 1, refCount(replaced.Checksum))
+		assert.True(t, storedFileExists(evidenceKey(replaced.Checksum)))
+	})
+
+	t.Run("Ganti Attachment Hash Lama Masih Dipakai", func(t *testing.T) {
+		kept, err := achService.UploadEvidence(ctx, mhsUser.ID, achB.ID, testPDF("refcount-kept"))
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		other, err := achService.UploadEvidence(ctx, mhsUser.ID, achA.ID, testPDF("refcount-kept"))
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		_, err = achService.ReplaceAttachment(ctx, mhsUser.ID, achA.ID, other.ID, testPDF("refcount-other"))
+		assert.NoError(t, err)
+		assert.Equal(t, 1, refCount(kept.Checksum))
+		assert.True(t, storedFileExists(evidenceKey(kept.Checksum)))
+	})
+}
+
+// --- TEST 6: EVIDENCE PROCESSOR (Scan, Karantina & Thumbnail) ---
+
+func TestEvidenceProcessor_Integration(t *testing.T) {
+	ctx := context.Background()
+	mhsUser, cleanup := createTestStudent(t, "mhs_processor")
+	defer cleanup()
+
+	processor := &EvidenceProcessor{
+		storage:      testStorage,
+		scanner:      fakeScanner{},
+		evidenceRepo: evidenceRepo,
+		achMongoRepo: achMongoRepo,
+		links:        achService.links,
+		workers:      1,
+		jobs:         make(chan EvidenceJob, 10),
+	}
+	achA, err := achService.Create(ctx, mhsUser.ID, CreateAchievementRequest{Title: "Lomba Scan A", Type: "competition", Points: 10})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	achB, err := achService.Create(ctx, mhsUser.ID, CreateAchievementRequest{Title: "Lomba Scan B", Type: "competition", Points: 10})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	// attachmentsByChecksum: attachment di kedua prestasi dengan checksum tersebut
+	attachmentsByChecksum := func(hash string) []mongoModel.Attachment {
+		docs, _ := achMongoRepo.FindByIDs(ctx, []string{achA.MongoAchievementID, achB.MongoAchievementID})
+		var result []mongoModel.Attachment
+		for _, doc := range docs {
+			for _, att := range doc.Attachments {
+				if att.Checksum == hash {
+					result = append(result, att)
+				}
+			}
+		}
+		return result
+	}
+	upload := func(file func() EvidenceFile) *AttachmentDTO {
+		first, err := achService.UploadEvidence(ctx, mhsUser.ID, achA.ID, file())
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		_, err = achService.UploadEvidence(ctx, mhsUser.ID, achB.ID, file())
+		assert.NoError(t, err)
+		return first
+	}
+
+	t.Run("Malware Dikarantina", func(t *testing.T) {
+		infected := upload(func() EvidenceFile { return testPDF("EICAR-processor") })
+		job := EvidenceJob{Hash: infected.Checksum, StorageKey: evidenceKey(infected.Checksum), ContentType: infected.FileType}
+		if !assert.NoError(t, processor.process(ctx, job)) {
+			t.FailNow()
+		}
+
+		assert.False(t, storedFileExists(evidenceKey(infected.Checksum)))
+		assert.True(t, storedFileExists("quarantine/"+infected.Checksum))
+
+		attachments := attachmentsByChecksum(infected.Checksum)
+		assert.Len(t, attachments, 2)
+		for _, att := range attachments {
+			assert.Equal(t, AttachmentRejected, att.Status)
+			assert.Equal(t, "malware detected: Eicar-Test-Signature", att.RejectionReason)
+			assert.Empty(t, att.ThumbnailKey)
+		}
+
+		// Upload ulang isi yang sama langsung ditolak
+		_, err := achService.UploadEvidence(ctx, mhsUser.ID, achA.ID, testPDF("EICAR-processor"))
+		assert.ErrorIs(t, err, ErrEvidenceRejected)
+	})
+
+	t.Run("File Bersih Dapat Thumbnail", func(t *testing.T) {
+		clean := upload(func() EvidenceFile { return testPNG("processor-clean", 640, 480) })
+		job := EvidenceJob{Hash: clean.Checksum, StorageKey: evidenceKey(clean.Checksum), ContentType: clean.FileType}
+		if !assert.NoError(t, processor.process(ctx, job)) {
+			t.FailNow()
+		}
+
+		assert.True(t, storedFileExists(evidenceKey(clean.Checksum)))
+		assert.True(t, storedFileExists(thumbnailKey(clean.Checksum)))
+
+		attachments := attachmentsByChecksum(clean.Checksum)
+		assert.Len(t, attachments, 2)
+		for _, att := range attachments {
+			assert.Equal(t, AttachmentClean, att.Status)
+			assert.Equal(t, thumbnailKey(clean.Checksum), att.ThumbnailKey)
+			assert.Equal(t, achService.links.URL(thumbnailKey(clean.Checksum)), att.ThumbnailURL)
+		}
+
+		// Attachment baru dengan isi sama: hasil yang tersimpan disalin tanpa scan ulang
+		_, err := achService.UploadEvidence(ctx, mhsUser.ID, achA.ID, testPNG("processor-clean", 640, 480))
+		assert.NoError(t, err)
+		assert.NoError(t, processor.process(ctx, job))
+		for _, att := range attachmentsByChecksum(clean.Checksum) {
+			assert.Equal(t, AttachmentClean, att.Status)
+		}
+	})
+}
+
+// --- TEST 7: RESUMABLE UPLOAD (tus) ---
+
+func TestResumableUpload_Integration(t *testing.T) {
+	ctx := context.Background()
+	mhsUser, cleanup := createTestStudent(t, "mhs_resumable")
+	defer cleanup()
+
+	uploads, err := NewResumableUploadService(&config.Config{TusUploadDir: t.TempDir(), TusUploadTTL: time.Hour}, achService, achService.policy)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	ach, err := achService.Create(ctx, mhsUser.ID, CreateAchievementRequest{Title: "Lomba Resumable", Type: "competition", Points: 10})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	file := testPDF("resumable-upload")
+	content, _ := io.ReadAll(file.Content)
+	upload, err := uploads.Create(mhsUser.ID, ach.ID, int64(len(content)), "../sertifikat lomba.pdf")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	// Dua chunk, chunk terakhir meneruskan file ke UploadEvidence
+	upload, err = uploads.Append(ctx, mhsUser.ID, ach.ID, upload.ID, 0, bytes.NewReader(content[:100]))
+	assert.NoError(t, err)
+	assert.Nil(t, upload.Attachment)
+	upload, err = uploads.Append(ctx, mhsUser.ID, ach.ID, upload.ID, 100, bytes.NewReader(content[100:]))
+	if !assert.NoError(t, err) || !assert.NotNil(t, upload.Attachment) {
+		t.FailNow()
+	}
+	assert.Equal(t, "sertifikat_lomba.pdf", upload.Attachment.FileName)
+	assert.Equal(t, int64(len(content)), upload.Attachment.Size)
+	assert.True(t, storedFileExists(evidenceKey(upload.Attachment.Checksum)))
+
+	docs, _ := achMongoRepo.FindByIDs(ctx, []string{ach.MongoAchievementID})
+	if assert.Len(t, docs, 1) && assert.Len(t, docs[0].Attachments, 1) {
+		assert.Equal(t, upload.Attachment.ID, docs[0].Attachments[0].ID.Hex())
+	}
+
+	// Status selesai tetap bisa dilihat, PATCH lagi ditolak
+	status, err := uploads.Get(mhsUser.ID, ach.ID, upload.ID)
+	if assert.NoError(t, err) {
+		assert.Equal(t, upload.Attachment.ID, status.Attachment.ID)
+	}
+	_, err = uploads.Append(ctx, mhsUser.ID, ach.ID, upload.ID, int64(len(content)), bytes.NewReader(nil))
+	assert.ErrorIs(t, err, ErrUploadCompleted)
+
+	// Prestasi yang tidak bisa diedit ditolak sebelum byte pertama dikirim
+	assert.NoError(t, achService.Submit(ctx, mhsUser.ID, ach.ID))
+	_, err = uploads.Create(mhsUser.ID, ach.ID, int64(len(content)), "lagi.pdf")
+	assert.EqualError(t, err, "cannot upload evidence for status: submitted")
+}
+
+func TestStorageGC_LegacyAttachment_Integration(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	fileStorage, err := storage.NewLocalStorage(root, "http://localhost:3000/uploads")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	// Attachment lama: hanya file_url static, storage_key belum diisi migrasi
+	id, err := achMongoRepo.Insert(ctx, &mongoModel.Achievement{
+		StudentPostgresID: uuid.New().String(),
+		AchievementType:   "competition",
+		Title:             "Prestasi Lama GC",
+		Attachments: []mongoModel.Attachment{{
+			ID:         primitive.NewObjectID(),
+			FileName:   "legacy gc.pdf",
+			FileURL:    "http://localhost:3000/uploads/legacy%20gc-test.pdf",
+			FileType:   "application/pdf",
+			UploadedAt: time.Now().Add(-time.Hour),
+		}},
+	})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer func() {
+		objID, _ := primitive.ObjectIDFromHex(id)
+		achMongoRepo.Coll.DeleteOne(ctx, bson.M{"_id": objID})
+	}()
+
+	old := time.Now().Add(-time.Hour)
+	for key, content := range map[string]string{
+		"legacy gc-test.pdf": "legacy evidence",
+		"orphan-gc-test.pdf": "orphan evidence",
+	} {
+		assert.NoError(t, fileStorage.Put(ctx, key, strings.NewReader(content), int64(len(content)), "application/pdf"))
+		assert.NoError(t, os.Chtimes(root+"/"+key, old, old))
+	}
+
+	gc := NewStorageGC(&config.Config{StorageGCGracePeriod: time.Minute}, fileStorage, achMongoRepo, evidenceRepo)
+
+	report, err := gc.Run(ctx, true)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if assert.Len(t, report.Orphans, 1) {
+		assert.Equal(t, "orphan-gc-test.pdf", report.Orphans[0].Key)
+	}
+	assert.Equal(t, int64(len("orphan evidence")), report.OrphanBytes)
+	assert.Zero(t, report.DeletedBytes)
+
+	report, err = gc.Run(ctx, false)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, 1, report.DeletedFiles)
+	assert.Equal(t, int64(len("orphan evidence")), report.DeletedBytes)
+	_, err = os.Stat(root + "/legacy gc-test.pdf")
+	assert.NoError(t, err, "file attachment lama tidak boleh dihapus")
+	_, err = os.Stat(root + "/orphan-gc-test.pdf")
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestExportBuild_Integration(t *testing.T) {
+	ctx := context.Background()
+	mhsUser, cleanup := createTestStudent(t, "mhs_export")
+	defer cleanup()
+	program := "Prodi Export " + uuid.NewString()[:8]
+	testDB.Model(&mhsUser).Update("full_name", "Budi Santoso")
+	testDB.Model(&postgre.Student{}).Where("user_id = ?", mhsUser.ID).Update("program_study", program)
+	var student postgre.Student
+	testDB.Where("user_id = ?", mhsUser.ID).First(&student)
+
+	content := "isi sertifikat"
+	assert.NoError(t, testStorage.Put(ctx, "export-test/sertifikat.pdf", strings.NewReader(content), int64(len(content)), "application/pdf"))
+	defer testStorage.Delete(ctx, "export-test/sertifikat.pdf")
+
+	// Dua prestasi verified: attachment pending & yang hilang dari storage tidak ikut, nomor file mengikuti urutan asli
+	now := time.Now()
+	for i, doc := range []mongoModel.Achievement{
+		{Title: "Lomba: Juara 1", Attachments: []mongoModel.Attachment{
+			{ID: primitive.NewObjectID(), FileName: "sertifikat juara.pdf", StorageKey: "export-test/sertifikat.pdf", Status: AttachmentClean},
+			{ID: primitive.NewObjectID(), FileName: "video.mp4", StorageKey: "export-test/video.mp4", Status: AttachmentPending},
+			{ID: primitive.NewObjectID(), FileName: "../foto.pdf", StorageKey: "export-test/sertifikat.pdf"},
+		}},
+		{Title: "Seminar Nasional", Attachments: []mongoModel.Attachment{
+			{ID: primitive.NewObjectID(), FileName: "hilang.pdf", StorageKey: "export-test/hilang.pdf", Status: AttachmentClean},
+		}},
+	} {
+		doc.StudentPostgresID = student.ID.String()
+		doc.AchievementType = "competition"
+		mongoID, err := achMongoRepo.Insert(ctx, &doc)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		defer func() {
+			objID, _ := primitive.ObjectIDFromHex(mongoID)
+			achMongoRepo.Coll.DeleteOne(ctx, bson.M{"_id": objID})
+		}()
+		verifiedAt := now.Add(time.Duration(i-10) * time.Minute)
+		assert.NoError(t, testDB.Create(&postgre.AchievementReference{
+			StudentID: student.ID, MongoAchievementID: mongoID, Status: "verified", VerifiedAt: &verifiedAt,
+		}).Error)
+	}
+
+	exportSvc := NewExportService(&config.Config{ExportRetention: time.Hour}, achRefRepo, achMongoRepo, nil, testStorage)
+	job := &mongoModel.ExportJob{ID: primitive.NewObjectID(), ProgramStudy: program, From: now.Add(-time.Hour), To: now.Add(time.Hour)}
+	if !assert.NoError(t, exportSvc.build(ctx, job)) {
+		t.FailNow()
+	}
+	defer testStorage.Delete(ctx, job.StorageKey)
+	assert.Equal(t, 2, job.AchievementCount)
+	assert.Equal(t, 2, job.FileCount)
+
+	r, err := testStorage.Get(ctx, job.StorageKey)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	data, _ := io.ReadAll(r)
+	r.Close()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+	}
+	assert.Equal(t, []string{
+		"NIM_mhs_export_Budi_Santoso/01_Lomba_Juara_1/01_sertifikat_juara.pdf",
+		"NIM_mhs_export_Budi_Santoso/01_Lomba_Juara_1/03_foto.pdf",
+		"index.json",
+		"index.csv",
+	}, names)
+	assert.Equal(t, content, string(readZipFile(t, zr, names[0])))
+
+	var index []ExportIndexEntry
+	if assert.NoError(t, json.Unmarshal(readZipFile(t, zr, "index.json"), &index)) && assert.Len(t, index, 2) {
+		assert.Equal(t, "Budi Santoso", index[0].StudentName)
+		assert.Equal(t, names[:2], index[0].Files)
+		assert.Equal(t, []string{"video.mp4 (pending)"}, index[0].SkippedFiles)
+		assert.Empty(t, index[1].Files)
+		assert.Equal(t, []string{"hilang.pdf (missing)"}, index[1].SkippedFiles)
+	}
+	assert.True(t, bytes.HasPrefix(readZipFile(t, zr, "index.csv"), []byte("\ufeff")))
+}
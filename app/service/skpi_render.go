@@ -0,0 +1,211 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"time"
+
+	"github.com/go-pdf/fpdf"
+)
+
+// Nama bulan untuk tanggal berbahasa Indonesia
+var indonesianMonths = [...]string{"Januari", "Februari", "Maret", "April", "Mei", "Juni", "Juli", "Agustus", "September", "Oktober", "November", "Desember"}
+
+func formatDateID(t time.Time) string {
+	return fmt.Sprintf("%d %s %d", t.Day(), indonesianMonths[t.Month()-1], t.Year())
+}
+
+func formatDateEN(t time.Time) string {
+	return t.Format("January 2, 2006")
+}
+
+func formatOptionalDate(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Format("02/01/2006")
+}
+
+// --- HTML (bisa dibuka langsung oleh MS Word sebagai .doc) ---
+
+var skpiHTMLTemplate = template.Must(template.New("skpi").Funcs(template.FuncMap{
+	"dateID":   formatDateID,
+	"dateEN":   formatDateEN,
+	"optDate":  formatOptionalDate,
+	"sequence": func(i int) int { return i + 1 },
+}).Parse(`<html xmlns:o="urn:schemas-microsoft-com:office:office" xmlns:w="urn:schemas-microsoft-com:office:word" xmlns="http://www.w3.org/TR/REC-html40">
+<head>
+<meta charset="utf-8">
+<title>Surat Keterangan Pendamping Ijazah / Diploma Supplement</title>
+<style>
+body { font-family: "Times New Roman", serif; font-size: 11pt; }
+h1 { font-size: 14pt; text-align: center; margin-bottom: 0; }
+h2 { font-size: 12pt; margin-top: 18pt; }
+.en { font-style: italic; color: #444444; }
+table { border-collapse: collapse; width: 100%; }
+td, th { border: 1px solid #000000; padding: 4pt; vertical-align: top; }
+table.identity td { border: none; padding: 2pt; }
+.page { page-break-after: always; }
+.page:last-child { page-break-after: auto; }
+</style>
+</head>
+<body>
+{{range .}}
+<div class="page">
+<h1>SURAT KETERANGAN PENDAMPING IJAZAH</h1>
+<p style="text-align:center" class="en">Diploma Supplement</p>
+
+<h2>1. Identitas Pemegang / <span class="en">Holder Information</span></h2>
+<table class="identity">
+<tr><td>Nama Lengkap / <span class="en">Full Name</span></td><td>: {{.StudentName}}</td></tr>
+<tr><td>Nomor Induk Mahasiswa / <span class="en">Student ID Number</span></td><td>: {{.NIM}}</td></tr>
+<tr><td>Program Studi / <span class="en">Study Program</span></td><td>: {{.ProgramStudy}}</td></tr>
+<tr><td>Angkatan / <span class="en">Year of Entry</span></td><td>: {{.AcademicYear}}</td></tr>
+</table>
+
+<h2>2. Prestasi dan Penghargaan / <span class="en">Achievements and Awards</span></h2>
+{{if not .Categories}}
+<p>Tidak ada prestasi terverifikasi. / <span class="en">No verified achievements.</span></p>
+{{end}}
+{{range .Categories}}
+<h3>{{.LabelID}} / <span class="en">{{.LabelEN}}</span></h3>
+<table>
+<tr>
+<th>No</th>
+<th>Prestasi / <span class="en">Achievement</span></th>
+<th>Tingkat / <span class="en">Level</span></th>
+<th>Poin / <span class="en">Points</span></th>
+<th>Tanggal Verifikasi / <span class="en">Verified On</span></th>
+</tr>
+{{range $i, $item := .Items}}
+<tr>
+<td>{{sequence $i}}</td>
+<td><b>{{$item.Title}}</b>{{if $item.Description}}<br>{{$item.Description}}{{end}}</td>
+<td>{{if $item.Level}}{{$item.Level}}{{else}}-{{end}}</td>
+<td>{{$item.Points}}</td>
+<td>{{optDate $item.VerifiedAt}}</td>
+</tr>
+{{end}}
+</table>
+{{end}}
+
+<p>Total Poin / <span class="en">Total Points</span>: <b>{{.TotalPoints}}</b></p>
+<p>Diterbitkan pada {{dateID .GeneratedAt}} / <span class="en">Issued on {{dateEN .GeneratedAt}}</span></p>
+</div>
+{{end}}
+</body>
+</html>`))
+
+func RenderSKPIHTML(docs []SKPIDocument) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := skpiHTMLTemplate.Execute(&buf, docs); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// --- PDF ---
+
+func RenderSKPIPDF(docs []SKPIDocument) ([]byte, error) {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetTitle("Surat Keterangan Pendamping Ijazah / Diploma Supplement", true)
+	pdf.SetMargins(20, 20, 20)
+	pdf.SetAutoPageBreak(true, 20)
+	tr := pdf.UnicodeTranslatorFromDescriptor("") // cp1252, untuk nama dengan huruf non-ASCII
+
+	// Lebar kolom tabel prestasi (total 170mm)
+	colWidths := []float64{10, 85, 30, 15, 30}
+
+	for _, doc := range docs {
+		pdf.AddPage()
+
+		pdf.SetFont("Times", "B", 14)
+		pdf.CellFormat(0, 8, "SURAT KETERANGAN PENDAMPING IJAZAH", "", 1, "C", false, 0, "")
+		pdf.SetFont("Times", "I", 11)
+		pdf.CellFormat(0, 6, "Diploma Supplement", "", 1, "C", false, 0, "")
+		pdf.Ln(6)
+
+		pdf.SetFont("Times", "B", 12)
+		pdf.CellFormat(0, 7, tr("1. Identitas Pemegang / Holder Information"), "", 1, "L", false, 0, "")
+		pdf.SetFont("Times", "", 11)
+		identity := [][2]string{
+			{"Nama Lengkap / Full Name", doc.StudentName},
+			{"Nomor Induk Mahasiswa / Student ID Number", doc.NIM},
+			{"Program Studi / Study Program", doc.ProgramStudy},
+			{"Angkatan / Year of Entry", doc.AcademicYear},
+		}
+		for _, row := range identity {
+			pdf.CellFormat(80, 6, tr(row[0]), "", 0, "L", false, 0, "")
+			pdf.CellFormat(0, 6, tr(": "+row[1]), "", 1, "L", false, 0, "")
+		}
+		pdf.Ln(4)
+
+		pdf.SetFont("Times", "B", 12)
+		pdf.CellFormat(0, 7, tr("2. Prestasi dan Penghargaan / Achievements and Awards"), "", 1, "L", false, 0, "")
+
+		if len(doc.Categories) == 0 {
+			pdf.SetFont("Times", "", 11)
+			pdf.CellFormat(0, 6, tr("Tidak ada prestasi terverifikasi. / No verified achievements."), "", 1, "L", false, 0, "")
+		}
+
+		for _, cat := range doc.Categories {
+			pdf.Ln(2)
+			pdf.SetFont("Times", "B", 11)
+			pdf.CellFormat(0, 6, tr(cat.LabelID+" / "+cat.LabelEN), "", 1, "L", false, 0, "")
+
+			pdf.SetFont("Times", "B", 9)
+			pdf.SetFillColor(230, 230, 230)
+			headers := []string{"No", "Prestasi / Achievement", "Tingkat / Level", "Poin", "Verifikasi"}
+			for i, h := range headers {
+				pdf.CellFormat(colWidths[i], 6, tr(h), "1", 0, "C", true, 0, "")
+			}
+			pdf.Ln(-1)
+
+			pdf.SetFont("Times", "", 9)
+			for i, item := range cat.Items {
+				title := item.Title
+				if item.Description != "" {
+					title += " - " + item.Description
+				}
+				level := item.Level
+				if level == "" {
+					level = "-"
+				}
+
+				// Tinggi baris mengikuti kolom judul (multi-baris)
+				lines := pdf.SplitLines([]byte(tr(title)), colWidths[1]-2)
+				rowHeight := float64(len(lines)) * 5
+				if rowHeight < 6 {
+					rowHeight = 6
+				}
+				_, pageHeight := pdf.GetPageSize()
+				_, _, _, bottom := pdf.GetMargins()
+				if pdf.GetY()+rowHeight > pageHeight-bottom {
+					pdf.AddPage()
+				}
+
+				x, y := pdf.GetXY()
+				pdf.CellFormat(colWidths[0], rowHeight, fmt.Sprintf("%d", i+1), "1", 0, "C", false, 0, "")
+				pdf.MultiCell(colWidths[1], 5, tr(title), "", "L", false)
+				pdf.Rect(x+colWidths[0], y, colWidths[1], rowHeight, "D")
+				pdf.SetXY(x+colWidths[0]+colWidths[1], y)
+				pdf.CellFormat(colWidths[2], rowHeight, tr(level), "1", 0, "C", false, 0, "")
+				pdf.CellFormat(colWidths[3], rowHeight, fmt.Sprintf("%d", item.Points), "1", 0, "C", false, 0, "")
+				pdf.CellFormat(colWidths[4], rowHeight, formatOptionalDate(item.VerifiedAt), "1", 1, "C", false, 0, "")
+			}
+		}
+
+		pdf.Ln(4)
+		pdf.SetFont("Times", "B", 11)
+		pdf.CellFormat(0, 6, fmt.Sprintf("Total Poin / Total Points: %d", doc.TotalPoints), "", 1, "L", false, 0, "")
+		pdf.SetFont("Times", "", 10)
+		pdf.CellFormat(0, 6, tr("Diterbitkan pada "+formatDateID(doc.GeneratedAt)+" / Issued on "+formatDateEN(doc.GeneratedAt)), "", 1, "L", false, 0, "")
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
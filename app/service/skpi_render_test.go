@@ -0,0 +1,73 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderSKPIHTML_EscapesContent(t *testing.T) {
+	verified := time.Date(2025, 3, 14, 0, 0, 0, 0, time.UTC)
+	docs := []SKPIDocument{{
+		StudentName:  "Budi <b>Santoso</b>",
+		NIM:          "187221001",
+		ProgramStudy: "Sistem Informasi",
+		AcademicYear: "2022",
+		Categories: []SKPICategory{{
+			Type:    "competition",
+			LabelID: "Kompetisi",
+			LabelEN: "Competition",
+			Items: []SKPIItem{{
+				Title:       `<script>alert("xss")</script>`,
+				Description: `Juara 1 & "Best Paper" <img src=x onerror=alert(1)>`,
+				Points:      50,
+				VerifiedAt:  &verified,
+			}},
+		}},
+		TotalPoints: 50,
+		GeneratedAt: time.Date(2025, 8, 17, 0, 0, 0, 0, time.UTC),
+	}}
+
+	out, err := RenderSKPIHTML(docs)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	html := string(out)
+
+	assert.NotContains(t, html, "<script>")
+	assert.NotContains(t, html, "<img src=x")
+	assert.NotContains(t, html, "<b>Santoso</b>")
+	assert.Contains(t, html, "&lt;script&gt;alert(&#34;xss&#34;)&lt;/script&gt;")
+	assert.Contains(t, html, "Juara 1 &amp; &#34;Best Paper&#34; &lt;img src=x onerror=alert(1)&gt;")
+	assert.Contains(t, html, "Budi &lt;b&gt;Santoso&lt;/b&gt;")
+
+	// Label, tanggal dwibahasa & level kosong
+	assert.Contains(t, html, "Kompetisi / <span class=\"en\">Competition</span>")
+	assert.Contains(t, html, "14/03/2025")
+	assert.Contains(t, html, "Diterbitkan pada 17 Agustus 2025")
+	assert.Contains(t, html, "Issued on August 17, 2025")
+	assert.Equal(t, 1, strings.Count(html, `<div class="page">`))
+}
+
+func TestRenderSKPIHTML_NoAchievements(t *testing.T) {
+	out, err := RenderSKPIHTML([]SKPIDocument{{StudentName: "Ani", GeneratedAt: time.Now()}})
+	assert.NoError(t, err)
+	assert.Contains(t, string(out), "Tidak ada prestasi terverifikasi.")
+}
+
+func TestSKPICategoryLabel(t *testing.T) {
+	tests := []struct{ key, id, en string }{
+		{"competition", "Kompetisi", "Competition"},
+		{"kompetisi", "Kompetisi", "Competition"},
+		{"publikasi", "Publikasi Ilmiah", "Scientific Publication"},
+		{"hackathon", "Hackathon", "Hackathon"}, // tidak dikenal: huruf pertama kapital
+		{"", "Lainnya", "Others"},
+	}
+	for _, tt := range tests {
+		id, en := skpiCategoryLabel(tt.key)
+		assert.Equal(t, tt.id, id, tt.key)
+		assert.Equal(t, tt.en, en, tt.key)
+	}
+}
@@ -0,0 +1,240 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	mongoModel "reportachievement/app/model/mongo"
+	postgreModel "reportachievement/app/model/postgre"
+	mongoRepo "reportachievement/app/repository/mongo"
+	postgreRepo "reportachievement/app/repository/postgre"
+)
+
+var (
+	ErrStudentNotFound      = errors.New("student not found")
+	ErrSKPIForbidden        = errors.New("unauthorized")
+	ErrAcademicYearRequired = errors.New("academic_year is required")
+	ErrCohortNotFound       = errors.New("no students found for this cohort")
+)
+
+// SKPI = Surat Keterangan Pendamping Ijazah (Diploma Supplement)
+type SKPIService struct {
+	studentRepo  *postgreRepo.StudentRepository
+	lecturerRepo *postgreRepo.LecturerRepository
+	achRefRepo   *postgreRepo.AchievementRepository
+	achMongoRepo *mongoRepo.AchievementRepository
+}
+
+func NewSKPIService(
+	studentRepo *postgreRepo.StudentRepository,
+	lecturerRepo *postgreRepo.LecturerRepository,
+	achRefRepo *postgreRepo.AchievementRepository,
+	achMongoRepo *mongoRepo.AchievementRepository,
+) *SKPIService {
+	return &SKPIService{
+		studentRepo:  studentRepo,
+		lecturerRepo: lecturerRepo,
+		achRefRepo:   achRefRepo,
+		achMongoRepo: achMongoRepo,
+	}
+}
+
+// --- DTO ---
+
+type SKPIDocument struct {
+	StudentID    uuid.UUID      `json:"student_id"`
+	StudentName  string         `json:"student_name"`
+	NIM          string         `json:"nim"`
+	ProgramStudy string         `json:"program_study"`
+	AcademicYear string         `json:"academic_year"`
+	Categories   []SKPICategory `json:"categories"`
+	TotalPoints  int            `json:"total_points"`
+	GeneratedAt  time.Time      `json:"generated_at"`
+}
+
+// Satu kategori = satu achievement_type, label dwibahasa
+type SKPICategory struct {
+	Type    string     `json:"type"`
+	LabelID string     `json:"label_id"`
+	LabelEN string     `json:"label_en"`
+	Items   []SKPIItem `json:"items"`
+}
+
+type SKPIItem struct {
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	Level       string     `json:"level,omitempty"`
+	Points      int        `json:"points"`
+	VerifiedAt  *time.Time `json:"verified_at"`
+	VerifiedBy  string     `json:"verified_by,omitempty"`
+}
+
+// Label kategori (Indonesia, Inggris). Key = achievement_type (lowercase).
+var skpiCategoryLabels = map[string][2]string{
+	"academic":      {"Akademik", "Academic"},
+	"akademik":      {"Akademik", "Academic"},
+	"competition":   {"Kompetisi", "Competition"},
+	"kompetisi":     {"Kompetisi", "Competition"},
+	"organization":  {"Organisasi", "Organization"},
+	"organisasi":    {"Organisasi", "Organization"},
+	"publication":   {"Publikasi Ilmiah", "Scientific Publication"},
+	"publikasi":     {"Publikasi Ilmiah", "Scientific Publication"},
+	"certification": {"Sertifikasi", "Certification"},
+	"sertifikasi":   {"Sertifikasi", "Certification"},
+	"community":     {"Pengabdian Masyarakat", "Community Service"},
+	"pengabdian":    {"Pengabdian Masyarakat", "Community Service"},
+}
+
+// --- METHODS ---
+
+// 1. SKPI per mahasiswa (Admin, mahasiswa ybs, atau dosen walinya)
+func (s *SKPIService) GetStudentSKPI(ctx context.Context, userID uuid.UUID, scope AccessScope, studentID uuid.UUID) (*SKPIDocument, error) {
+	student, err := s.studentRepo.FindByID(studentID)
+	if err != nil {
+		return nil, ErrStudentNotFound
+	}
+
+	if scope == ScopeOwn {
+		if student.UserID != userID {
+			return nil, fmt.Errorf("%w: you can only export your own SKPI", ErrSKPIForbidden)
+		}
+	} else if scope == ScopeAdvisee {
+		lecturer, err := s.lecturerRepo.FindByUserID(userID)
+		if err != nil {
+			return nil, fmt.Errorf("%w: lecturer profile not found", ErrSKPIForbidden)
+		}
+		if student.AdvisorID == nil || *student.AdvisorID != lecturer.ID {
+			return nil, fmt.Errorf("%w: you are not the advisor for this student", ErrSKPIForbidden)
+		}
+	} else if scope != ScopeAll {
+		return nil, fmt.Errorf("%w: missing achievement read permission", ErrSKPIForbidden)
+	}
+
+	docs, err := s.build(ctx, []postgreModel.Student{*student})
+	if err != nil {
+		return nil, err
+	}
+	return &docs[0], nil
+}
+
+// 2. SKPI satu angkatan (batch, khusus Admin)
+func (s *SKPIService) GetCohortSKPI(ctx context.Context, academicYear, programStudy string) ([]SKPIDocument, error) {
+	if academicYear == "" {
+		return nil, ErrAcademicYearRequired
+	}
+	students, err := s.studentRepo.FindByCohort(academicYear, programStudy)
+	if err != nil {
+		return nil, err
+	}
+	if len(students) == 0 {
+		return nil, ErrCohortNotFound
+	}
+	return s.build(ctx, students)
+}
+
+// build: gabungkan data Postgres (mahasiswa + status verified) dengan konten Mongo
+func (s *SKPIService) build(ctx context.Context, students []postgreModel.Student) ([]SKPIDocument, error) {
+	var studentIDs []uuid.UUID
+	for _, stu := range students {
+		studentIDs = append(studentIDs, stu.ID)
+	}
+
+	refs, err := s.achRefRepo.FindVerifiedByStudentIDs(studentIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	var mongoIDs []string
+	for _, ref := range refs {
+		mongoIDs = append(mongoIDs, ref.MongoAchievementID)
+	}
+
+	mongoMap := make(map[string]mongoModel.Achievement)
+	if len(mongoIDs) > 0 {
+		mongoDocs, err := s.achMongoRepo.FindByIDs(ctx, mongoIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, doc := range mongoDocs {
+			mongoMap[doc.ID.Hex()] = doc
+		}
+	}
+
+	// StudentID -> Type -> Items
+	grouped := make(map[uuid.UUID]map[string][]SKPIItem)
+	for _, ref := range refs {
+		doc, exists := mongoMap[ref.MongoAchievementID]
+		if !exists {
+			continue
+		}
+		item := SKPIItem{
+			Title:       doc.Title,
+			Description: doc.Description,
+			Level:       detailString(doc.Details, "level", "tingkat"),
+			Points:      doc.Points,
+			VerifiedAt:  ref.VerifiedAt,
+		}
+		if ref.Verifier != nil {
+			item.VerifiedBy = ref.Verifier.FullName
+		}
+
+		key := strings.ToLower(strings.TrimSpace(doc.AchievementType))
+		if grouped[ref.StudentID] == nil {
+			grouped[ref.StudentID] = make(map[string][]SKPIItem)
+		}
+		grouped[ref.StudentID][key] = append(grouped[ref.StudentID][key], item)
+	}
+
+	now := time.Now()
+	var docs []SKPIDocument
+	for _, stu := range students {
+		doc := SKPIDocument{
+			StudentID:    stu.ID,
+			StudentName:  stu.User.FullName,
+			NIM:          stu.NIM,
+			ProgramStudy: stu.ProgramStudy,
+			AcademicYear: stu.AcademicYear,
+			Categories:   []SKPICategory{},
+			GeneratedAt:  now,
+		}
+
+		for key, items := range grouped[stu.ID] {
+			labelID, labelEN := skpiCategoryLabel(key)
+			for _, item := range items {
+				doc.TotalPoints += item.Points
+			}
+			doc.Categories = append(doc.Categories, SKPICategory{Type: key, LabelID: labelID, LabelEN: labelEN, Items: items})
+		}
+		sort.Slice(doc.Categories, func(i, j int) bool { return doc.Categories[i].LabelID < doc.Categories[j].LabelID })
+
+		docs = append(docs, doc)
+	}
+	return docs, nil
+}
+
+func skpiCategoryLabel(key string) (string, string) {
+	if labels, ok := skpiCategoryLabels[key]; ok {
+		return labels[0], labels[1]
+	}
+	if key == "" {
+		return "Lainnya", "Others"
+	}
+	label := strings.ToUpper(key[:1]) + key[1:]
+	return label, label
+}
+
+// detailString: ambil nilai string pertama yang ada dari map Details
+func detailString(details map[string]interface{}, keys ...string) string {
+	for _, key := range keys {
+		if v, ok := details[key]; ok && v != nil {
+			return fmt.Sprintf("%v", v)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,196 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"log"
+	"path"
+	"strings"
+	"time"
+
+	mongoRepo "reportachievement/app/repository/mongo"
+	"reportachievement/config"
+	"reportachievement/storage"
+)
+
+// OrphanFile: file di storage yang tidak direferensikan attachment mana pun
+type OrphanFile struct {
+	Key        string    `json:"key"`
+	Size       int64     `json:"size"`
+	ModifiedAt time.Time `json:"modified_at"`
+}
+
+// StorageGCReport: hasil satu kali jalan garbage collector
+type StorageGCReport struct {
+	DryRun         bool         `json:"dry_run"`
+	GracePeriod    string       `json:"grace_period"`
+	ScannedFiles   int          `json:"scanned_files"`
+	Orphans        []OrphanFile `json:"orphans"`
+	OrphanBytes    int64        `json:"orphan_bytes"`
+	DeletedFiles   int          `json:"deleted_files"`
+	DeletedBytes   int64        `json:"deleted_bytes"`
+	StaleRecords   []string     `json:"stale_records"` // hash evidence_files tanpa attachment
+	DeletedRecords int          `json:"deleted_records"`
+	Errors         []string     `json:"errors,omitempty"`
+}
+
+// StorageGC: hapus file storage yang tidak dipakai lagi, misalnya milik prestasi yang
+// sudah dihapus atau sisa upload yang terhenti. File & record yang lebih muda dari
+// masa tenggang selalu dilewati agar upload yang sedang berjalan tidak ikut terhapus.
+type StorageGC struct {
+	storage      storage.Storage
+	achMongoRepo *mongoRepo.AchievementRepository
+	evidenceRepo *mongoRepo.EvidenceFileRepository
+	grace        time.Duration
+	interval     time.Duration
+}
+
+func NewStorageGC(
+	cfg *config.Config,
+	fileStorage storage.Storage,
+	achMongoRepo *mongoRepo.AchievementRepository,
+	evidenceRepo *mongoRepo.EvidenceFileRepository,
+) *StorageGC {
+	return &StorageGC{
+		storage:      fileStorage,
+		achMongoRepo: achMongoRepo,
+		evidenceRepo: evidenceRepo,
+		grace:        cfg.StorageGCGracePeriod,
+		interval:     cfg.StorageGCInterval,
+	}
+}
+
+// Start: jalankan GC berkala jika STORAGE_GC_INTERVAL_HOURS > 0
+func (g *StorageGC) Start(ctx context.Context) {
+	if g.interval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(g.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				report, err := g.Run(ctx, false)
+				if err != nil {
+					log.Println("⚠️ Storage GC gagal:", err)
+					continue
+				}
+				log.Printf("🧹 Storage GC: %d file & %d record dihapus (%d byte)",
+					report.DeletedFiles, report.DeletedRecords, report.DeletedBytes)
+			}
+		}
+	}()
+}
+
+// Run: dryRun = true hanya melaporkan tanpa menghapus apa pun
+func (g *StorageGC) Run(ctx context.Context, dryRun bool) (*StorageGCReport, error) {
+	cutoff := time.Now().Add(-g.grace)
+	report := &StorageGCReport{
+		DryRun:       dryRun,
+		GracePeriod:  g.grace.String(),
+		Orphans:      []OrphanFile{},
+		StaleRecords: []string{},
+	}
+
+	// 1. Kumpulkan referensi dari attachment (prestasi yang dihapus masih dihitung selama masa tenggang)
+	attachments, err := g.achMongoRepo.FindReferencedAttachments(ctx, cutoff)
+	if err != nil {
+		return nil, err
+	}
+	referenced := map[string]bool{}
+	hashes := map[string]bool{}
+	for _, att := range attachments {
+		referenced[att.StorageKey] = true
+		referenced[att.ThumbnailKey] = true
+		// Attachment lama yang belum dimigrasi (MigrateLegacyAttachments jalan saat server start,
+		// GC lewat CLI bisa lebih dulu): file-nya tetap dipakai walau storage_key masih kosong
+		if att.StorageKey == "" {
+			if key, ok := mongoRepo.LegacyStorageKey(att.FileURL); ok {
+				referenced[key] = true
+			}
+		}
+		if att.Checksum != "" {
+			hashes[att.Checksum] = true
+		}
+	}
+
+	// 2. Record evidence_files: lokasi file bisa berbeda dari attachment (karantina),
+	// record tanpa attachment berarti ref_count tidak lagi akurat dan ikut dibersihkan
+	files, err := g.evidenceRepo.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		if hashes[file.Hash] || file.UpdatedAt.After(cutoff) {
+			referenced[file.StorageKey] = true
+			referenced[file.ThumbnailKey] = true
+			continue
+		}
+		report.StaleRecords = append(report.StaleRecords, file.Hash)
+		if dryRun {
+			continue
+		}
+		deleted, err := g.evidenceRepo.DeleteStale(ctx, file.Hash, cutoff)
+		if err != nil {
+			report.Errors = append(report.Errors, file.Hash+": "+err.Error())
+			referenced[file.StorageKey] = true
+			referenced[file.ThumbnailKey] = true
+			continue
+		}
+		if !deleted {
+			// Di-Acquire ulang di sela-sela, file kembali dipakai
+			referenced[file.StorageKey] = true
+			referenced[file.ThumbnailKey] = true
+			continue
+		}
+		report.DeletedRecords++
+	}
+
+	// 3. File di storage yang tidak direferensikan & lebih tua dari masa tenggang
+	err = g.storage.List(ctx, func(obj storage.Object) error {
+		report.ScannedFiles++
+		if strings.HasPrefix(obj.Key, exportKeyPrefix) {
+			return nil // dikelola ExportService (masa simpan sendiri)
+		}
+		if referenced[obj.Key] || obj.ModTime.After(cutoff) {
+			return nil
+		}
+		report.Orphans = append(report.Orphans, OrphanFile{Key: obj.Key, Size: obj.Size, ModifiedAt: obj.ModTime})
+		report.OrphanBytes += obj.Size
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if dryRun {
+		return report, nil
+	}
+	for _, orphan := range report.Orphans {
+		// Isi yang sama bisa diupload ulang (key sama) setelah listing
+		if g.reacquired(ctx, orphan.Key) {
+			continue
+		}
+		if err := g.storage.Delete(ctx, orphan.Key); err != nil {
+			report.Errors = append(report.Errors, orphan.Key+": "+err.Error())
+			continue
+		}
+		report.DeletedFiles++
+		report.DeletedBytes += orphan.Size
+	}
+	return report, nil
+}
+
+// reacquired: true jika key content-addressed (sha256/, thumbnails/, quarantine/)
+// kembali punya record evidence_files setelah record lamanya dihapus
+func (g *StorageGC) reacquired(ctx context.Context, key string) bool {
+	hash := strings.TrimSuffix(path.Base(key), ".jpg")
+	if len(hash) != sha256.Size*2 {
+		return false
+	}
+	_, err := g.evidenceRepo.FindByHash(ctx, hash)
+	return err == nil
+}
@@ -0,0 +1,61 @@
+package service
+
+import "strings"
+
+// Urutan penting: UA Edge/Opera/Samsung juga memuat "Chrome/", UA Chrome juga memuat "Safari/"
+var browserTokens = []struct{ token, name string }{
+	{"Edg", "Edge"},
+	{"OPR/", "Opera"},
+	{"SamsungBrowser/", "Samsung Internet"},
+	{"Firefox/", "Firefox"},
+	{"FxiOS/", "Firefox"},
+	{"CriOS/", "Chrome"},
+	{"Chrome/", "Chrome"},
+	{"Safari/", "Safari"},
+}
+
+// Android & ChromeOS dicek sebelum Linux, iPhone/iPad sebelum "Mac OS X" (UA iOS memuatnya juga)
+var osTokens = []struct{ token, name string }{
+	{"Windows", "Windows"},
+	{"Android", "Android"},
+	{"iPhone", "iOS"},
+	{"iPad", "iPadOS"},
+	{"Mac OS X", "macOS"},
+	{"CrOS", "ChromeOS"},
+	{"Linux", "Linux"},
+}
+
+// describeDevice: label singkat dari User-Agent untuk daftar session, misal "Chrome on Windows".
+// Client non-browser (curl, Postman, aplikasi mobile) ditampilkan dengan nama produknya.
+func describeDevice(userAgent string) string {
+	userAgent = strings.TrimSpace(userAgent)
+	if userAgent == "" {
+		return "Unknown device"
+	}
+
+	browser, platform := "", ""
+	for _, b := range browserTokens {
+		if strings.Contains(userAgent, b.token) {
+			browser = b.name
+			break
+		}
+	}
+	for _, o := range osTokens {
+		if strings.Contains(userAgent, o.token) {
+			platform = o.name
+			break
+		}
+	}
+
+	switch {
+	case browser != "" && platform != "":
+		return browser + " on " + platform
+	case browser != "":
+		return browser
+	case platform != "":
+		return platform
+	}
+	// Produk pertama tanpa versi: "curl/8.5.0" -> "curl"
+	product, _, _ := strings.Cut(strings.Fields(userAgent)[0], "/")
+	return product
+}
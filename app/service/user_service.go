@@ -4,17 +4,23 @@ import (
 	"errors"
 	"reportachievement/app/model/postgre"
 	repo "reportachievement/app/repository/postgre"
+	"reportachievement/password"
 
 	"github.com/google/uuid"
-	"golang.org/x/crypto/bcrypt"
 )
 
+var ErrUnknownAuthSource = errors.New("auth_source must be \"local\" or a configured provider such as \"ldap\"")
+
 type UserService struct {
-	userRepo *repo.UserRepository
+	userRepo    *repo.UserRepository
+	permRepo    *repo.PermissionRepository // Untuk menjaga admin terakhir
+	permService *PermissionService
+	authService *AuthService // Untuk mencabut session saat user dinonaktifkan/dihapus
+	policy      *password.Policy
 }
 
-func NewUserService(userRepo *repo.UserRepository) *UserService {
-	return &UserService{userRepo: userRepo}
+func NewUserService(userRepo *repo.UserRepository, permRepo *repo.PermissionRepository, permService *PermissionService, authService *AuthService, policy *password.Policy) *UserService {
+	return &UserService{userRepo: userRepo, permRepo: permRepo, permService: permService, authService: authService, policy: policy}
 }
 
 // DTO: Input Create User
@@ -24,6 +30,8 @@ type CreateUserRequest struct {
 	Password string `json:"password"`
 	FullName string `json:"full_name"`
 	RoleName string `json:"role"` // "Admin", "Mahasiswa", "Dosen Wali"
+	// "local" (default) atau "ldap": password diverifikasi ke LDAP, field password diabaikan
+	AuthSource string `json:"auth_source,omitempty"`
 
 	// Opsional: Data Profil
 	NIM          string `json:"nim,omitempty"`           // Jika Mahasiswa
@@ -38,6 +46,8 @@ type UpdateUserRequest struct {
 	FullName string `json:"full_name"`
 	Email    string `json:"email"`
 	IsActive *bool  `json:"is_active"` // Pointer agar bisa detect false
+	// Pindah ke "ldap" membuat password lokal tidak berlaku, kembali ke "local" perlu reset password
+	AuthSource string `json:"auth_source"`
 }
 
 // 1. Get All Users
@@ -53,20 +63,34 @@ func (s *UserService) Create(req CreateUserRequest) error {
 		return errors.New("invalid role name: " + req.RoleName)
 	}
 
-	// B. Hash Password
-	hashedPwd, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	// B. Validasi & Hash Password (password awal dari admin, user wajib menggantinya saat login pertama).
+	// Akun LDAP tidak punya password lokal.
+	authSource, err := s.authSource(req.AuthSource)
 	if err != nil {
 		return err
 	}
+	hashedPwd, mustChange := unusablePasswordHash, false
+	if authSource == postgre.AuthSourceLocal {
+		if err := s.policy.Validate(req.Password, req.Username, req.Email); err != nil {
+			return err
+		}
+		if hashedPwd, err = s.policy.Hash(req.Password); err != nil {
+			return err
+		}
+		mustChange = true
+	}
 
 	// C. Siapkan Object User Utama
 	newUser := postgre.User{
 		Username:     req.Username,
 		Email:        req.Email,
-		PasswordHash: string(hashedPwd),
+		PasswordHash: hashedPwd,
 		FullName:     req.FullName,
 		RoleID:       role.ID,
 		IsActive:     true,
+		AuthSource:   authSource,
+
+		MustChangePassword: mustChange,
 	}
 
 	// D. Tentukan apakah perlu buat profil tambahan
@@ -111,19 +135,106 @@ func (s *UserService) Update(id uuid.UUID, req UpdateUserRequest) error {
 	if req.Email != "" {
 		user.Email = req.Email
 	}
+	deactivated := false
 	if req.IsActive != nil {
+		deactivated = user.IsActive && !*req.IsActive
+		// Admin terakhir tidak boleh dinonaktifkan
+		if deactivated {
+			if err := s.ensureNotLastAdmin(user); err != nil {
+				return err
+			}
+		}
 		user.IsActive = *req.IsActive
 	}
+	sourceChanged := false
+	if req.AuthSource != "" && req.AuthSource != user.AuthSource {
+		authSource, err := s.authSource(req.AuthSource)
+		if err != nil {
+			return err
+		}
+		user.AuthSource = authSource
+		user.PasswordHash = unusablePasswordHash
+		user.MustChangePassword = false
+		sourceChanged = true
+	}
+
+	if err := s.userRepo.Update(user); err != nil {
+		return err
+	}
+	// Status wajib ganti password di cache session ikut berubah
+	if sourceChanged {
+		s.authService.InvalidateUserSessions(id)
+	}
 
-	return s.userRepo.Update(user)
+	// Akun dinonaktifkan: token yang sudah beredar langsung tidak berlaku
+	if deactivated {
+		return s.authService.RevokeAllSessions(id, "account deactivated")
+	}
+	return nil
 }
 
 // 4. Delete User
 func (s *UserService) Delete(id uuid.UUID) error {
 	// Cek dulu apakah user ada
-	_, err := s.userRepo.FindByID(id)
+	user, err := s.userRepo.FindByID(id)
 	if err != nil {
 		return errors.New("user not found")
 	}
+	// Admin terakhir tidak boleh dihapus
+	if err := s.ensureNotLastAdmin(user); err != nil {
+		return err
+	}
+
+	// Cabut dulu agar cache session di middleware ikut dibersihkan
+	if err := s.authService.RevokeAllSessions(id, "account deleted"); err != nil {
+		return err
+	}
 	return s.userRepo.Delete(id)
 }
+
+// 5. Revoke Sessions (paksa logout semua perangkat)
+func (s *UserService) RevokeSessions(id uuid.UUID) error {
+	if _, err := s.userRepo.FindByID(id); err != nil {
+		return errors.New("user not found")
+	}
+	return s.authService.RevokeAllSessions(id, "revoked by admin")
+}
+
+// 6. List Sessions (perangkat yang sedang login)
+func (s *UserService) ListSessions(id uuid.UUID) ([]SessionInfo, error) {
+	if _, err := s.userRepo.FindByID(id); err != nil {
+		return nil, errors.New("user not found")
+	}
+	return s.authService.ListSessions(id, uuid.Nil)
+}
+
+// 7. Revoke Session (logout satu perangkat)
+func (s *UserService) RevokeSession(id, sessionID uuid.UUID) error {
+	return s.authService.RevokeUserSession(id, sessionID, "revoked by admin")
+}
+
+// ensureNotLastAdmin: ErrLastAdmin jika user aktif ini satu-satunya pemegang permission role:manage
+func (s *UserService) ensureNotLastAdmin(user *postgre.User) error {
+	if !user.IsActive || !s.permService.HasPermission(user.Role.Name, adminPermission) {
+		return nil
+	}
+	remaining, err := s.permRepo.CountActiveUsersWithPermission(adminPermission, user.ID, uuid.Nil)
+	if err != nil {
+		return err
+	}
+	if remaining == 0 {
+		return ErrLastAdmin
+	}
+	return nil
+}
+
+// authSource: validasi auth_source dari admin, kosong = "local"
+func (s *UserService) authSource(name string) (string, error) {
+	if name == "" {
+		return postgre.AuthSourceLocal, nil
+	}
+	if !s.authService.HasProvider(name) {
+		return "", ErrUnknownAuthSource
+	}
+	return name, nil
+}
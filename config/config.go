@@ -2,16 +2,141 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
+// Fallback yang tercantum di repo (publik), hanya diterima saat APP_ENV=development
+const (
+	DefaultJWTSecret        = "rahasia_negara"
+	DefaultMFAEncryptionKey = "ganti_kunci_mfa_ini"
+)
+
 type Config struct {
 	AppPort     string
+	AppBaseURL  string // URL publik aplikasi, dipakai untuk membentuk link download
+	AppEnv      string // development | production
 	PostgresDSN string
 	MongoURI    string
 	MongoDBName string
 	JWTSecret   string
+
+	// Kunci JWT: HS256 | RS256 | EdDSA. RS256/EdDSA atau rotasi > 0 menyimpan kunci di JWTKeyDir
+	JWTAlgorithm   string
+	JWTKeyDir      string
+	JWTKeyRotation time.Duration // 0 = tidak dirotasi
+	JWTKeyOverlap  time.Duration // kunci baru dipublikasikan sebelum dipakai, kunci lama tetap valid sesudahnya
+
+	// Access token berumur pendek, diperpanjang lewat refresh token (rotasi)
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+
+	// Password policy
+	PasswordMinLength     int
+	PasswordBlocklistFile string // daftar password umum/bocor, satu per baris
+	BcryptCost            int    // dinaikkan = hash lama di-upgrade otomatis saat login
+
+	// Proteksi brute-force login
+	LoginMaxAttempts      int           // gagal berturut-turut per akun sebelum dikunci (0 = nonaktif)
+	LoginLockout          time.Duration // lockout pertama, berlipat dua setiap kegagalan berikutnya
+	LoginIPMaxAttempts    int           // gagal per IP dalam LoginIPWindow (0 = nonaktif)
+	LoginIPWindow         time.Duration
+	LoginAttemptRetention time.Duration // log login_attempts disimpan selama ini
+
+	// 2FA (TOTP)
+	MFAIssuer        string // nama akun di aplikasi authenticator
+	MFAEncryptionKey string // kunci enkripsi secret TOTP di database
+
+	// SSO OpenID Connect (OIDC_ISSUER kosong = nonaktif)
+	OIDCIssuer        string
+	OIDCClientID      string
+	OIDCClientSecret  string
+	OIDCRedirectURL   string // callback backend: .../api/v1/auth/oidc/callback
+	OIDCScopes        string // dipisah spasi
+	OIDCFrontendURL   string // token dikirim ke sini lewat fragment (#token=...)
+	OIDCUsernameClaim string // klaim yang dicocokkan ke username jika email tidak cocok, kosong = hanya email
+	OIDCAutoProvision bool   // buat user baru jika belum ada
+	OIDCDefaultRole   string // role user hasil auto provision
+	// true: provider sudah mewajibkan MFA, user dengan 2FA lokal tidak diminta kode TOTP lagi saat login SSO
+	OIDCTrustProviderMFA bool
+
+	// Login LDAP / Active Directory (LDAP_URL kosong = nonaktif), berlaku untuk user dengan auth_source "ldap"
+	LDAPURL                string // ldap://host:389 atau ldaps://host:636
+	LDAPStartTLS           bool
+	LDAPInsecureSkipVerify bool
+	LDAPTimeout            time.Duration
+	LDAPBindDN             string // akun service untuk mencari user, kosong = anonymous
+	LDAPBindPassword       string
+	LDAPBaseDN             string
+	LDAPUserFilter         string // {username} diganti username login, AD: (sAMAccountName={username})
+	LDAPUsernameAttribute  string
+	LDAPEmailAttribute     string
+	LDAPNameAttribute      string
+	LDAPGroupAttribute     string // memberOf (AD / OpenLDAP dengan overlay memberof), kosong = tidak dipakai
+	LDAPGroupBaseDN        string // kosong = LDAPBaseDN
+	LDAPGroupFilter        string // pencarian grup, contoh: (&(objectClass=groupOfNames)(member={dn}))
+	LDAPGroupRoles         string // grup ke role, urutan = prioritas: "dosen=Dosen Wali;cn=admin,ou=groups,dc=x=Admin"
+	LDAPDefaultRole        string // role jika tidak ada grup yang cocok, kosong = login ditolak
+	LDAPAutoProvision      bool   // buat user baru saat login pertama (just-in-time)
+	LDAPProfileIDAttribute string // NIP (Dosen Wali) / NIM (Mahasiswa) untuk profil user baru
+
+	// API key service account: scope jika tidak ditentukan saat membuat key (dipisah spasi)
+	APIKeyDefaultScopes string
+
+	// Reset password lewat email
+	PasswordResetURL string // Halaman frontend, token ditambahkan sebagai ?token=
+	PasswordResetTTL time.Duration
+
+	// Email (reset password)
+	Mailer       string // "log" atau "smtp"
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+	SMTPTimeout  time.Duration
+	MailFrom     string
+
+	// File Storage (Evidence Upload)
+	StorageDriver    string // "local" atau "s3"
+	StorageLocalPath string
+	StoragePublicURL string // Base URL publik untuk file yang tersimpan
+	S3Endpoint       string
+	S3AccessKey      string
+	S3SecretKey      string
+	S3Bucket         string
+	S3Region         string
+	S3UseSSL         bool
+
+	// Batas ukuran per file evidence (byte)
+	UploadMaxImageSize int64
+	UploadMaxPDFSize   int64
+	UploadMaxVideoSize int64
+
+	// Resumable upload (tus)
+	TusUploadDir string
+	TusUploadTTL time.Duration
+
+	// Garbage collector file storage yang tidak direferensikan
+	StorageGCGracePeriod time.Duration
+	StorageGCInterval    time.Duration // 0 = hanya manual (CLI / endpoint admin)
+
+	// Export bundle evidence (zip akreditasi)
+	ExportRetention time.Duration
+
+	// Download evidence (signed URL)
+	FileURLSecret string // Jika kosong diturunkan dari JWTSecret (HMAC dengan label "file-url")
+	SignedURLTTL  time.Duration
+
+	// Pemrosesan evidence di background (thumbnail/preview)
+	EvidenceWorkers   int
+	PDFPreviewCommand string // pdftoppm (poppler-utils), kosongkan untuk mematikan preview PDF
+
+	// Malware scanning
+	Scanner       string // "noop" atau "clamav"
+	ClamAVAddress string // unix:///var/run/clamav/clamd.ctl atau tcp://localhost:3310
+	ClamAVTimeout time.Duration
 }
 
 func LoadConfig() *Config {
@@ -19,12 +144,111 @@ func LoadConfig() *Config {
 	_ = godotenv.Load()
 
 	return &Config{
-		AppPort: getEnv("APP_PORT", ":3000"),
+		AppPort:    getEnv("APP_PORT", ":3000"),
+		AppBaseURL: getEnv("APP_BASE_URL", "http://localhost:3000"),
+		AppEnv:     getEnv("APP_ENV", "production"),
 		// Default DSN disesuaikan dengan setting lokal umumnya
 		PostgresDSN: getEnv("DB_DSN", "host=localhost user=postgres password=pedja12345 dbname=report_achievement_db port=5432 sslmode=disable"),
 		MongoURI:    getEnv("MONGO_URI", "mongodb://localhost:27017"),
 		MongoDBName: getEnv("MONGO_DB_NAME", "achievement_logs"),
-		JWTSecret:   getEnv("JWT_SECRET", "rahasia_negara"), // Default key jika env kosong
+		JWTSecret:   getEnv("JWT_SECRET", DefaultJWTSecret), // Default key jika env kosong (development saja)
+
+		JWTAlgorithm:   getEnv("JWT_ALGORITHM", "HS256"),
+		JWTKeyDir:      getEnv("JWT_KEY_DIR", "./keys"),
+		JWTKeyRotation: time.Duration(getEnvInt("JWT_KEY_ROTATION_DAYS", 0)) * 24 * time.Hour,
+		JWTKeyOverlap:  time.Duration(getEnvInt("JWT_KEY_OVERLAP_MINUTES", 60)) * time.Minute,
+
+		AccessTokenTTL:  time.Duration(getEnvInt("ACCESS_TOKEN_TTL_MINUTES", 15)) * time.Minute,
+		RefreshTokenTTL: time.Duration(getEnvInt("REFRESH_TOKEN_TTL_DAYS", 30)) * 24 * time.Hour,
+
+		PasswordMinLength:     getEnvInt("PASSWORD_MIN_LENGTH", 8),
+		PasswordBlocklistFile: getEnv("PASSWORD_BLOCKLIST_FILE", "./config/common-passwords.txt"),
+		BcryptCost:            getEnvInt("BCRYPT_COST", 12),
+
+		LoginMaxAttempts:      getEnvInt("LOGIN_MAX_ATTEMPTS", 5),
+		LoginLockout:          time.Duration(getEnvInt("LOGIN_LOCKOUT_MINUTES", 15)) * time.Minute,
+		LoginIPMaxAttempts:    getEnvInt("LOGIN_IP_MAX_ATTEMPTS", 20),
+		LoginIPWindow:         time.Duration(getEnvInt("LOGIN_IP_WINDOW_MINUTES", 15)) * time.Minute,
+		LoginAttemptRetention: time.Duration(getEnvInt("LOGIN_ATTEMPT_RETENTION_DAYS", 90)) * 24 * time.Hour,
+
+		MFAIssuer:        getEnv("MFA_ISSUER", "Prestasi Mahasiswa"),
+		MFAEncryptionKey: getEnv("MFA_ENCRYPTION_KEY", DefaultMFAEncryptionKey),
+
+		OIDCIssuer:        getEnv("OIDC_ISSUER", ""),
+		OIDCClientID:      getEnv("OIDC_CLIENT_ID", ""),
+		OIDCClientSecret:  getEnv("OIDC_CLIENT_SECRET", ""),
+		OIDCRedirectURL:   getEnv("OIDC_REDIRECT_URL", "http://localhost:3000/api/v1/auth/oidc/callback"),
+		OIDCScopes:        getEnv("OIDC_SCOPES", "openid email profile"),
+		OIDCFrontendURL:   getEnv("OIDC_FRONTEND_URL", ""),
+		OIDCUsernameClaim: getEnv("OIDC_USERNAME_CLAIM", "preferred_username"),
+		OIDCAutoProvision: getEnvBool("OIDC_AUTO_PROVISION", false),
+		OIDCDefaultRole:   getEnv("OIDC_DEFAULT_ROLE", "Mahasiswa"),
+
+		OIDCTrustProviderMFA: getEnvBool("OIDC_TRUST_PROVIDER_MFA", false),
+
+		LDAPURL:                getEnv("LDAP_URL", ""),
+		LDAPStartTLS:           getEnvBool("LDAP_START_TLS", false),
+		LDAPInsecureSkipVerify: getEnvBool("LDAP_INSECURE_SKIP_VERIFY", false),
+		LDAPTimeout:            time.Duration(getEnvInt("LDAP_TIMEOUT_SECONDS", 10)) * time.Second,
+		LDAPBindDN:             getEnv("LDAP_BIND_DN", ""),
+		LDAPBindPassword:       getEnv("LDAP_BIND_PASSWORD", ""),
+		LDAPBaseDN:             getEnv("LDAP_BASE_DN", ""),
+		LDAPUserFilter:         getEnv("LDAP_USER_FILTER", "(uid={username})"),
+		LDAPUsernameAttribute:  getEnv("LDAP_USERNAME_ATTRIBUTE", "uid"),
+		LDAPEmailAttribute:     getEnv("LDAP_EMAIL_ATTRIBUTE", "mail"),
+		LDAPNameAttribute:      getEnv("LDAP_NAME_ATTRIBUTE", "cn"),
+		LDAPGroupAttribute:     getEnv("LDAP_GROUP_ATTRIBUTE", "memberOf"),
+		LDAPGroupBaseDN:        getEnv("LDAP_GROUP_BASE_DN", ""),
+		LDAPGroupFilter:        getEnv("LDAP_GROUP_FILTER", ""),
+		LDAPGroupRoles:         getEnv("LDAP_GROUP_ROLES", ""),
+		LDAPDefaultRole:        getEnv("LDAP_DEFAULT_ROLE", ""),
+		LDAPAutoProvision:      getEnvBool("LDAP_AUTO_PROVISION", false),
+		LDAPProfileIDAttribute: getEnv("LDAP_PROFILE_ID_ATTRIBUTE", "employeeNumber"),
+
+		APIKeyDefaultScopes: getEnv("API_KEY_DEFAULT_SCOPES", "achievement:read_all report:read"),
+
+		PasswordResetURL: getEnv("PASSWORD_RESET_URL", "http://localhost:3000/reset-password"),
+		PasswordResetTTL: time.Duration(getEnvInt("PASSWORD_RESET_TTL_MINUTES", 30)) * time.Minute,
+
+		Mailer:       getEnv("MAILER", "log"),
+		SMTPHost:     getEnv("SMTP_HOST", "localhost"),
+		SMTPPort:     getEnvInt("SMTP_PORT", 1025),
+		SMTPUsername: getEnv("SMTP_USERNAME", ""),
+		SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+		SMTPTimeout:  time.Duration(getEnvInt("SMTP_TIMEOUT_SECONDS", 30)) * time.Second,
+		MailFrom:     getEnv("MAIL_FROM", "Sistem Prestasi <no-reply@localhost>"),
+
+		StorageDriver:    getEnv("STORAGE_DRIVER", "local"),
+		StorageLocalPath: getEnv("STORAGE_LOCAL_PATH", "./uploads"),
+		StoragePublicURL: getEnv("STORAGE_PUBLIC_URL", "http://localhost:3000/uploads"),
+		S3Endpoint:       getEnv("S3_ENDPOINT", "localhost:9000"),
+		S3AccessKey:      getEnv("S3_ACCESS_KEY", "minioadmin"),
+		S3SecretKey:      getEnv("S3_SECRET_KEY", "minioadmin"),
+		S3Bucket:         getEnv("S3_BUCKET", "evidence"),
+		S3Region:         getEnv("S3_REGION", "us-east-1"),
+		S3UseSSL:         getEnvBool("S3_USE_SSL", false),
+
+		UploadMaxImageSize: int64(getEnvInt("UPLOAD_MAX_IMAGE_SIZE_MB", 5)) * 1024 * 1024,
+		UploadMaxPDFSize:   int64(getEnvInt("UPLOAD_MAX_PDF_SIZE_MB", 10)) * 1024 * 1024,
+		UploadMaxVideoSize: int64(getEnvInt("UPLOAD_MAX_VIDEO_SIZE_MB", 200)) * 1024 * 1024,
+
+		TusUploadDir: getEnv("TUS_UPLOAD_DIR", "./tmp/tus"),
+		TusUploadTTL: time.Duration(getEnvInt("TUS_UPLOAD_TTL_HOURS", 24)) * time.Hour,
+
+		StorageGCGracePeriod: time.Duration(getEnvInt("STORAGE_GC_GRACE_HOURS", 72)) * time.Hour,
+		StorageGCInterval:    time.Duration(getEnvInt("STORAGE_GC_INTERVAL_HOURS", 0)) * time.Hour,
+
+		ExportRetention: time.Duration(getEnvInt("EXPORT_RETENTION_DAYS", 7)) * 24 * time.Hour,
+
+		FileURLSecret: getEnv("FILE_URL_SECRET", ""),
+		SignedURLTTL:  time.Duration(getEnvInt("SIGNED_URL_TTL_MINUTES", 15)) * time.Minute,
+
+		EvidenceWorkers:   getEnvInt("EVIDENCE_WORKERS", 2),
+		PDFPreviewCommand: getEnv("PDF_PREVIEW_COMMAND", "pdftoppm"),
+
+		Scanner:       getEnv("SCANNER", "noop"),
+		ClamAVAddress: getEnv("CLAMAV_ADDRESS", "tcp://localhost:3310"),
+		ClamAVTimeout: time.Duration(getEnvInt("CLAMAV_TIMEOUT_SECONDS", 60)) * time.Second,
 	}
 }
 
@@ -34,3 +258,21 @@ func getEnv(key, fallback string) string {
 	}
 	return fallback
 }
+
+func getEnvBool(key string, fallback bool) bool {
+	if value, exists := os.LookupEnv(key); exists {
+		if parsed, err := strconv.ParseBool(value); err == nil {
+			return parsed
+		}
+	}
+	return fallback
+}
+
+func getEnvInt(key string, fallback int) int {
+	if value, exists := os.LookupEnv(key); exists {
+		if parsed, err := strconv.Atoi(value); err == nil {
+			return parsed
+		}
+	}
+	return fallback
+}
@@ -0,0 +1,82 @@
+package postgres
+
+import (
+	"log"
+	"reportachievement/app/model/postgre"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// DefaultPermission: permission bawaan beserta role yang mendapatkannya saat pertama dibuat
+type DefaultPermission struct {
+	Name        string
+	Description string
+	Roles       []string
+}
+
+var defaultRoles = []postgre.Role{
+	{Name: "Admin", Description: "Administrator"},
+	{Name: "Dosen Wali", Description: "Verifikator"},
+	{Name: "Mahasiswa", Description: "Pelapor"},
+}
+
+// DefaultPermissions: format nama "resource:action"
+var DefaultPermissions = []DefaultPermission{
+	{"achievement:create", "Membuat, mengubah, submit & menghapus prestasi sendiri beserta evidence", []string{"Mahasiswa"}},
+	{"achievement:read_own", "Melihat prestasi & SKPI milik sendiri", []string{"Mahasiswa"}},
+	{"achievement:read_advisee", "Melihat prestasi & SKPI mahasiswa bimbingan", []string{"Dosen Wali"}},
+	{"achievement:read_all", "Melihat seluruh prestasi & SKPI", []string{"Admin"}},
+	{"achievement:verify", "Memverifikasi / menolak prestasi mahasiswa bimbingan", []string{"Dosen Wali"}},
+	{"report:read", "Melihat statistik dashboard", []string{"Admin", "Dosen Wali", "Mahasiswa"}},
+	{"report:audit", "Melihat laporan evidence duplikat", []string{"Admin"}},
+	{"skpi:export_cohort", "Export SKPI satu angkatan", []string{"Admin"}},
+	{"export:manage", "Membuat & mengunduh export zip akreditasi", []string{"Admin"}},
+	{"user:manage", "Mengelola akun user & mencabut session", []string{"Admin"}},
+	{"role:manage", "Mengelola role & permission serta mengganti role user", []string{"Admin"}},
+	{"storage:manage", "Menjalankan garbage collector storage", []string{"Admin"}},
+	{"auth:manage_keys", "Melihat & merotasi kunci JWT", []string{"Admin"}},
+	{"service_account:manage", "Mengelola service account & API key untuk integrasi", []string{"Admin"}},
+	// Tanpa role bawaan agar akun lama tidak tiba-tiba terkunci, admin memasangnya lewat endpoint role
+	{"auth:require_2fa", "Wajib mengaktifkan 2FA (TOTP) sebelum bisa memakai API", nil},
+}
+
+// SeedPermissions: dijalankan setiap start, hanya menambah yang belum ada.
+// Role default didapat permission hanya saat permission baru dibuat,
+// sehingga perubahan oleh admin tidak tertimpa.
+func SeedPermissions(db *gorm.DB) {
+	roles := map[string]postgre.Role{}
+	for _, r := range defaultRoles {
+		role := r
+		if err := db.Where("name = ?", role.Name).Attrs(postgre.Role{Description: role.Description}).FirstOrCreate(&role).Error; err != nil {
+			log.Fatal("❌ Gagal seed role:", err)
+		}
+		roles[role.Name] = role
+	}
+
+	for _, p := range DefaultPermissions {
+		var existing int64
+		db.Model(&postgre.Permission{}).Where("name = ?", p.Name).Count(&existing)
+		if existing > 0 {
+			continue
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			resource, action, _ := strings.Cut(p.Name, ":")
+			perm := postgre.Permission{Name: p.Name, Resource: resource, Action: action, Description: p.Description}
+			if err := tx.Create(&perm).Error; err != nil {
+				return err
+			}
+			for _, roleName := range p.Roles {
+				if err := tx.Create(&postgre.RolePermission{RoleID: roles[roleName].ID, PermissionID: perm.ID}).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			log.Fatal("❌ Gagal seed permission "+p.Name+":", err)
+		}
+		log.Println("✅ Permission ditambahkan:", p.Name)
+	}
+}
@@ -31,19 +31,20 @@ func SeedDatabase(db *gorm.DB) {
 		log.Fatal("❌ Gagal seed roles:", err)
 	}
 
-	// Password Hash "123456"
+	// Password Hash "123456" (ada di blocklist, semua akun seed wajib ganti password saat login pertama)
 	hashedPwd, _ := bcrypt.GenerateFromPassword([]byte("123456"), bcrypt.DefaultCost)
 	strPwd := string(hashedPwd)
 
 	// 3. CREATE SUPERADMIN
 	admin := postgre.User{
-		ID:           uuid.New(),
-		Username:     "superadmin",
-		Email:        "admin@unair.ac.id",
-		PasswordHash: strPwd,
-		FullName:     "Super Administrator",
-		RoleID:       roleAdmin.ID,
-		IsActive:     true,
+		ID:                 uuid.New(),
+		Username:           "superadmin",
+		Email:              "admin@unair.ac.id",
+		PasswordHash:       strPwd,
+		FullName:           "Super Administrator",
+		RoleID:             roleAdmin.ID,
+		IsActive:           true,
+		MustChangePassword: true,
 	}
 	if err := db.Create(&admin).Error; err != nil {
 		log.Fatal("Gagal buat admin:", err)
@@ -56,13 +57,14 @@ func SeedDatabase(db *gorm.DB) {
 		// A. User Dosen
 		userID := uuid.New()
 		user := postgre.User{
-			ID:           userID,
-			Username:     fmt.Sprintf("dosen%d", i),
-			Email:        fmt.Sprintf("dosen%d@unair.ac.id", i),
-			PasswordHash: strPwd,
-			FullName:     fmt.Sprintf("Dr. Dosen %d", i),
-			RoleID:       roleDosen.ID,
-			IsActive:     true,
+			ID:                 userID,
+			Username:           fmt.Sprintf("dosen%d", i),
+			Email:              fmt.Sprintf("dosen%d@unair.ac.id", i),
+			PasswordHash:       strPwd,
+			FullName:           fmt.Sprintf("Dr. Dosen %d", i),
+			RoleID:             roleDosen.ID,
+			IsActive:           true,
+			MustChangePassword: true,
 		}
 		db.Create(&user)
 
@@ -85,13 +87,14 @@ func SeedDatabase(db *gorm.DB) {
 		// A. User Mahasiswa
 		userID := uuid.New()
 		user := postgre.User{
-			ID:           userID,
-			Username:     fmt.Sprintf("mhs%d", i),
-			Email:        fmt.Sprintf("mhs%d@unair.ac.id", i),
-			PasswordHash: strPwd,
-			FullName:     fmt.Sprintf("Mahasiswa %d", i),
-			RoleID:       roleMhs.ID,
-			IsActive:     true,
+			ID:                 userID,
+			Username:           fmt.Sprintf("mhs%d", i),
+			Email:              fmt.Sprintf("mhs%d@unair.ac.id", i),
+			PasswordHash:       strPwd,
+			FullName:           fmt.Sprintf("Mahasiswa %d", i),
+			RoleID:             roleMhs.ID,
+			IsActive:           true,
+			MustChangePassword: true,
 		}
 		db.Create(&user)
 
@@ -0,0 +1,381 @@
+package jwtkeys
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"reportachievement/config"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+)
+
+var (
+	ErrUnsupportedAlgorithm = errors.New("unsupported JWT algorithm")
+	ErrUnknownKey           = errors.New("unknown signing key")
+	ErrNoSigningKey         = errors.New("no signing key available")
+	ErrDefaultSecret        = errors.New("JWT_SECRET is the public default, set JWT_SECRET or use RS256/EdDSA or key rotation")
+)
+
+// Key: satu kunci penandatangan token, diidentifikasi lewat header "kid"
+type Key struct {
+	ID        string
+	Algorithm string
+	CreatedAt time.Time
+
+	signKey   interface{} // []byte (HS256), *rsa.PrivateKey, ed25519.PrivateKey
+	verifyKey interface{} // []byte (HS256), *rsa.PublicKey, ed25519.PublicKey
+}
+
+// Options: konfigurasi Manager
+type Options struct {
+	Algorithm string // HS256 | RS256 | EdDSA
+	Issuer    string // claim "iss", diverifikasi saat parse (kosong = tidak dicek)
+
+	// HS256 tanpa rotasi memakai Secret statis. Selain itu kunci dibuat & disimpan di Dir
+	// (harus dishare jika lebih dari satu instance).
+	Secret string
+	Dir    string
+	// AllowDefaultSecret: config.DefaultJWTSecret hanya diterima saat development
+	AllowDefaultSecret bool
+
+	// Rotation: umur kunci sebelum diganti (0 = tidak dirotasi).
+	// Overlap: kunci baru dipublikasikan di JWKS selama Overlap sebelum dipakai menandatangani,
+	// dan kunci lama tetap bisa memverifikasi selama Overlap setelah diganti.
+	Rotation time.Duration
+	Overlap  time.Duration
+}
+
+// Manager: tanda tangan & verifikasi JWT dengan kunci ber-kid, rotasi terjadwal dan JWKS
+type Manager struct {
+	opts Options
+
+	mu         sync.RWMutex
+	keys       []*Key // urut dari yang terbaru
+	lastReload time.Time
+}
+
+// New: bangun Manager dari config, aplikasi berhenti jika kunci tidak bisa dimuat
+func New(cfg *config.Config) *Manager {
+	// Kunci lama harus bisa memverifikasi setidaknya sampai access token terakhirnya expired
+	overlap := cfg.JWTKeyOverlap
+	if overlap < cfg.AccessTokenTTL {
+		overlap = cfg.AccessTokenTTL
+	}
+
+	m, err := NewManager(Options{
+		Algorithm: cfg.JWTAlgorithm,
+		Issuer:    cfg.AppBaseURL,
+		Secret:    cfg.JWTSecret,
+		Dir:       cfg.JWTKeyDir,
+		Rotation:  cfg.JWTKeyRotation,
+		Overlap:   overlap,
+
+		AllowDefaultSecret: cfg.AppEnv == "development",
+	})
+	if err != nil {
+		log.Fatal("❌ Gagal inisialisasi kunci JWT:", err)
+	}
+	if !m.managed() && cfg.JWTSecret == config.DefaultJWTSecret {
+		log.Println("⚠️ JWT_SECRET masih default dari repo, jangan dipakai selain development")
+	}
+	log.Printf("✅ JWT: %s, kid aktif %s", m.opts.Algorithm, m.signingKey().ID)
+	return m
+}
+
+func NewManager(opts Options) (*Manager, error) {
+	switch opts.Algorithm {
+	case AlgHS256, AlgRS256, AlgEdDSA:
+	case "":
+		opts.Algorithm = AlgHS256
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, opts.Algorithm)
+	}
+
+	if opts.Rotation > 0 && opts.Rotation <= opts.Overlap {
+		return nil, errors.New("JWT key rotation interval must be longer than the overlap")
+	}
+
+	m := &Manager{opts: opts}
+
+	// HS256 tanpa rotasi: perilaku lama, satu secret dari JWT_SECRET
+	if !m.managed() {
+		if opts.Secret == "" {
+			return nil, errors.New("JWT secret is empty")
+		}
+		if opts.Secret == config.DefaultJWTSecret && !opts.AllowDefaultSecret {
+			return nil, ErrDefaultSecret
+		}
+		sum := sha256.Sum256([]byte(opts.Secret))
+		m.keys = []*Key{{
+			ID:        "hs-" + hex.EncodeToString(sum[:6]),
+			Algorithm: AlgHS256,
+			signKey:   []byte(opts.Secret),
+			verifyKey: []byte(opts.Secret),
+		}}
+		return m, nil
+	}
+
+	if opts.Dir == "" {
+		return nil, errors.New("JWT key directory is required for " + opts.Algorithm + " or key rotation")
+	}
+	if err := m.Rotate(false); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// managed: kunci dibuat & dirotasi otomatis (disimpan di Dir)
+func (m *Manager) managed() bool {
+	return m.opts.Algorithm != AlgHS256 || m.opts.Rotation > 0
+}
+
+func (m *Manager) Algorithm() string {
+	return m.opts.Algorithm
+}
+
+// Start: cek rotasi berkala & muat ulang kunci yang dibuat instance lain
+func (m *Manager) Start(ctx context.Context) {
+	if !m.managed() {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := m.Rotate(false); err != nil {
+					log.Println("⚠️ Rotasi kunci JWT gagal:", err)
+				}
+			}
+		}
+	}()
+}
+
+// Rotate: muat kunci dari Dir, buat kunci baru jika belum ada / sudah waktunya
+// (force = buat sekarang juga), lalu hapus kunci yang sudah lewat masa overlap.
+func (m *Manager) Rotate(force bool) error {
+	if !m.managed() {
+		return errors.New("key rotation requires JWT_KEY_DIR mode")
+	}
+
+	keys, err := loadKeys(m.opts.Dir, m.opts.Algorithm)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	// Kunci baru dibuat Overlap sebelum kunci aktif habis umurnya agar sempat dipublikasikan
+	due := len(keys) == 0 || force
+	if !due && m.opts.Rotation > 0 {
+		due = now.Sub(keys[0].CreatedAt) >= m.opts.Rotation-m.opts.Overlap
+	}
+	if due {
+		key, err := generateKey(m.opts.Algorithm, now)
+		if err != nil {
+			return err
+		}
+		if err := saveKey(m.opts.Dir, key); err != nil {
+			return err
+		}
+		keys = append([]*Key{key}, keys...)
+		log.Println("🔑 Kunci JWT baru dibuat, kid", key.ID)
+	}
+
+	// Kunci ke-i diganti saat kunci ke-(i-1) mulai menandatangani
+	kept := keys[:1]
+	for i := 1; i < len(keys); i++ {
+		retiredAt := keys[i-1].CreatedAt.Add(m.opts.Overlap)
+		if now.Sub(retiredAt) < m.opts.Overlap {
+			kept = append(kept, keys[i])
+			continue
+		}
+		if err := removeKey(m.opts.Dir, keys[i].ID); err != nil {
+			log.Println("⚠️ Gagal menghapus kunci JWT lama:", keys[i].ID, err)
+		}
+	}
+
+	m.mu.Lock()
+	m.keys = kept
+	m.lastReload = now
+	m.mu.Unlock()
+	return nil
+}
+
+// signingKey: kunci terbaru yang sudah dipublikasikan selama Overlap.
+// Saat start pertama (hanya ada kunci baru) kunci terbaru langsung dipakai.
+func (m *Manager) signingKey() *Key {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if len(m.keys) == 0 {
+		return nil
+	}
+	now := time.Now()
+	for i, key := range m.keys {
+		if i == len(m.keys)-1 || now.Sub(key.CreatedAt) >= m.opts.Overlap {
+			return key
+		}
+	}
+	return m.keys[0]
+}
+
+func (m *Manager) findKey(kid string) *Key {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, key := range m.keys {
+		if key.ID == kid {
+			return key
+		}
+	}
+	return nil
+}
+
+// Sign: tandatangani claims dengan kunci aktif (header kid diisi), claim iss ditambahkan
+func (m *Manager) Sign(claims jwt.MapClaims) (string, error) {
+	key := m.signingKey()
+	if key == nil {
+		return "", ErrNoSigningKey
+	}
+	if m.opts.Issuer != "" {
+		claims["iss"] = m.opts.Issuer
+	}
+	token := jwt.NewWithClaims(signingMethod(key.Algorithm), claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.signKey)
+}
+
+// Parse: verifikasi token. Algoritma harus sama persis dengan algoritma kunci (kid),
+// token tanpa exp atau dengan iss berbeda ditolak.
+func (m *Manager) Parse(tokenString string) (jwt.MapClaims, error) {
+	parserOpts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{m.opts.Algorithm}),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	}
+	if m.opts.Issuer != "" {
+		parserOpts = append(parserOpts, jwt.WithIssuer(m.opts.Issuer))
+	}
+
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		key := m.findKey(kid)
+		if key == nil && kid != "" && m.managed() {
+			// Kunci bisa saja baru dibuat instance lain, muat ulang (dibatasi sekali per 10 detik)
+			m.reloadThrottled()
+			key = m.findKey(kid)
+		}
+		if key == nil {
+			return nil, ErrUnknownKey
+		}
+		if t.Method.Alg() != key.Algorithm {
+			return nil, ErrUnsupportedAlgorithm
+		}
+		return key.verifyKey, nil
+	}, parserOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+func (m *Manager) reloadThrottled() {
+	m.mu.RLock()
+	recent := time.Since(m.lastReload) < 10*time.Second
+	m.mu.RUnlock()
+	if recent {
+		return
+	}
+	if err := m.Rotate(false); err != nil {
+		log.Println("⚠️ Gagal memuat ulang kunci JWT:", err)
+	}
+}
+
+// Keys: daftar kunci yang sedang dipakai (untuk status admin), terbaru dulu
+func (m *Manager) Keys() []Key {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	out := make([]Key, 0, len(m.keys))
+	for _, key := range m.keys {
+		out = append(out, Key{ID: key.ID, Algorithm: key.Algorithm, CreatedAt: key.CreatedAt})
+	}
+	return out
+}
+
+func signingMethod(alg string) jwt.SigningMethod {
+	switch alg {
+	case AlgRS256:
+		return jwt.SigningMethodRS256
+	case AlgEdDSA:
+		return jwt.SigningMethodEdDSA
+	default:
+		return jwt.SigningMethodHS256
+	}
+}
+
+func generateKey(alg string, now time.Time) (*Key, error) {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	key := &Key{ID: hex.EncodeToString(id), Algorithm: alg, CreatedAt: now.UTC().Truncate(time.Second)}
+
+	switch alg {
+	case AlgRS256:
+		priv, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			return nil, err
+		}
+		key.signKey, key.verifyKey = priv, &priv.PublicKey
+	case AlgEdDSA:
+		pub, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		key.signKey, key.verifyKey = priv, pub
+	default:
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, err
+		}
+		key.signKey, key.verifyKey = secret, secret
+	}
+	return key, nil
+}
+
+// publicKey: kunci publik untuk JWKS, nil untuk HS256 (secret tidak pernah dipublikasikan)
+func (k *Key) publicKey() crypto.PublicKey {
+	switch pub := k.verifyKey.(type) {
+	case *rsa.PublicKey, ed25519.PublicKey:
+		return pub
+	}
+	return nil
+}
+
+func sortKeys(keys []*Key) {
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].CreatedAt.Equal(keys[j].CreatedAt) {
+			return keys[i].ID > keys[j].ID
+		}
+		return keys[i].CreatedAt.After(keys[j].CreatedAt)
+	})
+}
@@ -0,0 +1,156 @@
+package jwtkeys
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"reportachievement/config"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func testClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"user_id": "u-1",
+		"iat":     time.Now().Unix(),
+		"exp":     time.Now().Add(time.Minute).Unix(),
+	}
+}
+
+func TestManager_StaticHS256(t *testing.T) {
+	m, err := NewManager(Options{Algorithm: AlgHS256, Secret: "rahasia", Issuer: "http://localhost:3000"})
+	assert.NoError(t, err)
+
+	token, err := m.Sign(testClaims())
+	assert.NoError(t, err)
+
+	claims, err := m.Parse(token)
+	assert.NoError(t, err)
+	assert.Equal(t, "u-1", claims["user_id"])
+	assert.Equal(t, "http://localhost:3000", claims["iss"])
+
+	// Secret tidak pernah dipublikasikan
+	assert.Empty(t, m.JWKS().Keys)
+
+	// Token tanpa kid atau tanpa exp ditolak
+	raw := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"user_id": "u-1", "exp": time.Now().Add(time.Minute).Unix(), "iss": "http://localhost:3000"})
+	signed, _ := raw.SignedString([]byte("rahasia"))
+	_, err = m.Parse(signed)
+	assert.Error(t, err)
+
+	noExp := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"user_id": "u-1", "iss": "http://localhost:3000"})
+	noExp.Header["kid"] = m.Keys()[0].ID
+	signed, _ = noExp.SignedString([]byte("rahasia"))
+	_, err = m.Parse(signed)
+	assert.Error(t, err)
+}
+
+func TestManager_DefaultSecret(t *testing.T) {
+	_, err := NewManager(Options{Algorithm: AlgHS256, Secret: config.DefaultJWTSecret})
+	assert.ErrorIs(t, err, ErrDefaultSecret)
+
+	// Development boleh memakai default
+	_, err = NewManager(Options{Algorithm: AlgHS256, Secret: config.DefaultJWTSecret, AllowDefaultSecret: true})
+	assert.NoError(t, err)
+
+	// Kunci yang dikelola tidak memakai JWT_SECRET sama sekali
+	_, err = NewManager(Options{Algorithm: AlgEdDSA, Secret: config.DefaultJWTSecret, Dir: t.TempDir()})
+	assert.NoError(t, err)
+}
+
+func TestManager_AsymmetricAndJWKS(t *testing.T) {
+	for _, alg := range []string{AlgRS256, AlgEdDSA} {
+		t.Run(alg, func(t *testing.T) {
+			dir := t.TempDir()
+			m, err := NewManager(Options{Algorithm: alg, Dir: dir, Overlap: time.Hour})
+			assert.NoError(t, err)
+
+			token, err := m.Sign(testClaims())
+			assert.NoError(t, err)
+			_, err = m.Parse(token)
+			assert.NoError(t, err)
+
+			jwks := m.JWKS()
+			if assert.Len(t, jwks.Keys, 1) {
+				assert.Equal(t, m.Keys()[0].ID, jwks.Keys[0].KeyID)
+				assert.Equal(t, alg, jwks.Keys[0].Algorithm)
+			}
+
+			// Instance lain dengan direktori yang sama memakai kunci yang sama
+			other, err := NewManager(Options{Algorithm: alg, Dir: dir, Overlap: time.Hour})
+			assert.NoError(t, err)
+			_, err = other.Parse(token)
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestManager_RejectsAlgorithmConfusion(t *testing.T) {
+	dir := t.TempDir()
+	m, err := NewManager(Options{Algorithm: AlgRS256, Dir: dir})
+	assert.NoError(t, err)
+	kid := m.Keys()[0].ID
+
+	// HS256 dengan kid kunci RSA (serangan "public key sebagai HMAC secret")
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, testClaims())
+	forged.Header["kid"] = kid
+	signed, _ := forged.SignedString([]byte("apa saja"))
+	_, err = m.Parse(signed)
+	assert.Error(t, err)
+
+	// alg "none"
+	none := jwt.NewWithClaims(jwt.SigningMethodNone, testClaims())
+	none.Header["kid"] = kid
+	signed, _ = none.SignedString(jwt.UnsafeAllowNoneSignatureType)
+	_, err = m.Parse(signed)
+	assert.Error(t, err)
+}
+
+func TestManager_RotationOverlap(t *testing.T) {
+	dir := t.TempDir()
+	overlap := time.Hour
+	m, err := NewManager(Options{Algorithm: AlgEdDSA, Dir: dir, Rotation: 24 * time.Hour, Overlap: overlap})
+	assert.NoError(t, err)
+	oldKey := m.Keys()[0]
+	oldToken, _ := m.Sign(testClaims())
+
+	// Kunci berikutnya dibuat & dipublikasikan, tetapi kunci lama masih menandatangani
+	assert.NoError(t, m.Rotate(true))
+	assert.Len(t, m.Keys(), 2)
+	assert.Len(t, m.JWKS().Keys, 2)
+	assert.Equal(t, oldKey.ID, m.signingKey().ID)
+
+	// Setelah masa overlap kunci baru aktif, token lama masih valid
+	ageKey(t, dir, m.Keys()[0].ID, overlap)
+	ageKey(t, dir, oldKey.ID, 2*overlap)
+	assert.NoError(t, m.Rotate(false))
+	assert.NotEqual(t, oldKey.ID, m.signingKey().ID)
+	_, err = m.Parse(oldToken)
+	assert.NoError(t, err)
+
+	// Overlap kedua lewat: kunci lama dihapus
+	ageKey(t, dir, m.Keys()[0].ID, 2*overlap)
+	ageKey(t, dir, oldKey.ID, 3*overlap)
+	assert.NoError(t, m.Rotate(false))
+	assert.Len(t, m.Keys(), 1)
+	_, err = os.Stat(filepath.Join(dir, oldKey.ID+".pem"))
+	assert.True(t, os.IsNotExist(err))
+	_, err = m.Parse(oldToken)
+	assert.Error(t, err)
+}
+
+// ageKey: mundurkan waktu dibuat kunci di file (simulasi waktu berjalan)
+func ageKey(t *testing.T, dir, kid string, age time.Duration) {
+	t.Helper()
+	key, err := readKey(filepath.Join(dir, kid+".pem"), kid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key.CreatedAt = time.Now().Add(-age).UTC().Truncate(time.Second)
+	if err := saveKey(dir, key); err != nil {
+		t.Fatal(err)
+	}
+}
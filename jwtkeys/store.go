@@ -0,0 +1,143 @@
+package jwtkeys
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Kunci disimpan sebagai <Dir>/<kid>.pem, algoritma & waktu dibuat di header PEM.
+// HS256 memakai blok "HMAC SECRET", RS256/EdDSA PKCS#8 "PRIVATE KEY".
+const (
+	pemTypePrivate = "PRIVATE KEY"
+	pemTypeHMAC    = "HMAC SECRET"
+	headerAlg      = "Algorithm"
+	headerCreated  = "Created"
+)
+
+// loadKeys: baca semua kunci untuk algoritma alg, file rusak dilewati (dengan log)
+func loadKeys(dir, alg string) ([]*Key, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []*Key
+	for _, entry := range entries {
+		kid, ok := strings.CutSuffix(entry.Name(), ".pem")
+		if !ok || entry.IsDir() {
+			continue
+		}
+		key, err := readKey(filepath.Join(dir, entry.Name()), kid)
+		if err != nil {
+			log.Println("⚠️ Kunci JWT dilewati:", entry.Name(), err)
+			continue
+		}
+		if key.Algorithm == alg {
+			keys = append(keys, key)
+		}
+	}
+	sortKeys(keys)
+	return keys, nil
+}
+
+func readKey(path, kid string) (*Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("invalid PEM")
+	}
+	created, err := time.Parse(time.RFC3339, block.Headers[headerCreated])
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s header", headerCreated)
+	}
+	key := &Key{ID: kid, Algorithm: block.Headers[headerAlg], CreatedAt: created}
+
+	switch block.Type {
+	case pemTypeHMAC:
+		if key.Algorithm != AlgHS256 || len(block.Bytes) < 32 {
+			return nil, fmt.Errorf("invalid HMAC secret")
+		}
+		key.signKey, key.verifyKey = block.Bytes, block.Bytes
+	case pemTypePrivate:
+		parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		switch priv := parsed.(type) {
+		case *rsa.PrivateKey:
+			if key.Algorithm != AlgRS256 {
+				return nil, fmt.Errorf("RSA key with algorithm %q", key.Algorithm)
+			}
+			key.signKey, key.verifyKey = priv, &priv.PublicKey
+		case ed25519.PrivateKey:
+			if key.Algorithm != AlgEdDSA {
+				return nil, fmt.Errorf("Ed25519 key with algorithm %q", key.Algorithm)
+			}
+			key.signKey, key.verifyKey = priv, priv.Public().(ed25519.PublicKey)
+		default:
+			return nil, fmt.Errorf("unsupported private key type %T", parsed)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported PEM type %q", block.Type)
+	}
+	return key, nil
+}
+
+// saveKey: tulis ke file sementara lalu rename agar instance lain tidak membaca file setengah jadi
+func saveKey(dir string, key *Key) error {
+	block := &pem.Block{
+		Headers: map[string]string{
+			headerAlg:     key.Algorithm,
+			headerCreated: key.CreatedAt.Format(time.RFC3339),
+		},
+	}
+	if secret, ok := key.signKey.([]byte); ok {
+		block.Type, block.Bytes = pemTypeHMAC, secret
+	} else {
+		der, err := x509.MarshalPKCS8PrivateKey(key.signKey)
+		if err != nil {
+			return err
+		}
+		block.Type, block.Bytes = pemTypePrivate, der
+	}
+
+	tmp, err := os.CreateTemp(dir, ".key-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(0o600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := pem.Encode(tmp, block); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(dir, key.ID+".pem"))
+}
+
+func removeKey(dir, kid string) error {
+	err := os.Remove(filepath.Join(dir, kid+".pem"))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
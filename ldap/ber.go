@@ -0,0 +1,162 @@
+package ldap
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Subset BER (X.690) yang dibutuhkan LDAPv3: tag satu byte, panjang definite.
+
+const (
+	classApplication = 0x40
+	classContext     = 0x80
+	constructed      = 0x20
+
+	tagBoolean     = 0x01
+	tagInteger     = 0x02
+	tagOctetString = 0x04
+	tagEnumerated  = 0x0a
+	tagSequence    = 0x30
+	tagSet         = 0x31
+)
+
+// Batas ukuran satu pesan dari server, mencegah alokasi besar dari panjang palsu
+const maxMessageSize = 4 << 20
+
+var errMalformed = errors.New("ldap: malformed BER data")
+
+// element: satu TLV hasil decode
+type element struct {
+	tag     byte
+	content []byte
+}
+
+func encode(tag byte, content ...[]byte) []byte {
+	n := 0
+	for _, c := range content {
+		n += len(c)
+	}
+	out := append([]byte{tag}, encodeLength(n)...)
+	for _, c := range content {
+		out = append(out, c...)
+	}
+	return out
+}
+
+func encodeLength(n int) []byte {
+	if n < 0x80 {
+		return []byte{byte(n)}
+	}
+	var b []byte
+	for v := n; v > 0; v >>= 8 {
+		b = append([]byte{byte(v)}, b...)
+	}
+	return append([]byte{0x80 | byte(len(b))}, b...)
+}
+
+func encodeInt(tag byte, v int) []byte {
+	// Two's complement minimal
+	b := []byte{byte(v)}
+	for v >>= 8; v != 0 && v != -1; v >>= 8 {
+		b = append([]byte{byte(v)}, b...)
+	}
+	if v == 0 && b[0]&0x80 != 0 {
+		b = append([]byte{0}, b...)
+	} else if v == -1 && b[0]&0x80 == 0 {
+		b = append([]byte{0xff}, b...)
+	}
+	return encode(tag, b)
+}
+
+func encodeString(tag byte, s string) []byte {
+	return encode(tag, []byte(s))
+}
+
+func encodeBool(v bool) []byte {
+	if v {
+		return encode(tagBoolean, []byte{0xff})
+	}
+	return encode(tagBoolean, []byte{0x00})
+}
+
+// readElement: baca satu TLV utuh dari stream (pesan LDAP)
+func readElement(r *bufio.Reader) (element, error) {
+	tag, err := r.ReadByte()
+	if err != nil {
+		return element{}, err
+	}
+	first, err := r.ReadByte()
+	if err != nil {
+		return element{}, err
+	}
+	length := int(first)
+	if first&0x80 != 0 {
+		count := int(first & 0x7f)
+		if count == 0 || count > 4 {
+			return element{}, errMalformed
+		}
+		length = 0
+		for i := 0; i < count; i++ {
+			b, err := r.ReadByte()
+			if err != nil {
+				return element{}, err
+			}
+			length = length<<8 | int(b)
+		}
+	}
+	if length > maxMessageSize {
+		return element{}, fmt.Errorf("ldap: message too large (%d bytes)", length)
+	}
+	content := make([]byte, length)
+	if _, err := io.ReadFull(r, content); err != nil {
+		return element{}, err
+	}
+	return element{tag: tag, content: content}, nil
+}
+
+// parseElements: pecah isi elemen constructed menjadi elemen-elemen anaknya
+func parseElements(b []byte) ([]element, error) {
+	var out []element
+	for len(b) > 0 {
+		if len(b) < 2 {
+			return nil, errMalformed
+		}
+		tag, first := b[0], b[1]
+		b = b[2:]
+		length := int(first)
+		if first&0x80 != 0 {
+			count := int(first & 0x7f)
+			if count == 0 || count > 4 || len(b) < count {
+				return nil, errMalformed
+			}
+			length = 0
+			for _, c := range b[:count] {
+				length = length<<8 | int(c)
+			}
+			b = b[count:]
+		}
+		if length > len(b) {
+			return nil, errMalformed
+		}
+		out = append(out, element{tag: tag, content: b[:length]})
+		b = b[length:]
+	}
+	return out, nil
+}
+
+func (e element) children() ([]element, error) {
+	return parseElements(e.content)
+}
+
+func (e element) int() (int, error) {
+	if len(e.content) == 0 || len(e.content) > 4 {
+		return 0, errMalformed
+	}
+	v := int(int8(e.content[0]))
+	for _, b := range e.content[1:] {
+		v = v<<8 | int(b)
+	}
+	return v, nil
+}
@@ -0,0 +1,330 @@
+package ldap
+
+import (
+	"bufio"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Protocol operation (RFC 4511 4.2 - 4.12)
+const (
+	opBindRequest      = classApplication | constructed | 0
+	opBindResponse     = classApplication | constructed | 1
+	opUnbindRequest    = classApplication | 2
+	opSearchRequest    = classApplication | constructed | 3
+	opSearchEntry      = classApplication | constructed | 4
+	opSearchDone       = classApplication | constructed | 5
+	opSearchReference  = classApplication | constructed | 19
+	opExtendedRequest  = classApplication | constructed | 23
+	opExtendedResponse = classApplication | constructed | 24
+
+	bindSimple          = classContext | 0
+	extendedRequestName = classContext | 0
+)
+
+// Result code yang ditangani khusus
+const (
+	ResultSuccess            = 0
+	ResultSizeLimitExceeded  = 4
+	ResultInvalidCredentials = 49
+)
+
+const (
+	oidStartTLS    = "1.3.6.1.4.1.1466.20037"
+	defaultPort    = "389"
+	defaultTLSPort = "636"
+)
+
+// Scope pencarian
+const (
+	ScopeBaseObject   = 0
+	ScopeSingleLevel  = 1
+	ScopeWholeSubtree = 2
+)
+
+// ResultError: operasi ditolak server (resultCode != success)
+type ResultError struct {
+	Code    int
+	Message string
+}
+
+func (e *ResultError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("ldap: result code %d", e.Code)
+	}
+	return fmt.Sprintf("ldap: result code %d: %s", e.Code, e.Message)
+}
+
+type SearchRequest struct {
+	BaseDN     string
+	Scope      int
+	Filter     string
+	Attributes []string // kosong = semua atribut user, "1.1" = tanpa atribut
+	SizeLimit  int
+}
+
+// Entry: hasil pencarian, nama atribut disimpan lowercase
+type Entry struct {
+	DN         string
+	Attributes map[string][]string
+}
+
+func (e *Entry) Values(attr string) []string {
+	return e.Attributes[strings.ToLower(attr)]
+}
+
+// Get: nilai pertama atribut (kosong jika tidak ada)
+func (e *Entry) Get(attr string) string {
+	if values := e.Values(attr); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// Conn: koneksi LDAPv3 sinkron (satu operasi dalam satu waktu)
+type Conn struct {
+	conn    net.Conn
+	r       *bufio.Reader
+	timeout time.Duration
+	lastID  int
+}
+
+// Dial: ldap://host[:389] atau ldaps://host[:636]
+func Dial(rawURL string, tlsConfig *tls.Config, timeout time.Duration) (*Conn, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("ldap: invalid URL: %w", err)
+	}
+	dialer := &net.Dialer{Timeout: timeout}
+
+	var conn net.Conn
+	switch u.Scheme {
+	case "ldap":
+		conn, err = dialer.Dial("tcp", hostPort(u, defaultPort))
+	case "ldaps":
+		conn, err = tls.DialWithDialer(dialer, "tcp", hostPort(u, defaultTLSPort), withServerName(tlsConfig, u.Hostname()))
+	default:
+		return nil, fmt.Errorf("ldap: unsupported URL scheme %q", u.Scheme)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Conn{conn: conn, r: bufio.NewReader(conn), timeout: timeout}, nil
+}
+
+func hostPort(u *url.URL, port string) string {
+	if u.Port() != "" {
+		port = u.Port()
+	}
+	return net.JoinHostPort(u.Hostname(), port)
+}
+
+func withServerName(cfg *tls.Config, host string) *tls.Config {
+	if cfg == nil {
+		cfg = &tls.Config{}
+	} else {
+		cfg = cfg.Clone()
+	}
+	if cfg.ServerName == "" {
+		cfg.ServerName = host
+	}
+	return cfg
+}
+
+// StartTLS: upgrade koneksi ldap:// ke TLS sebelum bind (password tidak dikirim plaintext)
+func (c *Conn) StartTLS(tlsConfig *tls.Config) error {
+	id, err := c.send(encode(opExtendedRequest, encodeString(extendedRequestName, oidStartTLS)))
+	if err != nil {
+		return err
+	}
+	op, err := c.receive(id)
+	if err != nil {
+		return err
+	}
+	if op.tag != opExtendedResponse {
+		return fmt.Errorf("ldap: unexpected response 0x%02x to StartTLS", op.tag)
+	}
+	if err := parseResult(op); err != nil {
+		return err
+	}
+
+	host, _, _ := net.SplitHostPort(c.conn.RemoteAddr().String())
+	tlsConn := tls.Client(c.conn, withServerName(tlsConfig, host))
+	_ = tlsConn.SetDeadline(time.Now().Add(c.timeout))
+	if err := tlsConn.Handshake(); err != nil {
+		return err
+	}
+	c.conn = tlsConn
+	c.r = bufio.NewReader(tlsConn)
+	return nil
+}
+
+// Bind: simple bind. Password kosong ditolak karena server menganggapnya unauthenticated bind (selalu sukses).
+func (c *Conn) Bind(dn, password string) error {
+	if password == "" {
+		return &ResultError{Code: ResultInvalidCredentials, Message: "empty password"}
+	}
+	id, err := c.send(encode(opBindRequest,
+		encodeInt(tagInteger, 3),
+		encodeString(tagOctetString, dn),
+		encodeString(bindSimple, password),
+	))
+	if err != nil {
+		return err
+	}
+	op, err := c.receive(id)
+	if err != nil {
+		return err
+	}
+	if op.tag != opBindResponse {
+		return fmt.Errorf("ldap: unexpected response 0x%02x to bind", op.tag)
+	}
+	return parseResult(op)
+}
+
+// Search: entry yang cocok. Hasil yang terpotong SizeLimit tidak dianggap error.
+func (c *Conn) Search(req SearchRequest) ([]*Entry, error) {
+	filter, err := compileFilter(req.Filter)
+	if err != nil {
+		return nil, err
+	}
+	attrs := make([][]byte, len(req.Attributes))
+	for i, attr := range req.Attributes {
+		attrs[i] = encodeString(tagOctetString, attr)
+	}
+	timeLimit := int(c.timeout / time.Second)
+
+	id, err := c.send(encode(opSearchRequest,
+		encodeString(tagOctetString, req.BaseDN),
+		encodeInt(tagEnumerated, req.Scope),
+		encodeInt(tagEnumerated, 0), // derefAliases: never
+		encodeInt(tagInteger, req.SizeLimit),
+		encodeInt(tagInteger, timeLimit),
+		encodeBool(false),
+		filter,
+		encode(tagSequence, attrs...),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []*Entry
+	for {
+		op, err := c.receive(id)
+		if err != nil {
+			return nil, err
+		}
+		switch op.tag {
+		case opSearchEntry:
+			entry, err := parseEntry(op)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, entry)
+		case opSearchReference:
+			// Referral ke server lain tidak diikuti
+		case opSearchDone:
+			err := parseResult(op)
+			if resultErr, ok := err.(*ResultError); ok && resultErr.Code == ResultSizeLimitExceeded {
+				err = nil
+			}
+			return entries, err
+		default:
+			return nil, fmt.Errorf("ldap: unexpected response 0x%02x to search", op.tag)
+		}
+	}
+}
+
+// Close: kirim unbind (best effort) lalu tutup koneksi
+func (c *Conn) Close() error {
+	_, _ = c.send(encode(opUnbindRequest))
+	return c.conn.Close()
+}
+
+func (c *Conn) send(op []byte) (int, error) {
+	c.lastID++
+	if err := c.conn.SetDeadline(time.Now().Add(c.timeout)); err != nil {
+		return 0, err
+	}
+	_, err := c.conn.Write(encode(tagSequence, encodeInt(tagInteger, c.lastID), op))
+	return c.lastID, err
+}
+
+// receive: protocolOp dari LDAPMessage berikutnya untuk message ID ini
+func (c *Conn) receive(id int) (element, error) {
+	for {
+		msg, err := readElement(c.r)
+		if err != nil {
+			return element{}, err
+		}
+		if msg.tag != tagSequence {
+			return element{}, errMalformed
+		}
+		parts, err := msg.children()
+		if err != nil || len(parts) < 2 {
+			return element{}, errMalformed
+		}
+		msgID, err := parts[0].int()
+		if err != nil {
+			return element{}, err
+		}
+		if msgID == 0 && parts[1].tag == opExtendedResponse {
+			// Notice of Disconnection (RFC 4511 4.4.1)
+			if err := parseResult(parts[1]); err != nil {
+				return element{}, err
+			}
+			return element{}, fmt.Errorf("ldap: server closed the connection")
+		}
+		if msgID == id {
+			return parts[1], nil
+		}
+	}
+}
+
+// parseResult: LDAPResult ::= resultCode, matchedDN, diagnosticMessage, ...
+func parseResult(op element) error {
+	parts, err := op.children()
+	if err != nil || len(parts) < 3 || parts[0].tag != tagEnumerated {
+		return errMalformed
+	}
+	code, err := parts[0].int()
+	if err != nil {
+		return err
+	}
+	if code == ResultSuccess {
+		return nil
+	}
+	return &ResultError{Code: code, Message: string(parts[2].content)}
+}
+
+// parseEntry: SearchResultEntry ::= objectName, SEQUENCE OF (type, SET OF value)
+func parseEntry(op element) (*Entry, error) {
+	parts, err := op.children()
+	if err != nil || len(parts) < 2 {
+		return nil, errMalformed
+	}
+	entry := &Entry{DN: string(parts[0].content), Attributes: map[string][]string{}}
+	attrs, err := parts[1].children()
+	if err != nil {
+		return nil, err
+	}
+	for _, attr := range attrs {
+		fields, err := attr.children()
+		if err != nil || len(fields) < 2 {
+			return nil, errMalformed
+		}
+		values, err := fields[1].children()
+		if err != nil {
+			return nil, err
+		}
+		name := strings.ToLower(string(fields[0].content))
+		for _, v := range values {
+			entry.Attributes[name] = append(entry.Attributes[name], string(v.content))
+		}
+	}
+	return entry, nil
+}
@@ -0,0 +1,181 @@
+package ldap
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// Filter choice (RFC 4511 4.5.1)
+const (
+	filterAnd        = classContext | constructed | 0
+	filterOr         = classContext | constructed | 1
+	filterNot        = classContext | constructed | 2
+	filterEquality   = classContext | constructed | 3
+	filterSubstrings = classContext | constructed | 4
+	filterGreater    = classContext | constructed | 5
+	filterLess       = classContext | constructed | 6
+	filterPresent    = classContext | 7
+	filterApprox     = classContext | constructed | 8
+
+	substringInitial = classContext | 0
+	substringAny     = classContext | 1
+	substringFinal   = classContext | 2
+)
+
+// EscapeFilter: escape nilai dari input user sebelum disisipkan ke filter (RFC 4515)
+func EscapeFilter(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '*', '(', ')', '\\', 0:
+			fmt.Fprintf(&b, "\\%02x", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// compileFilter: string filter (RFC 4515) ke BER. Extensible match (:=) tidak didukung.
+func compileFilter(filter string) ([]byte, error) {
+	filter = strings.TrimSpace(filter)
+	if !strings.HasPrefix(filter, "(") {
+		filter = "(" + filter + ")"
+	}
+	out, rest, err := parseFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	if rest != "" {
+		return nil, fmt.Errorf("ldap: unexpected %q after filter", rest)
+	}
+	return out, nil
+}
+
+func parseFilter(s string) ([]byte, string, error) {
+	if !strings.HasPrefix(s, "(") {
+		return nil, "", fmt.Errorf("ldap: filter must start with '(' near %q", s)
+	}
+	s = s[1:]
+	if s == "" {
+		return nil, "", fmt.Errorf("ldap: unterminated filter")
+	}
+
+	switch s[0] {
+	case '&', '|':
+		tag := byte(filterAnd)
+		if s[0] == '|' {
+			tag = filterOr
+		}
+		s = s[1:]
+		var parts [][]byte
+		for strings.HasPrefix(s, "(") {
+			part, rest, err := parseFilter(s)
+			if err != nil {
+				return nil, "", err
+			}
+			parts = append(parts, part)
+			s = rest
+		}
+		if !strings.HasPrefix(s, ")") {
+			return nil, "", fmt.Errorf("ldap: unterminated filter")
+		}
+		return encode(tag, parts...), s[1:], nil
+	case '!':
+		part, rest, err := parseFilter(s[1:])
+		if err != nil {
+			return nil, "", err
+		}
+		if !strings.HasPrefix(rest, ")") {
+			return nil, "", fmt.Errorf("ldap: unterminated filter")
+		}
+		return encode(filterNot, part), rest[1:], nil
+	}
+
+	end := strings.IndexByte(s, ')')
+	if end < 0 {
+		return nil, "", fmt.Errorf("ldap: unterminated filter")
+	}
+	item, rest := s[:end], s[end+1:]
+	out, err := parseItem(item)
+	return out, rest, err
+}
+
+func parseItem(item string) ([]byte, error) {
+	eq := strings.IndexByte(item, '=')
+	if eq <= 0 {
+		return nil, fmt.Errorf("ldap: invalid filter item %q", item)
+	}
+	attr, raw := item[:eq], item[eq+1:]
+	tag := byte(filterEquality)
+	switch attr[len(attr)-1] {
+	case '>':
+		tag, attr = filterGreater, attr[:len(attr)-1]
+	case '<':
+		tag, attr = filterLess, attr[:len(attr)-1]
+	case '~':
+		tag, attr = filterApprox, attr[:len(attr)-1]
+	case ':':
+		return nil, fmt.Errorf("ldap: extensible match is not supported")
+	}
+	if attr == "" {
+		return nil, fmt.Errorf("ldap: invalid filter item %q", item)
+	}
+
+	if tag != filterEquality || !strings.Contains(raw, "*") {
+		value, err := unescapeValue(raw)
+		if err != nil {
+			return nil, err
+		}
+		return encode(tag, encodeString(tagOctetString, attr), encodeString(tagOctetString, value)), nil
+	}
+	if raw == "*" {
+		return encodeString(filterPresent, attr), nil
+	}
+
+	// Substring: a*b*c -> initial "a", any "b", final "c"
+	pieces := strings.Split(raw, "*")
+	var subs [][]byte
+	for i, piece := range pieces {
+		if piece == "" {
+			continue
+		}
+		value, err := unescapeValue(piece)
+		if err != nil {
+			return nil, err
+		}
+		subTag := byte(substringAny)
+		if i == 0 {
+			subTag = substringInitial
+		} else if i == len(pieces)-1 {
+			subTag = substringFinal
+		}
+		subs = append(subs, encodeString(subTag, value))
+	}
+	return encode(filterSubstrings, encodeString(tagOctetString, attr), encode(tagSequence, subs...)), nil
+}
+
+// unescapeValue: \XX (hex) menjadi byte aslinya
+func unescapeValue(s string) (string, error) {
+	if !strings.Contains(s, "\\") {
+		return s, nil
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			b.WriteByte(s[i])
+			continue
+		}
+		if i+3 > len(s) {
+			return "", fmt.Errorf("ldap: invalid escape in filter value %q", s)
+		}
+		decoded, err := hex.DecodeString(s[i+1 : i+3])
+		if err != nil {
+			return "", fmt.Errorf("ldap: invalid escape in filter value %q", s)
+		}
+		b.Write(decoded)
+		i += 2
+	}
+	return b.String(), nil
+}
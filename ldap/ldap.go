@@ -0,0 +1,237 @@
+package ldap
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"reportachievement/config"
+)
+
+var (
+	ErrInvalidCredentials = errors.New("ldap: invalid credentials")
+	ErrUserNotFound       = errors.New("ldap: user not found")
+)
+
+type Options struct {
+	URL                string // ldap://host:389 atau ldaps://host:636
+	StartTLS           bool   // ldap:// + StartTLS
+	InsecureSkipVerify bool   // hanya untuk development (sertifikat self-signed)
+	Timeout            time.Duration
+
+	// Akun service untuk mencari DN user, kosong = anonymous search
+	BindDN       string
+	BindPassword string
+
+	BaseDN            string
+	UserFilter        string // {username} diganti username yang sudah di-escape, contoh: (uid={username})
+	UsernameAttribute string
+	EmailAttribute    string
+	NameAttribute     string
+	ExtraAttributes   []string // atribut lain yang ikut dibaca (misal NIP)
+
+	// Grup: dari atribut user (memberOf) dan/atau pencarian grup ({dn}, {username})
+	GroupAttribute string
+	GroupBaseDN    string
+	GroupFilter    string
+}
+
+// Identity: user LDAP yang berhasil bind
+type Identity struct {
+	DN       string
+	Username string
+	Email    string
+	FullName string
+	Groups   []string // DN grup
+	Entry    *Entry
+}
+
+// Directory: autentikasi dengan pola search lalu bind sebagai DN user.
+// Setiap login membuka koneksi baru, jumlah login tidak cukup besar untuk butuh pool.
+type Directory struct {
+	opts Options
+	tls  *tls.Config
+}
+
+// New: nil jika LDAP_URL kosong (login LDAP nonaktif)
+func New(cfg *config.Config) *Directory {
+	if cfg.LDAPURL == "" {
+		log.Println("⚠️ LDAP nonaktif (LDAP_URL kosong)")
+		return nil
+	}
+	if cfg.LDAPInsecureSkipVerify {
+		log.Println("⚠️ LDAP: verifikasi sertifikat TLS dimatikan, jangan dipakai di production")
+	}
+	var extra []string
+	if cfg.LDAPProfileIDAttribute != "" {
+		extra = append(extra, cfg.LDAPProfileIDAttribute)
+	}
+	log.Println("✅ LDAP:", cfg.LDAPURL)
+	return NewDirectory(Options{
+		URL:                cfg.LDAPURL,
+		StartTLS:           cfg.LDAPStartTLS,
+		InsecureSkipVerify: cfg.LDAPInsecureSkipVerify,
+		Timeout:            cfg.LDAPTimeout,
+		BindDN:             cfg.LDAPBindDN,
+		BindPassword:       cfg.LDAPBindPassword,
+		BaseDN:             cfg.LDAPBaseDN,
+		UserFilter:         cfg.LDAPUserFilter,
+		UsernameAttribute:  cfg.LDAPUsernameAttribute,
+		EmailAttribute:     cfg.LDAPEmailAttribute,
+		NameAttribute:      cfg.LDAPNameAttribute,
+		ExtraAttributes:    extra,
+		GroupAttribute:     cfg.LDAPGroupAttribute,
+		GroupBaseDN:        cfg.LDAPGroupBaseDN,
+		GroupFilter:        cfg.LDAPGroupFilter,
+	})
+}
+
+func NewDirectory(opts Options) *Directory {
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Second
+	}
+	if opts.UserFilter == "" {
+		opts.UserFilter = "(uid={username})"
+	}
+	if opts.UsernameAttribute == "" {
+		opts.UsernameAttribute = "uid"
+	}
+	if opts.EmailAttribute == "" {
+		opts.EmailAttribute = "mail"
+	}
+	if opts.NameAttribute == "" {
+		opts.NameAttribute = "cn"
+	}
+	if opts.GroupBaseDN == "" {
+		opts.GroupBaseDN = opts.BaseDN
+	}
+	return &Directory{
+		opts: opts,
+		tls:  &tls.Config{InsecureSkipVerify: opts.InsecureSkipVerify, MinVersion: tls.VersionTLS12},
+	}
+}
+
+// Authenticate: cari DN user (akun service), bind dengan password user, lalu kumpulkan grupnya.
+// ErrInvalidCredentials / ErrUserNotFound untuk password salah / user tidak ada, error lain = server bermasalah.
+func (d *Directory) Authenticate(username, password string) (*Identity, error) {
+	if username == "" || password == "" {
+		return nil, ErrInvalidCredentials
+	}
+	conn, err := d.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	// 1. Cari user
+	if err := d.bindService(conn); err != nil {
+		return nil, err
+	}
+	attrs := []string{d.opts.UsernameAttribute, d.opts.EmailAttribute, d.opts.NameAttribute}
+	if d.opts.GroupAttribute != "" {
+		attrs = append(attrs, d.opts.GroupAttribute)
+	}
+	attrs = append(attrs, d.opts.ExtraAttributes...)
+	entries, err := conn.Search(SearchRequest{
+		BaseDN:     d.opts.BaseDN,
+		Scope:      ScopeWholeSubtree,
+		Filter:     strings.ReplaceAll(d.opts.UserFilter, "{username}", EscapeFilter(username)),
+		Attributes: attrs,
+		SizeLimit:  2,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ldap: user search: %w", err)
+	}
+	if len(entries) == 0 {
+		return nil, ErrUserNotFound
+	}
+	if len(entries) > 1 {
+		return nil, fmt.Errorf("ldap: username %q matches more than one entry", username)
+	}
+	entry := entries[0]
+
+	// 2. Verifikasi password
+	if err := conn.Bind(entry.DN, password); err != nil {
+		var resultErr *ResultError
+		if errors.As(err, &resultErr) && resultErr.Code == ResultInvalidCredentials {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, err
+	}
+
+	identity := &Identity{
+		DN:       entry.DN,
+		Username: entry.Get(d.opts.UsernameAttribute),
+		Email:    entry.Get(d.opts.EmailAttribute),
+		FullName: entry.Get(d.opts.NameAttribute),
+		Entry:    entry,
+	}
+	if identity.Username == "" {
+		identity.Username = username
+	}
+	if d.opts.GroupAttribute != "" {
+		identity.Groups = append(identity.Groups, entry.Values(d.opts.GroupAttribute)...)
+	}
+
+	// 3. Grup tanpa memberOf (OpenLDAP tanpa overlay memberof): cari sebagai akun service
+	if d.opts.GroupFilter != "" {
+		if err := d.bindService(conn); err != nil {
+			return nil, err
+		}
+		filter := strings.NewReplacer(
+			"{dn}", EscapeFilter(entry.DN),
+			"{username}", EscapeFilter(identity.Username),
+		).Replace(d.opts.GroupFilter)
+		groups, err := conn.Search(SearchRequest{
+			BaseDN:     d.opts.GroupBaseDN,
+			Scope:      ScopeWholeSubtree,
+			Filter:     filter,
+			Attributes: []string{"1.1"},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("ldap: group search: %w", err)
+		}
+		for _, group := range groups {
+			identity.Groups = append(identity.Groups, group.DN)
+		}
+	}
+	return identity, nil
+}
+
+func (d *Directory) connect() (*Conn, error) {
+	conn, err := Dial(d.opts.URL, d.tls, d.opts.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	if d.opts.StartTLS {
+		if err := conn.StartTLS(d.tls); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("ldap: StartTLS: %w", err)
+		}
+	}
+	return conn, nil
+}
+
+// bindService: bind akun service, tanpa BindDN koneksi tetap anonymous
+func (d *Directory) bindService(conn *Conn) error {
+	if d.opts.BindDN == "" {
+		return nil
+	}
+	if err := conn.Bind(d.opts.BindDN, d.opts.BindPassword); err != nil {
+		return fmt.Errorf("ldap: service bind: %w", err)
+	}
+	return nil
+}
+
+// FirstRDNValue: "cn=dosen,ou=groups,dc=x" -> "dosen"
+func FirstRDNValue(dn string) string {
+	rdn, _, _ := strings.Cut(dn, ",")
+	_, value, found := strings.Cut(rdn, "=")
+	if !found {
+		return ""
+	}
+	return strings.TrimSpace(value)
+}
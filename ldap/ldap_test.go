@@ -0,0 +1,226 @@
+package ldap
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	serviceDN = "cn=reader,dc=unair,dc=ac,dc=id"
+	dosenDN   = "uid=dosen.ldap,ou=people,dc=unair,dc=ac,dc=id"
+	groupDN   = "cn=dosen-wali,ou=groups,dc=unair,dc=ac,dc=id"
+)
+
+// fakeLDAP: server LDAP minimal (bind + search), hasil search ditentukan dari filter hasil compile
+type fakeLDAP struct {
+	passwords map[string]string   // DN -> password
+	results   map[string][]*Entry // filter (BER) -> entry
+	searchers map[string]bool     // DN yang boleh search
+	searches  []string            // filter yang diterima, untuk assert
+}
+
+func newFakeLDAP(t *testing.T) (*fakeLDAP, string) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	f := &fakeLDAP{
+		passwords: map[string]string{serviceDN: "service-secret", dosenDN: "Dosen-Ldap-2026"},
+		results:   map[string][]*Entry{},
+		searchers: map[string]bool{serviceDN: true},
+	}
+	f.on(t, "(uid=dosen.ldap)", &Entry{DN: dosenDN, Attributes: map[string][]string{
+		"uid":            {"dosen.ldap"},
+		"mail":           {"dosen.ldap@unair.ac.id"},
+		"cn":             {"Dosen LDAP"},
+		"employeenumber": {"198001012005011001"},
+		"memberof":       {"cn=staff,ou=groups,dc=unair,dc=ac,dc=id"},
+	}})
+	f.on(t, "(&(objectClass=groupOfNames)(member="+EscapeFilter(dosenDN)+"))", &Entry{DN: groupDN})
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+	return f, "ldap://" + ln.Addr().String()
+}
+
+func (f *fakeLDAP) on(t *testing.T, filter string, entries ...*Entry) {
+	compiled, err := compileFilter(filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.results[string(compiled)] = entries
+}
+
+func (f *fakeLDAP) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	bound := ""
+	for {
+		msg, err := readElement(r)
+		if err != nil {
+			return
+		}
+		parts, _ := msg.children()
+		id, _ := parts[0].int()
+		reply := func(op []byte) { conn.Write(encode(tagSequence, encodeInt(tagInteger, id), op)) }
+		result := func(tag byte, code int) []byte {
+			return encode(tag, encodeInt(tagEnumerated, code), encodeString(tagOctetString, ""), encodeString(tagOctetString, ""))
+		}
+
+		switch op := parts[1]; op.tag {
+		case opBindRequest:
+			fields, _ := op.children()
+			dn, password := string(fields[1].content), string(fields[2].content)
+			if want, ok := f.passwords[dn]; !ok || want != password {
+				bound = ""
+				reply(result(opBindResponse, ResultInvalidCredentials))
+				continue
+			}
+			bound = dn
+			reply(result(opBindResponse, ResultSuccess))
+		case opSearchRequest:
+			fields, _ := op.children()
+			filter := fields[6]
+			raw := string(encode(filter.tag, filter.content))
+			f.searches = append(f.searches, raw)
+			if !f.searchers[bound] {
+				reply(result(opSearchDone, 50)) // insufficientAccessRights
+				continue
+			}
+			for _, entry := range f.results[raw] {
+				var attrs [][]byte
+				for name, values := range entry.Attributes {
+					var vals [][]byte
+					for _, v := range values {
+						vals = append(vals, encodeString(tagOctetString, v))
+					}
+					attrs = append(attrs, encode(tagSequence, encodeString(tagOctetString, name), encode(tagSet, vals...)))
+				}
+				reply(encode(opSearchEntry, encodeString(tagOctetString, entry.DN), encode(tagSequence, attrs...)))
+			}
+			reply(result(opSearchDone, ResultSuccess))
+		case opUnbindRequest:
+			return
+		}
+	}
+}
+
+func newTestDirectory(url string) *Directory {
+	return NewDirectory(Options{
+		URL:             url,
+		Timeout:         2 * time.Second,
+		BindDN:          serviceDN,
+		BindPassword:    "service-secret",
+		BaseDN:          "dc=unair,dc=ac,dc=id",
+		GroupAttribute:  "memberOf",
+		GroupFilter:     "(&(objectClass=groupOfNames)(member={dn}))",
+		ExtraAttributes: []string{"employeeNumber"},
+	})
+}
+
+func TestDirectory_Authenticate(t *testing.T) {
+	_, url := newFakeLDAP(t)
+	dir := newTestDirectory(url)
+
+	t.Run("Sukses", func(t *testing.T) {
+		identity, err := dir.Authenticate("dosen.ldap", "Dosen-Ldap-2026")
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, dosenDN, identity.DN)
+		assert.Equal(t, "dosen.ldap@unair.ac.id", identity.Email)
+		assert.Equal(t, "Dosen LDAP", identity.FullName)
+		assert.Equal(t, "198001012005011001", identity.Entry.Get("employeeNumber"))
+		assert.ElementsMatch(t, []string{"cn=staff,ou=groups,dc=unair,dc=ac,dc=id", groupDN}, identity.Groups)
+	})
+
+	t.Run("Password Salah", func(t *testing.T) {
+		_, err := dir.Authenticate("dosen.ldap", "salah")
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+	})
+
+	t.Run("Password Kosong (unauthenticated bind)", func(t *testing.T) {
+		_, err := dir.Authenticate("dosen.ldap", "")
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+	})
+
+	t.Run("User Tidak Ada", func(t *testing.T) {
+		_, err := dir.Authenticate("tidak.ada", "apapun")
+		assert.ErrorIs(t, err, ErrUserNotFound)
+	})
+
+	t.Run("Injeksi Filter", func(t *testing.T) {
+		f, url := newFakeLDAP(t)
+		_, err := newTestDirectory(url).Authenticate("*", "Dosen-Ldap-2026")
+		assert.ErrorIs(t, err, ErrUserNotFound)
+		want, _ := compileFilter(`(uid=\2a)`)
+		assert.Equal(t, string(want), f.searches[0])
+	})
+
+	t.Run("Akun Service Salah", func(t *testing.T) {
+		bad := newTestDirectory(url)
+		bad.opts.BindPassword = "salah"
+		_, err := bad.Authenticate("dosen.ldap", "Dosen-Ldap-2026")
+		assert.Error(t, err)
+		assert.NotErrorIs(t, err, ErrInvalidCredentials)
+	})
+}
+
+func TestCompileFilter(t *testing.T) {
+	got, err := compileFilter("(&(objectClass=person)(!(uid=a))(cn=Do*n*))")
+	assert.NoError(t, err)
+	want := encode(filterAnd,
+		encode(filterEquality, encodeString(tagOctetString, "objectClass"), encodeString(tagOctetString, "person")),
+		encode(filterNot, encode(filterEquality, encodeString(tagOctetString, "uid"), encodeString(tagOctetString, "a"))),
+		encode(filterSubstrings, encodeString(tagOctetString, "cn"), encode(tagSequence,
+			encodeString(substringInitial, "Do"), encodeString(substringAny, "n"))),
+	)
+	assert.Equal(t, want, got)
+
+	got, err = compileFilter("mail=*")
+	assert.NoError(t, err)
+	assert.Equal(t, encodeString(filterPresent, "mail"), got)
+
+	got, err = compileFilter("(cn=" + EscapeFilter("a*(b)\\") + ")")
+	assert.NoError(t, err)
+	assert.Equal(t, encode(filterEquality, encodeString(tagOctetString, "cn"), encodeString(tagOctetString, "a*(b)\\")), got)
+
+	for _, bad := range []string{"(uid=a", "(&(uid=a)", "(=a)", "(uid=a))", `(uid=\zz)`, "(uid:dn:=a)"} {
+		_, err := compileFilter(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestBER_LongLength(t *testing.T) {
+	value := strings.Repeat("x", 300)
+	elements, err := parseElements(encodeString(tagOctetString, value))
+	assert.NoError(t, err)
+	assert.Len(t, elements, 1)
+	assert.Equal(t, value, string(elements[0].content))
+
+	for _, v := range []int{0, 3, 127, 128, 255, 256, 65535} {
+		el, _ := parseElements(encodeInt(tagInteger, v))
+		got, err := el[0].int()
+		assert.NoError(t, err)
+		assert.Equal(t, v, got)
+	}
+}
+
+func TestFirstRDNValue(t *testing.T) {
+	assert.Equal(t, "dosen-wali", FirstRDNValue(groupDN))
+	assert.Equal(t, "", FirstRDNValue("bukan dn"))
+}
@@ -0,0 +1,52 @@
+package mailer
+
+import (
+	"context"
+	"log"
+
+	"reportachievement/config"
+)
+
+// Message: email teks biasa (UTF-8)
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer: pengirim email (reset password, notifikasi)
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+	Name() string
+}
+
+// New: pilih mailer sesuai MAILER (log | smtp)
+func New(cfg *config.Config) Mailer {
+	switch cfg.Mailer {
+	case "smtp":
+		log.Println("✅ Mailer: SMTP", cfg.SMTPHost)
+		return NewSMTP(SMTPOptions{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     cfg.MailFrom,
+			Timeout:  cfg.SMTPTimeout,
+		})
+	default:
+		log.Println("⚠️ Mailer: log (email hanya ditulis ke log, jangan dipakai di production)")
+		return Log{}
+	}
+}
+
+// Log: default untuk development, isi email ditulis ke log
+type Log struct{}
+
+func (Log) Send(ctx context.Context, msg Message) error {
+	log.Printf("📧 [mailer:log] To: %s | Subject: %s\n%s", msg.To, msg.Subject, msg.Body)
+	return nil
+}
+
+func (Log) Name() string {
+	return "log"
+}
@@ -0,0 +1,112 @@
+package mailer
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"mime/quotedprintable"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type received struct {
+	from, to string
+	data     string
+}
+
+// fakeSMTP: server SMTP minimal (tanpa STARTTLS/AUTH, seperti MailHog), hasil dikirim ke channel
+func fakeSMTP(t *testing.T) (string, int, <-chan received) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	out := make(chan received, 1)
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+		var msg received
+		reply("220 fake ESMTP")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			cmd := strings.TrimSpace(line)
+			switch {
+			case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+				reply("250 fake")
+			case strings.HasPrefix(cmd, "MAIL FROM:"):
+				msg.from = strings.Trim(strings.TrimPrefix(cmd, "MAIL FROM:"), "<> ")
+				reply("250 OK")
+			case strings.HasPrefix(cmd, "RCPT TO:"):
+				msg.to = strings.Trim(strings.TrimPrefix(cmd, "RCPT TO:"), "<> ")
+				reply("250 OK")
+			case cmd == "DATA":
+				reply("354 go ahead")
+				var data strings.Builder
+				for {
+					l, err := r.ReadString('\n')
+					if err != nil || l == ".\r\n" {
+						break
+					}
+					data.WriteString(l)
+				}
+				msg.data = data.String()
+				reply("250 queued")
+			case cmd == "QUIT":
+				reply("221 bye")
+				out <- msg
+				return
+			default:
+				reply("250 OK")
+			}
+		}
+	}()
+
+	addr := ln.Addr().(*net.TCPAddr)
+	return addr.IP.String(), addr.Port, out
+}
+
+func TestSMTP_Send(t *testing.T) {
+	host, port, out := fakeSMTP(t)
+	m := NewSMTP(SMTPOptions{Host: host, Port: port, From: "Sistem Prestasi <no-reply@unair.ac.id>", Timeout: 5 * time.Second})
+
+	err := m.Send(context.Background(), Message{
+		To:      "mhs1@unair.ac.id",
+		Subject: "Reset password – Sistem Prestasi",
+		Body:    "Halo,\nklik link berikut: http://localhost:3000/reset-password?token=abc",
+	})
+	assert.NoError(t, err)
+
+	select {
+	case msg := <-out:
+		assert.Equal(t, "no-reply@unair.ac.id", msg.from)
+		assert.Equal(t, "mhs1@unair.ac.id", msg.to)
+		assert.Contains(t, msg.data, "Subject: =?utf-8?q?")
+		assert.Contains(t, msg.data, "Content-Transfer-Encoding: quoted-printable")
+
+		_, body, _ := strings.Cut(msg.data, "\r\n\r\n")
+		decoded, _ := io.ReadAll(quotedprintable.NewReader(strings.NewReader(body)))
+		assert.Contains(t, string(decoded), "reset-password?token=abc")
+	case <-time.After(5 * time.Second):
+		t.Fatal("email tidak diterima server")
+	}
+}
+
+func TestSMTP_InvalidRecipient(t *testing.T) {
+	m := NewSMTP(SMTPOptions{Host: "127.0.0.1", Port: 1, From: "no-reply@unair.ac.id"})
+	err := m.Send(context.Background(), Message{To: "bukan email", Subject: "x", Body: "x"})
+	assert.Error(t, err)
+}
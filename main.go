@@ -2,6 +2,9 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
 	"log"
 	"os"
 
@@ -15,8 +18,16 @@ import (
 	repoPostgre "reportachievement/app/repository/postgre"
 
 	"reportachievement/app/service"
+	"reportachievement/jwtkeys"
+	"reportachievement/ldap"
+	"reportachievement/mailer"
+	"reportachievement/middleware"
+	"reportachievement/oidc"
+	"reportachievement/password"
 
 	routePostgre "reportachievement/route/postgre"
+	"reportachievement/scanner"
+	"reportachievement/storage"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
@@ -49,7 +60,11 @@ func main() {
 	dbPostgres.AutoMigrate(
 		&postgre.Role{}, &postgre.User{}, &postgre.Permission{}, &postgre.RolePermission{},
 		&postgre.Lecturer{}, &postgre.Student{}, &postgre.AchievementReference{},
+		&postgre.UserSession{}, &postgre.RefreshToken{}, &postgre.PasswordResetToken{},
+		&postgre.LoginAttempt{}, &postgre.MFARecoveryCode{},
+		&postgre.ServiceAccount{}, &postgre.APIKey{},
 	)
+	postgres.SeedPermissions(dbPostgres)
 
 	sqlDB, _ := dbPostgres.DB()
 	defer sqlDB.Close()
@@ -61,17 +76,63 @@ func main() {
 		}
 	}()
 
+	fileStorage := storage.New(cfg)
+
 	// 4. Dependency Injection
 	userRepo := repoPostgre.NewUserRepository(dbPostgres)
 	studentRepo := repoPostgre.NewStudentRepository(dbPostgres)
 	achRefRepo := repoPostgre.NewAchievementRepository(dbPostgres)
 	lecturerRepo := repoPostgre.NewLecturerRepository(dbPostgres)
 	achMongoRepo := repoMongo.NewAchievementRepository(dbMongo.Db)
+	evidenceRepo := repoMongo.NewEvidenceFileRepository(dbMongo.Db)
 
-	authService := service.NewAuthService(userRepo)
-	userService := service.NewUserService(userRepo)
-	achService := service.NewAchievementService(studentRepo, lecturerRepo, achRefRepo, achMongoRepo)
+	// Subcommand: go run . gc [-delete], default hanya laporan (dry-run)
+	storageGC := service.NewStorageGC(cfg, fileStorage, achMongoRepo, evidenceRepo)
+	if len(os.Args) > 1 && os.Args[1] == "gc" {
+		runStorageGC(storageGC, os.Args[2:])
+		return
+	}
+	storageGC.Start(context.Background())
+
+	jwtKeys := jwtkeys.New(cfg)
+	jwtKeys.Start(context.Background())
+	middleware.SetKeyManager(jwtKeys)
+
+	passwordPolicy := password.New(cfg)
+	loginGuard := service.NewLoginGuard(repoPostgre.NewLoginAttemptRepository(dbPostgres), userRepo, cfg)
+	loginGuard.StartCleanup(context.Background())
+	permissionRepo := repoPostgre.NewPermissionRepository(dbPostgres)
+	permissionService := service.NewPermissionService(permissionRepo)
+	middleware.SetPermissionChecker(permissionService.HasPermission)
+	authService := service.NewAuthService(userRepo, repoPostgre.NewSessionRepository(dbPostgres), jwtKeys, passwordPolicy, loginGuard, permissionService, cfg)
+	// Login LDAP (opsional), user belum terdaftar dibuat saat login pertama jika LDAP_AUTO_PROVISION=true
+	if directory := ldap.New(cfg); directory != nil {
+		authService.RegisterProvider(service.NewLDAPProvider(directory, userRepo, authService, cfg), cfg.LDAPAutoProvision)
+	}
+	mfaService := service.NewMFAService(userRepo, repoPostgre.NewMFARepository(dbPostgres), authService, loginGuard, cfg)
+	oidcService := service.NewOIDCService(oidc.New(cfg), userRepo, authService, loginGuard, cfg)
+	userService := service.NewUserService(userRepo, permissionRepo, permissionService, authService, passwordPolicy)
+	passwordService := service.NewPasswordService(userRepo, repoPostgre.NewPasswordResetRepository(dbPostgres), authService, mailer.New(cfg), passwordPolicy, cfg)
+	passwordService.StartCleanup(context.Background())
+	middleware.SetSessionValidator(authService.SessionStatus)
+	apiKeyService := service.NewAPIKeyService(repoPostgre.NewAPIKeyRepository(dbPostgres), permissionRepo, cfg)
+	middleware.SetAPIKeyValidator(service.APIKeyPrefix, apiKeyService.Authenticate)
+	roleService := service.NewRoleService(permissionRepo, userRepo, permissionService, authService)
+	fileLinker := service.NewFileLinker(cfg)
+	evidenceProcessor := service.NewEvidenceProcessor(cfg, fileStorage, scanner.New(cfg), evidenceRepo, achMongoRepo, fileLinker)
+	evidenceProcessor.Start(context.Background())
+
+	evidencePolicy := service.NewEvidencePolicy(cfg)
+	achService := service.NewAchievementService(studentRepo, lecturerRepo, achRefRepo, achMongoRepo, evidenceRepo, fileStorage, evidencePolicy, fileLinker, evidenceProcessor)
+	uploadService, err := service.NewResumableUploadService(cfg, achService, evidencePolicy)
+	if err != nil {
+		log.Fatal("❌ Gagal menyiapkan direktori resumable upload:", err)
+	}
+	uploadService.StartCleanup(context.Background())
 	reportService := service.NewReportService(achMongoRepo, studentRepo)
+	skpiService := service.NewSKPIService(studentRepo, lecturerRepo, achRefRepo, achMongoRepo)
+	exportService := service.NewExportService(cfg, achRefRepo, achMongoRepo, repoMongo.NewExportJobRepository(dbMongo.Db), fileStorage)
+	exportService.Start(context.Background())
 
 	// 5. Init Fiber
 	app := fiber.New(fiber.Config{
@@ -79,27 +140,56 @@ func main() {
 	})
 
 	app.Use(recover.New())
-	app.Use(cors.New())
+	app.Use(cors.New(cors.Config{
+		// Header tus harus terbaca oleh client browser (tus-js-client)
+		ExposeHeaders: "Location, Upload-Offset, Upload-Length, Upload-Expires, Upload-Attachment-Id, Tus-Resumable, Tus-Version, Tus-Extension, Tus-Max-Size",
+	}))
 	// Logger Middleware menulis ke file juga
 	app.Use(logger.New(logger.Config{
 		Output: file,
 	}))
 
 	// 6. Static Files
-	if _, err := os.Stat("./uploads"); os.IsNotExist(err) {
-		os.Mkdir("./uploads", 0755)
+	// Evidence TIDAK lagi diserve static, download lewat /api/v1/files (cek akses)
+	if n, err := achService.MigrateLegacyAttachments(context.Background()); err != nil {
+		log.Println("⚠️ Gagal migrasi attachment lama:", err)
+	} else if n > 0 {
+		log.Printf("✅ %d prestasi dengan attachment lama dimigrasi", n)
 	}
-	app.Static("/uploads", "./uploads")
 	app.Static("/", "./public")
 
 	// 7. Routes
 	app.Get("/swagger/*", swagger.HandlerDefault)
-	routePostgre.RegisterAuthRoutes(app, authService)
+	routePostgre.RegisterAuthRoutes(app, authService, passwordService)
+	routePostgre.RegisterKeyRoutes(app, jwtKeys)
 	routePostgre.RegisterAchievementRoutes(app, achService)
-	routePostgre.RegisterUserRoutes(app, userService)
-	routePostgre.RegisterReportRoutes(app, reportService)
+	routePostgre.RegisterUploadRoutes(app, uploadService)
+	routePostgre.RegisterFileRoutes(app, achService)
+	routePostgre.RegisterUserRoutes(app, userService, passwordService)
+	routePostgre.RegisterRoleRoutes(app, roleService)
+	routePostgre.RegisterLoginAttemptRoutes(app, loginGuard)
+	routePostgre.RegisterMFARoutes(app, mfaService)
+	routePostgre.RegisterServiceAccountRoutes(app, apiKeyService)
+	routePostgre.RegisterOIDCRoutes(app, oidcService, cfg.OIDCFrontendURL)
+	routePostgre.RegisterReportRoutes(app, reportService, skpiService)
+	routePostgre.RegisterStorageRoutes(app, storageGC)
+	routePostgre.RegisterExportRoutes(app, exportService)
 
 	// 8. Run
 	log.Println("🚀 Server running on port", cfg.AppPort)
 	log.Fatal(app.Listen(cfg.AppPort))
 }
+
+func runStorageGC(gc *service.StorageGC, args []string) {
+	fs := flag.NewFlagSet("gc", flag.ExitOnError)
+	deleteFiles := fs.Bool("delete", false, "hapus file orphan (tanpa flag ini hanya laporan)")
+	fs.Parse(args)
+
+	report, err := gc.Run(context.Background(), !*deleteFiles)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "storage gc gagal:", err)
+		os.Exit(1)
+	}
+	out, _ := json.MarshalIndent(report, "", "  ")
+	fmt.Println(string(out))
+}
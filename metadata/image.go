@@ -0,0 +1,267 @@
+package metadata
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+var (
+	ErrInvalidJPEG = errors.New("invalid JPEG file")
+	ErrInvalidPNG  = errors.New("invalid PNG file")
+)
+
+// StripImage: salin gambar dari r ke w tanpa metadata (EXIF, XMP, IPTC, komentar).
+// Hanya struktur container yang diubah, pixel tidak di-encode ulang.
+// Tipe selain JPEG/PNG disalin apa adanya.
+func StripImage(contentType string, r io.Reader, w io.Writer) error {
+	switch contentType {
+	case "image/jpeg":
+		return stripJPEG(bufio.NewReader(r), w)
+	case "image/png":
+		return stripPNG(bufio.NewReader(r), w)
+	default:
+		_, err := io.Copy(w, r)
+		return err
+	}
+}
+
+// readError: file terpotong = format tidak valid, error lain (misal batas ukuran) diteruskan
+func readError(err error, invalid error) error {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return invalid
+	}
+	return err
+}
+
+// --- JPEG ---
+
+const (
+	markerSOI   = 0xD8
+	markerEOI   = 0xD9
+	markerSOS   = 0xDA
+	markerAPP0  = 0xE0 // JFIF
+	markerAPP1  = 0xE1 // Exif, XMP
+	markerAPP2  = 0xE2 // ICC profile (dipertahankan), FlashPix
+	markerAPP14 = 0xEE // Adobe, dibutuhkan untuk transformasi warna
+	markerAPP15 = 0xEF
+	markerCOM   = 0xFE
+)
+
+// stripJPEG: buang segmen APPn kecuali JFIF, ICC profile & Adobe, serta COM.
+// Orientasi dari EXIF dipertahankan (EXIF minimal) agar foto dari HP tidak tampil miring.
+// Setelah SOS data gambar disalin apa adanya.
+func stripJPEG(r *bufio.Reader, w io.Writer) error {
+	var soi [2]byte
+	if _, err := io.ReadFull(r, soi[:]); err != nil {
+		return readError(err, ErrInvalidJPEG)
+	}
+	if soi[0] != 0xFF || soi[1] != markerSOI {
+		return ErrInvalidJPEG
+	}
+	if _, err := w.Write(soi[:]); err != nil {
+		return err
+	}
+
+	orientationWritten := false
+	for {
+		marker, err := readMarker(r)
+		if err != nil {
+			return err
+		}
+
+		// Marker tanpa payload
+		if marker == markerEOI || (marker >= 0xD0 && marker <= 0xD7) || marker == 0x01 {
+			if _, err := w.Write([]byte{0xFF, marker}); err != nil {
+				return err
+			}
+			if marker == markerEOI {
+				return nil
+			}
+			continue
+		}
+
+		var lengthBuf [2]byte
+		if _, err := io.ReadFull(r, lengthBuf[:]); err != nil {
+			return readError(err, ErrInvalidJPEG)
+		}
+		length := int(binary.BigEndian.Uint16(lengthBuf[:]))
+		if length < 2 {
+			return ErrInvalidJPEG
+		}
+		payload := make([]byte, length-2)
+		if _, err := io.ReadFull(r, payload); err != nil {
+			return readError(err, ErrInvalidJPEG)
+		}
+
+		if dropJPEGSegment(marker, payload) {
+			if marker == markerAPP1 && !orientationWritten {
+				if orientation := exifOrientation(payload); orientation > 1 {
+					if _, err := w.Write(orientationSegment(orientation)); err != nil {
+						return err
+					}
+					orientationWritten = true
+				}
+			}
+			continue
+		}
+
+		if _, err := w.Write([]byte{0xFF, marker, lengthBuf[0], lengthBuf[1]}); err != nil {
+			return err
+		}
+		if _, err := w.Write(payload); err != nil {
+			return err
+		}
+
+		// Start of scan: sisa file adalah data gambar
+		if marker == markerSOS {
+			_, err := io.Copy(w, r)
+			return err
+		}
+	}
+}
+
+// readMarker: lewati fill byte 0xFF lalu kembalikan kode marker
+func readMarker(r *bufio.Reader) (byte, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return 0, readError(err, ErrInvalidJPEG)
+	}
+	if b != 0xFF {
+		return 0, ErrInvalidJPEG
+	}
+	for b == 0xFF {
+		if b, err = r.ReadByte(); err != nil {
+			return 0, readError(err, ErrInvalidJPEG)
+		}
+	}
+	return b, nil
+}
+
+func dropJPEGSegment(marker byte, payload []byte) bool {
+	switch {
+	case marker == markerCOM:
+		return true
+	case marker == markerAPP0, marker == markerAPP14:
+		return false
+	case marker == markerAPP2:
+		return !bytes.HasPrefix(payload, []byte("ICC_PROFILE\x00"))
+	case marker >= markerAPP1 && marker <= markerAPP15:
+		return true
+	}
+	return false
+}
+
+// exifOrientation: baca tag Orientation (0x0112) dari IFD0, 0 jika tidak ada
+func exifOrientation(payload []byte) int {
+	tiff, ok := bytes.CutPrefix(payload, []byte("Exif\x00\x00"))
+	if !ok || len(tiff) < 8 {
+		return 0
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0
+	}
+
+	ifd := int(order.Uint32(tiff[4:8]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 0
+	}
+	count := int(order.Uint16(tiff[ifd : ifd+2]))
+	for i := 0; i < count; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 0
+		}
+		if order.Uint16(tiff[entry:entry+2]) == 0x0112 && order.Uint16(tiff[entry+2:entry+4]) == 3 {
+			orientation := int(order.Uint16(tiff[entry+8 : entry+10]))
+			if orientation >= 1 && orientation <= 8 {
+				return orientation
+			}
+			return 0
+		}
+	}
+	return 0
+}
+
+// orientationSegment: APP1 EXIF yang hanya berisi tag Orientation
+func orientationSegment(orientation int) []byte {
+	exif := []byte{
+		'E', 'x', 'i', 'f', 0, 0,
+		'M', 'M', 0x00, 0x2A, 0x00, 0x00, 0x00, 0x08, // TIFF header big endian, IFD0 di offset 8
+		0x00, 0x01, // 1 entry
+		0x01, 0x12, 0x00, 0x03, 0x00, 0x00, 0x00, 0x01, 0x00, byte(orientation), 0x00, 0x00, // Orientation SHORT
+		0x00, 0x00, 0x00, 0x00, // tidak ada IFD berikutnya
+	}
+	segment := []byte{0xFF, markerAPP1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(exif)+2))
+	return append(segment, exif...)
+}
+
+// --- PNG ---
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// Chunk metadata yang dibuang; chunk lain (termasuk yang tidak dikenal) tetap disalin
+var pngMetadataChunks = map[string]bool{
+	"tEXt": true,
+	"zTXt": true,
+	"iTXt": true,
+	"eXIf": true,
+	"tIME": true,
+}
+
+func stripPNG(r *bufio.Reader, w io.Writer) error {
+	signature := make([]byte, len(pngSignature))
+	if _, err := io.ReadFull(r, signature); err != nil {
+		return readError(err, ErrInvalidPNG)
+	}
+	if !bytes.Equal(signature, pngSignature) {
+		return ErrInvalidPNG
+	}
+	if _, err := w.Write(signature); err != nil {
+		return err
+	}
+
+	for {
+		var header [8]byte // length + type
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			return readError(err, ErrInvalidPNG)
+		}
+		length := int64(binary.BigEndian.Uint32(header[:4]))
+		chunkType := string(header[4:])
+		body := io.LimitReader(r, length+4) // data + CRC
+
+		if pngMetadataChunks[chunkType] {
+			n, err := io.Copy(io.Discard, body)
+			if err != nil {
+				return err
+			}
+			if n != length+4 {
+				return ErrInvalidPNG
+			}
+			continue
+		}
+
+		if _, err := w.Write(header[:]); err != nil {
+			return err
+		}
+		n, err := io.Copy(w, body)
+		if err != nil {
+			return err
+		}
+		if n != length+4 {
+			return ErrInvalidPNG
+		}
+		if chunkType == "IEND" {
+			return nil // data setelah IEND (misal file tersembunyi) ikut dibuang
+		}
+	}
+}
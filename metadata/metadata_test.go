@@ -0,0 +1,116 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"testing"
+	"time"
+
+	"github.com/go-pdf/fpdf"
+	"github.com/stretchr/testify/assert"
+)
+
+func testImage() image.Image {
+	return image.NewRGBA(image.Rect(0, 0, 4, 3))
+}
+
+// exifWithGPS: APP1 EXIF (little endian) dengan Orientation = 6 dan string GPS palsu
+func exifWithGPS() []byte {
+	tiff := []byte{'I', 'I', 0x2A, 0x00, 0x08, 0x00, 0x00, 0x00, 0x01, 0x00,
+		0x12, 0x01, 0x03, 0x00, 0x01, 0x00, 0x00, 0x00, 0x06, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00}
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	payload = append(payload, []byte("GPS -6.9175,107.6191")...)
+	segment := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	return append(segment, payload...)
+}
+
+func TestStripImage_JPEG(t *testing.T) {
+	var src bytes.Buffer
+	assert.NoError(t, jpeg.Encode(&src, testImage(), nil))
+	raw := src.Bytes()
+	comment := []byte{0xFF, 0xFE, 0x00, 0x0B, 'r', 'u', 'm', 'a', 'h', ' ', 's', 'a', 'y'}
+	withMeta := append(append(append([]byte{}, raw[:2]...), append(exifWithGPS(), comment...)...), raw[2:]...)
+
+	var out bytes.Buffer
+	assert.NoError(t, StripImage("image/jpeg", bytes.NewReader(withMeta), &out))
+
+	assert.NotContains(t, out.String(), "GPS")
+	assert.NotContains(t, out.String(), "rumah")
+	_, err := jpeg.Decode(bytes.NewReader(out.Bytes()))
+	assert.NoError(t, err)
+
+	// Orientasi tetap ada sebagai EXIF minimal
+	idx := bytes.Index(out.Bytes(), []byte("Exif\x00\x00"))
+	assert.True(t, idx > 0)
+	assert.Equal(t, 6, exifOrientation(out.Bytes()[idx:]))
+}
+
+func TestStripImage_PNG(t *testing.T) {
+	var src bytes.Buffer
+	assert.NoError(t, png.Encode(&src, testImage()))
+	raw := src.Bytes()
+
+	text := []byte("Comment\x00lokasi rumah")
+	chunk := make([]byte, 8, 12+len(text))
+	binary.BigEndian.PutUint32(chunk[:4], uint32(len(text)))
+	copy(chunk[4:], "tEXt")
+	chunk = append(chunk, text...)
+	chunk = binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+
+	ihdrEnd := 8 + 8 + 13 + 4 // signature + IHDR
+	withMeta := append(append(append([]byte{}, raw[:ihdrEnd]...), chunk...), raw[ihdrEnd:]...)
+
+	var out bytes.Buffer
+	assert.NoError(t, StripImage("image/png", bytes.NewReader(withMeta), &out))
+
+	assert.NotContains(t, out.String(), "lokasi rumah")
+	assert.Equal(t, raw, out.Bytes())
+}
+
+func TestStripImage_RejectsInvalidJPEG(t *testing.T) {
+	var out bytes.Buffer
+	err := StripImage("image/jpeg", bytes.NewReader([]byte{0xFF, 0xD8, 0x00}), &out)
+	assert.ErrorIs(t, err, ErrInvalidJPEG)
+}
+
+func TestReadPDF(t *testing.T) {
+	created := time.Date(2024, 5, 17, 9, 30, 0, 0, time.UTC)
+	doc := fpdf.New("P", "mm", "A4", "")
+	doc.SetTitle("Sertifikat Juara 1", true)
+	doc.SetAuthor("Panitia Lomba", true)
+	doc.SetCreationDate(created)
+	doc.AddPage()
+	doc.AddPage()
+	var buf bytes.Buffer
+	assert.NoError(t, doc.Output(&buf))
+
+	info, err := ReadPDF(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.NoError(t, err)
+	assert.Equal(t, "Sertifikat Juara 1", info.Title)
+	assert.Equal(t, "Panitia Lomba", info.Author)
+	assert.Equal(t, 2, info.PageCount)
+	if assert.NotNil(t, info.CreatedAt) {
+		assert.True(t, created.Equal(*info.CreatedAt))
+	}
+}
+
+func TestReadPDF_Invalid(t *testing.T) {
+	data := []byte("%PDF-1.4\nbukan pdf")
+	_, err := ReadPDF(bytes.NewReader(data), int64(len(data)))
+	assert.Error(t, err)
+}
+
+func TestParsePDFDate(t *testing.T) {
+	d := parsePDFDate("D:20240517163000+07'00'")
+	if assert.NotNil(t, d) {
+		assert.Equal(t, "2024-05-17T09:30:00Z", d.UTC().Format(time.RFC3339))
+	}
+	assert.Nil(t, parsePDFDate("D:2024133"))
+	assert.Nil(t, parsePDFDate(""))
+}
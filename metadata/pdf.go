@@ -0,0 +1,119 @@
+package metadata
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// PDFInfo: isi dictionary /Info dan jumlah halaman sebuah PDF
+type PDFInfo struct {
+	Title      string
+	Author     string
+	Creator    string // aplikasi pembuat dokumen asli
+	Producer   string // aplikasi yang menulis PDF
+	CreatedAt  *time.Time
+	ModifiedAt *time.Time
+	PageCount  int
+}
+
+// ReadPDF: baca metadata PDF. PDF rusak/terenkripsi mengembalikan error, bukan panic.
+func ReadPDF(r io.ReaderAt, size int64) (info *PDFInfo, err error) {
+	defer func() {
+		// Parser pdf bisa panic untuk struktur yang tidak valid
+		if rec := recover(); rec != nil {
+			info, err = nil, fmt.Errorf("invalid PDF: %v", rec)
+		}
+	}()
+
+	reader, err := pdf.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	dict := reader.Trailer().Key("Info")
+	info = &PDFInfo{
+		Title:      cleanText(dict.Key("Title").Text()),
+		Author:     cleanText(dict.Key("Author").Text()),
+		Creator:    cleanText(dict.Key("Creator").Text()),
+		Producer:   cleanText(dict.Key("Producer").Text()),
+		CreatedAt:  parsePDFDate(dict.Key("CreationDate").RawString()),
+		ModifiedAt: parsePDFDate(dict.Key("ModDate").RawString()),
+		PageCount:  reader.NumPage(),
+	}
+	return info, nil
+}
+
+// cleanText: buang karakter kontrol & batasi panjang (nilai dari file upload)
+func cleanText(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return -1
+		}
+		return r
+	}, s)
+	s = strings.TrimSpace(s)
+	if runes := []rune(s); len(runes) > 200 {
+		s = string(runes[:200])
+	}
+	return s
+}
+
+// parsePDFDate: format tanggal PDF "D:YYYYMMDDHHmmSSOHH'mm'", bagian setelah tahun opsional
+func parsePDFDate(raw string) *time.Time {
+	s := strings.TrimPrefix(strings.TrimSpace(raw), "D:")
+	if len(s) < 4 {
+		return nil
+	}
+
+	// Bagian tanggal & jam: default bulan/hari 1, jam 0
+	fields := []int{0, 1, 1, 0, 0, 0}
+	widths := []int{4, 2, 2, 2, 2, 2}
+	pos := 0
+	for i, width := range widths {
+		if pos+width > len(s) || !isDigits(s[pos:pos+width]) {
+			break
+		}
+		fields[i], _ = strconv.Atoi(s[pos : pos+width])
+		pos += width
+	}
+
+	loc := time.UTC
+	if pos < len(s) {
+		switch sign := s[pos]; sign {
+		case '+', '-':
+			tz := strings.ReplaceAll(s[pos+1:], "'", "")
+			if len(tz) >= 2 && isDigits(tz[:2]) {
+				hours, _ := strconv.Atoi(tz[:2])
+				minutes := 0
+				if len(tz) >= 4 && isDigits(tz[2:4]) {
+					minutes, _ = strconv.Atoi(tz[2:4])
+				}
+				offset := hours*3600 + minutes*60
+				if sign == '-' {
+					offset = -offset
+				}
+				loc = time.FixedZone("", offset)
+			}
+		}
+	}
+
+	t := time.Date(fields[0], time.Month(fields[1]), fields[2], fields[3], fields[4], fields[5], 0, loc)
+	if t.Year() != fields[0] {
+		return nil // tanggal tidak valid (misal bulan 13)
+	}
+	return &t
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
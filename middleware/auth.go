@@ -1,38 +1,156 @@
 package middleware
 
 import (
-	"os"
 	"strings"
 
+	"reportachievement/jwtkeys"
+
 	"github.com/gofiber/fiber/v2"
-	"github.com/golang-jwt/jwt/v5"
 )
 
+// keyManager: verifikasi token (kid, algoritma, exp, iss), diset dari main
+var keyManager *jwtkeys.Manager
+
+// SetKeyManager: pakai kunci yang sama dengan AuthService untuk verifikasi token
+func SetKeyManager(m *jwtkeys.Manager) {
+	keyManager = m
+}
+
+// sessionValidator: cek apakah session (claim sid) masih aktif dan kembalikan role user saat ini
+// serta langkah akun yang masih harus diselesaikan (misal "password_change_required"), diset dari main
+var sessionValidator func(sessionID string) (role string, pending string, ok bool)
+
+// SetSessionValidator: aktifkan pengecekan pencabutan session (logout, akun dinonaktifkan/dihapus).
+// Role dari validator menggantikan claim role, sehingga pergantian role langsung berlaku.
+func SetSessionValidator(fn func(sessionID string) (role string, pending string, ok bool)) {
+	sessionValidator = fn
+}
+
+// permissionChecker: cek permission milik role (cache role -> permission), diset dari main
+var permissionChecker func(role, permission string) bool
+
+// SetPermissionChecker: sumber data untuk RequirePermission & HasPermission
+func SetPermissionChecker(fn func(role, permission string) bool) {
+	permissionChecker = fn
+}
+
+// apiKeyValidator: cek API key service account, mengembalikan ID service account & scope-nya, diset dari main
+var (
+	apiKeyPrefix    string
+	apiKeyValidator func(key, ip string) (subject string, scopes []string, ok bool)
+)
+
+// SetAPIKeyValidator: terima API key (header X-API-Key, atau Bearer yang diawali prefix) sebagai
+// alternatif JWT. Permission request dengan API key = scope key, bukan permission role.
+func SetAPIKeyValidator(prefix string, fn func(key, ip string) (subject string, scopes []string, ok bool)) {
+	apiKeyPrefix = prefix
+	apiKeyValidator = fn
+}
+
+// Protected: wajib token valid atau API key. User yang masih harus ganti password / mengaktifkan 2FA
+// ditolak (403, field "code") sampai langkah tersebut selesai.
 func Protected() fiber.Handler {
+	return protected(false)
+}
+
+// ProtectedAllowPending: seperti Protected, tetapi tetap bisa diakses user yang masih harus
+// ganti password / mengaktifkan 2FA (profil, ganti password, setup 2FA, logout).
+// Endpoint akun milik user sendiri, sehingga API key ditolak.
+func ProtectedAllowPending() fiber.Handler {
+	return protected(true)
+}
+
+func protected(allowPending bool) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		authHeader := c.Get("Authorization")
+		tokenString := strings.Replace(authHeader, "Bearer ", "", 1)
+
+		apiKey := c.Get("X-API-Key")
+		if apiKey == "" && apiKeyPrefix != "" && strings.HasPrefix(tokenString, apiKeyPrefix) {
+			apiKey = tokenString
+		}
+		if apiKey != "" {
+			return apiKeyAuth(c, apiKey, allowPending)
+		}
+
 		if authHeader == "" {
 			return c.Status(401).JSON(fiber.Map{"error": "Unauthorized: Missing token"})
 		}
 
-		tokenString := strings.Replace(authHeader, "Bearer ", "", 1)
-
-		token, err := jwt.Parse(tokenString, func(t *jwt.Token) (interface{}, error) {
-			secret := os.Getenv("JWT_SECRET")
-			if secret == "" {
-				secret = "rahasia-default-jangan-dipakai-di-prod"
-			}
-			return []byte(secret), nil
-		})
+		if keyManager == nil {
+			return c.Status(500).JSON(fiber.Map{"error": "Token verification is not configured"})
+		}
 
-		if err != nil || !token.Valid {
+		claims, err := keyManager.Parse(tokenString)
+		if err != nil {
+			return c.Status(401).JSON(fiber.Map{"error": "Unauthorized: Invalid token"})
+		}
+		// Token khusus (misal challenge 2FA) bukan access token
+		if typ, _ := claims["typ"].(string); typ != "" {
 			return c.Status(401).JSON(fiber.Map{"error": "Unauthorized: Invalid token"})
 		}
 
-		claims := token.Claims.(jwt.MapClaims)
+		// Token tanpa sid (format lama) atau dari session yang sudah dicabut ditolak
+		sessionID, _ := claims["sid"].(string)
+		role, _ := claims["role"].(string)
+		if sessionValidator != nil {
+			current, pending, ok := sessionValidator(sessionID)
+			if sessionID == "" || !ok {
+				return c.Status(401).JSON(fiber.Map{"error": "Unauthorized: Session revoked"})
+			}
+			if pending != "" && !allowPending {
+				return c.Status(403).JSON(fiber.Map{"error": "Forbidden: " + strings.ReplaceAll(pending, "_", " "), "code": pending})
+			}
+			role = current
+		}
+
 		c.Locals("user_id", claims["user_id"])
-		c.Locals("role", claims["role"])
+		c.Locals("session_id", sessionID)
+		c.Locals("role", role)
 
 		return c.Next()
 	}
 }
+
+func apiKeyAuth(c *fiber.Ctx, apiKey string, accountRoute bool) error {
+	if apiKeyValidator == nil {
+		return c.Status(401).JSON(fiber.Map{"error": "Unauthorized: API keys are not accepted"})
+	}
+	subject, scopes, ok := apiKeyValidator(apiKey, c.IP())
+	if !ok {
+		return c.Status(401).JSON(fiber.Map{"error": "Unauthorized: Invalid API key"})
+	}
+	if accountRoute {
+		return c.Status(403).JSON(fiber.Map{"error": "Forbidden: endpoint requires a user login"})
+	}
+
+	c.Locals("user_id", subject)
+	c.Locals("api_key_scopes", scopes)
+	return c.Next()
+}
+
+// RequirePermission: lanjut jika role user punya salah satu permission. Dipasang setelah Protected().
+func RequirePermission(permissions ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		for _, permission := range permissions {
+			if HasPermission(c, permission) {
+				return c.Next()
+			}
+		}
+		return c.Status(403).JSON(fiber.Map{"error": "Forbidden: missing permission " + strings.Join(permissions, " or ")})
+	}
+}
+
+// HasPermission: cek permission di handler, misal untuk menentukan cakupan data
+func HasPermission(c *fiber.Ctx, permission string) bool {
+	if scopes, ok := c.Locals("api_key_scopes").([]string); ok {
+		for _, scope := range scopes {
+			if scope == permission {
+				return true
+			}
+		}
+		return false
+	}
+	role, _ := c.Locals("role").(string)
+	return permissionChecker != nil && role != "" && permissionChecker(role, permission)
+}
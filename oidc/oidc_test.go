@@ -0,0 +1,146 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeProvider: discovery, JWKS & token endpoint minimal (pengganti Dex di unit test)
+type fakeProvider struct {
+	server   *httptest.Server
+	key      *rsa.PrivateKey
+	claims   jwt.MapClaims
+	verifier string // code_verifier terakhir yang diterima token endpoint
+}
+
+func newFakeProvider(t *testing.T) *fakeProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeProvider{key: key}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 f.server.URL,
+			"authorization_endpoint": f.server.URL + "/auth",
+			"token_endpoint":         f.server.URL + "/token",
+			"jwks_uri":               f.server.URL + "/keys",
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+			"kty": "RSA", "kid": "k1", "use": "sig", "alg": "RS256",
+			"n": base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		f.verifier = r.PostForm.Get("code_verifier")
+		if id, secret, _ := r.BasicAuth(); id != "prestasi" || secret != "rahasia" || r.PostForm.Get("code") != "kode-valid" {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"access_token": "x", "id_token": f.sign(t, f.claims)})
+	})
+	f.server = httptest.NewServer(mux)
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+func (f *fakeProvider) sign(t *testing.T, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "k1"
+	signed, err := token.SignedString(f.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestProvider_CodeFlow(t *testing.T) {
+	f := newFakeProvider(t)
+	p := NewProvider(Options{Issuer: f.server.URL, ClientID: "prestasi", ClientSecret: "rahasia", RedirectURL: "http://localhost:3000/cb"})
+	ctx := context.Background()
+
+	verifier, challenge, err := NewPKCE()
+	assert.NoError(t, err)
+	sum := sha256.Sum256([]byte(verifier))
+	assert.Equal(t, base64.RawURLEncoding.EncodeToString(sum[:]), challenge)
+
+	authURL, err := p.AuthCodeURL(ctx, "state-1", "nonce-1", challenge)
+	assert.NoError(t, err)
+	u, _ := url.Parse(authURL)
+	assert.Equal(t, "S256", u.Query().Get("code_challenge_method"))
+	assert.Equal(t, "openid email profile", u.Query().Get("scope"))
+
+	f.claims = jwt.MapClaims{
+		"iss": f.server.URL, "aud": "prestasi", "sub": "dex|dosen1", "nonce": "nonce-1",
+		"email": "dosen1@unair.ac.id", "email_verified": true, "preferred_username": "dosen1",
+		"exp": time.Now().Add(time.Minute).Unix(),
+	}
+	claims, err := p.Exchange(ctx, "kode-valid", verifier, "nonce-1")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "dex|dosen1", claims.Subject)
+		assert.Equal(t, "dosen1@unair.ac.id", claims.Email)
+		assert.True(t, claims.EmailVerified)
+		assert.Equal(t, "dosen1", claims.PreferredUsername)
+	}
+	assert.Equal(t, verifier, f.verifier)
+
+	// Nonce lain (replay dari login berbeda) ditolak
+	_, err = p.Exchange(ctx, "kode-valid", verifier, "nonce-lain")
+	assert.ErrorIs(t, err, ErrInvalidIDToken)
+
+	// Code tidak valid
+	_, err = p.Exchange(ctx, "kode-salah", verifier, "nonce-1")
+	assert.Error(t, err)
+}
+
+func TestProvider_VerifyIDToken_Rejects(t *testing.T) {
+	f := newFakeProvider(t)
+	p := NewProvider(Options{Issuer: f.server.URL, ClientID: "prestasi"})
+	ctx := context.Background()
+	base := func() jwt.MapClaims {
+		return jwt.MapClaims{"iss": f.server.URL, "aud": "prestasi", "sub": "u1", "exp": time.Now().Add(time.Minute).Unix()}
+	}
+
+	_, err := p.VerifyIDToken(ctx, f.sign(t, base()), "")
+	assert.NoError(t, err)
+
+	wrongAud := base()
+	wrongAud["aud"] = "client-lain"
+	_, err = p.VerifyIDToken(ctx, f.sign(t, wrongAud), "")
+	assert.ErrorIs(t, err, ErrInvalidIDToken)
+
+	wrongIss := base()
+	wrongIss["iss"] = "http://evil.example"
+	_, err = p.VerifyIDToken(ctx, f.sign(t, wrongIss), "")
+	assert.ErrorIs(t, err, ErrInvalidIDToken)
+
+	expired := base()
+	expired["exp"] = time.Now().Add(-time.Hour).Unix()
+	_, err = p.VerifyIDToken(ctx, f.sign(t, expired), "")
+	assert.ErrorIs(t, err, ErrInvalidIDToken)
+
+	// HS256 dengan secret sembarang tidak pernah diterima
+	hs := jwt.NewWithClaims(jwt.SigningMethodHS256, base())
+	hs.Header["kid"] = "k1"
+	signed, _ := hs.SignedString([]byte("apa saja"))
+	_, err = p.VerifyIDToken(ctx, signed, "")
+	assert.ErrorIs(t, err, ErrInvalidIDToken)
+}
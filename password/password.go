@@ -0,0 +1,138 @@
+package password
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"reportachievement/config"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrTooShort         = errors.New("password is too short")
+	ErrTooLong          = errors.New("password is too long")
+	ErrCommon           = errors.New("password is too common or known to be breached")
+	ErrContainsIdentity = errors.New("password must not contain the username or email")
+)
+
+// IsPolicyError: error karena password tidak memenuhi policy (input user, bukan kesalahan server)
+func IsPolicyError(err error) bool {
+	return errors.Is(err, ErrTooShort) || errors.Is(err, ErrTooLong) || errors.Is(err, ErrCommon) || errors.Is(err, ErrContainsIdentity)
+}
+
+// bcrypt hanya memakai 72 byte pertama
+const maxBytes = 72
+
+type Options struct {
+	MinLength     int
+	BlocklistFile string // satu password per baris, baris "#" = komentar, kosong = tanpa blocklist
+	Cost          int    // bcrypt cost, dinaikkan = hash lama di-upgrade saat login
+}
+
+// Policy: validasi password baru & hashing bcrypt dengan cost yang dikonfigurasi
+type Policy struct {
+	minLength int
+	cost      int
+	blocked   map[string]struct{}
+}
+
+// New: bangun Policy dari config. Blocklist yang tidak bisa dibaca hanya di-log (policy panjang tetap berlaku).
+func New(cfg *config.Config) *Policy {
+	opts := Options{MinLength: cfg.PasswordMinLength, BlocklistFile: cfg.PasswordBlocklistFile, Cost: cfg.BcryptCost}
+	p, err := NewPolicy(opts)
+	if err != nil {
+		log.Println("⚠️ Blocklist password tidak dimuat:", err)
+		opts.BlocklistFile = ""
+		p, err = NewPolicy(opts)
+		if err != nil {
+			log.Fatal("❌ Password policy tidak valid:", err)
+		}
+	}
+	log.Printf("✅ Password policy: min %d karakter, %d password umum diblokir, bcrypt cost %d", p.minLength, len(p.blocked), p.cost)
+	return p
+}
+
+func NewPolicy(opts Options) (*Policy, error) {
+	if opts.Cost == 0 {
+		opts.Cost = bcrypt.DefaultCost
+	}
+	if opts.Cost < bcrypt.MinCost || opts.Cost > bcrypt.MaxCost {
+		return nil, fmt.Errorf("bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+	}
+	if opts.MinLength < 1 {
+		opts.MinLength = 8
+	}
+
+	p := &Policy{minLength: opts.MinLength, cost: opts.Cost, blocked: map[string]struct{}{}}
+	if opts.BlocklistFile != "" {
+		if err := p.loadBlocklist(opts.BlocklistFile); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+func (p *Policy) loadBlocklist(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		p.blocked[strings.ToLower(line)] = struct{}{}
+	}
+	return scanner.Err()
+}
+
+func (p *Policy) MinLength() int {
+	return p.minLength
+}
+
+// Validate: cek password baru. identities = username/email pemilik akun, tidak boleh menjadi bagian password.
+func (p *Policy) Validate(password string, identities ...string) error {
+	if utf8.RuneCountInString(password) < p.minLength {
+		return fmt.Errorf("%w: minimum %d characters", ErrTooShort, p.minLength)
+	}
+	if len(password) > maxBytes {
+		return fmt.Errorf("%w: maximum %d bytes", ErrTooLong, maxBytes)
+	}
+
+	lower := strings.ToLower(password)
+	if _, found := p.blocked[lower]; found {
+		return ErrCommon
+	}
+	for _, identity := range identities {
+		identity = strings.ToLower(strings.TrimSpace(identity))
+		if at := strings.IndexByte(identity, '@'); at >= 0 {
+			identity = identity[:at] // bagian lokal email
+		}
+		if len(identity) >= 3 && strings.Contains(lower, identity) {
+			return ErrContainsIdentity
+		}
+	}
+	return nil
+}
+
+// Hash: bcrypt dengan cost dari config
+func (p *Policy) Hash(password string) (string, error) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), p.cost)
+	return string(hashed), err
+}
+
+// NeedsRehash: hash dibuat dengan cost lebih rendah dari config (atau tidak bisa dibaca)
+func (p *Policy) NeedsRehash(hash string) bool {
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err != nil || cost < p.cost
+}
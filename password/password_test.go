@@ -0,0 +1,44 @@
+package password
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestPolicy_Validate(t *testing.T) {
+	blocklist := filepath.Join(t.TempDir(), "common.txt")
+	os.WriteFile(blocklist, []byte("# komentar\n123456\nPassword123\n\nbismillah\n"), 0o644)
+
+	p, err := NewPolicy(Options{MinLength: 8, BlocklistFile: blocklist, Cost: bcrypt.MinCost})
+	assert.NoError(t, err)
+
+	assert.ErrorIs(t, p.Validate("1234567"), ErrTooShort)
+	assert.ErrorIs(t, p.Validate("password123"), ErrCommon) // blocklist tidak case-sensitive
+	assert.ErrorIs(t, p.Validate("BISMILLAH"), ErrCommon)
+	assert.ErrorIs(t, p.Validate(string(make([]byte, 73))), ErrTooLong)
+	assert.ErrorIs(t, p.Validate("mhs1-rahasia!", "mhs1", "mhs1@unair.ac.id"), ErrContainsIdentity)
+	assert.ErrorIs(t, p.Validate("xx-dosen.wali-xx", "dw", "dosen.wali@unair.ac.id"), ErrContainsIdentity)
+	assert.NoError(t, p.Validate("kuda-lari-pagi-7", "mhs1", "mhs1@unair.ac.id"))
+}
+
+func TestPolicy_MissingBlocklist(t *testing.T) {
+	_, err := NewPolicy(Options{BlocklistFile: filepath.Join(t.TempDir(), "tidak-ada.txt")})
+	assert.Error(t, err)
+}
+
+func TestPolicy_HashAndRehash(t *testing.T) {
+	low, _ := NewPolicy(Options{Cost: bcrypt.MinCost})
+	high, _ := NewPolicy(Options{Cost: bcrypt.MinCost + 1})
+
+	hash, err := low.Hash("kuda-lari-pagi-7")
+	assert.NoError(t, err)
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(hash), []byte("kuda-lari-pagi-7")))
+
+	assert.False(t, low.NeedsRehash(hash))
+	assert.True(t, high.NeedsRehash(hash))
+	assert.True(t, high.NeedsRehash("bukan-hash"))
+}
@@ -1,14 +1,14 @@
 package postgre
 
 import (
+	"errors"
 	"fmt"
-	"path/filepath"
+	"io"
 	"reportachievement/app/repository/postgre"
 	"reportachievement/app/service"
 	"reportachievement/helper"
 	"reportachievement/middleware"
 	"strconv"
-	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
@@ -22,13 +22,21 @@ func RegisterAchievementRoutes(app *fiber.App, achievementService *service.Achie
 	h := &AchievementHandler{Service: achievementService}
 	api := app.Group("/api/v1/achievements")
 
-	api.Post("/", middleware.Protected(), h.Create)
-	api.Get("/", middleware.Protected(), h.GetList)
-	api.Delete("/:id", middleware.Protected(), h.Delete)
-	api.Post("/:id/submit", middleware.Protected(), h.Submit)
-	api.Post("/:id/verify", middleware.Protected(), h.Verify)
-	api.Post("/:id/reject", middleware.Protected(), h.Reject)
-	api.Post("/:id/attachments", middleware.Protected(), h.UploadEvidence)
+	api.Use(middleware.Protected())
+
+	canWrite := middleware.RequirePermission("achievement:create")
+	canVerify := middleware.RequirePermission("achievement:verify")
+	canRead := middleware.RequirePermission("achievement:read_own", "achievement:read_advisee", "achievement:read_all")
+
+	api.Post("/", canWrite, h.Create)
+	api.Get("/", canRead, h.GetList)
+	api.Delete("/:id", canWrite, h.Delete)
+	api.Post("/:id/submit", canWrite, h.Submit)
+	api.Post("/:id/verify", canVerify, h.Verify)
+	api.Post("/:id/reject", canVerify, h.Reject)
+	api.Post("/:id/attachments", canWrite, h.UploadEvidence)
+	api.Put("/:id/attachments/:attachmentId", canWrite, h.ReplaceEvidence)
+	api.Delete("/:id/attachments/:attachmentId", canWrite, h.DeleteEvidence)
 }
 
 func getUserID(c *fiber.Ctx) (uuid.UUID, error) {
@@ -39,6 +47,13 @@ func getUserID(c *fiber.Ctx) (uuid.UUID, error) {
 	return uuid.Parse(fmt.Sprintf("%v", claims))
 }
 
+// accessScope: cakupan data yang boleh dilihat user berdasarkan permission role-nya
+func accessScope(c *fiber.Ctx) service.AccessScope {
+	return service.ScopeFromPermissions(func(permission string) bool {
+		return middleware.HasPermission(c, permission)
+	})
+}
+
 func (h *AchievementHandler) Create(c *fiber.Ctx) error {
 	userID, err := getUserID(c)
 	if err != nil {
@@ -63,8 +78,8 @@ func (h *AchievementHandler) GetList(c *fiber.Ctx) error {
 		return helper.Error(c, 401, "Unauthorized")
 	}
 
-	// 2. Ambil Role dari Token (Diset di Middleware)
-	role := c.Locals("role").(string)
+	// 2. Cakupan data dari permission role (Diset di Middleware)
+	scope := accessScope(c)
 
 	page, _ := strconv.Atoi(c.Query("page", "1"))
 	limit, _ := strconv.Atoi(c.Query("limit", "10"))
@@ -72,8 +87,8 @@ func (h *AchievementHandler) GetList(c *fiber.Ctx) error {
 
 	filter := postgre.AchievementFilter{Page: page, Limit: limit, Status: status}
 
-	// 3. Panggil Service dengan UserID dan Role
-	data, total, err := h.Service.GetAll(c.Context(), userID, role, filter)
+	// 3. Panggil Service dengan UserID dan cakupan
+	data, total, err := h.Service.GetAll(c.Context(), userID, scope, filter)
 	if err != nil {
 		return helper.Error(c, 500, err.Error())
 	}
@@ -144,19 +159,78 @@ func (h *AchievementHandler) UploadEvidence(c *fiber.Ctx) error {
 		return helper.Error(c, 401, err.Error())
 	}
 	achID, _ := uuid.Parse(c.Params("id"))
-	file, err := c.FormFile("file")
+	input, err := evidenceFromForm(c)
 	if err != nil {
-		return helper.Error(c, 400, "File required")
+		return helper.Error(c, 400, err.Error())
 	}
-	filename := fmt.Sprintf("%d_%s", time.Now().Unix(), file.Filename)
-	savePath := filepath.Join("./uploads", filename)
-	if err := c.SaveFile(file, savePath); err != nil {
-		return helper.Error(c, 500, "Save failed")
+	defer input.Content.(io.Closer).Close()
+
+	dto, err := h.Service.UploadEvidence(c.Context(), userID, achID, input)
+	if err != nil {
+		return evidenceError(c, err)
+	}
+	return helper.Success(c, 200, "Upload Success", dto)
+}
+
+func (h *AchievementHandler) ReplaceEvidence(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return helper.Error(c, 401, err.Error())
 	}
-	fileURL := fmt.Sprintf("http://localhost:3000/uploads/%s", filename)
-	dto := service.AttachmentDTO{FileName: file.Filename, FileURL: fileURL, FileType: file.Header.Get("Content-Type")}
-	if err := h.Service.UploadEvidence(c.Context(), userID, achID, dto); err != nil {
+	achID, _ := uuid.Parse(c.Params("id"))
+	input, err := evidenceFromForm(c)
+	if err != nil {
 		return helper.Error(c, 400, err.Error())
 	}
-	return helper.Success(c, 200, "Upload Success", dto)
+	defer input.Content.(io.Closer).Close()
+
+	dto, err := h.Service.ReplaceAttachment(c.Context(), userID, achID, c.Params("attachmentId"), input)
+	if err != nil {
+		return evidenceError(c, err)
+	}
+	return helper.Success(c, 200, "Attachment replaced", dto)
+}
+
+func (h *AchievementHandler) DeleteEvidence(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return helper.Error(c, 401, err.Error())
+	}
+	achID, _ := uuid.Parse(c.Params("id"))
+	if err := h.Service.DeleteAttachment(c.Context(), userID, achID, c.Params("attachmentId")); err != nil {
+		return evidenceError(c, err)
+	}
+	return helper.Success(c, 200, "Attachment deleted", nil)
+}
+
+// evidenceFromForm: ambil field multipart "file" (Content wajib di-Close oleh pemanggil)
+func evidenceFromForm(c *fiber.Ctx) (service.EvidenceFile, error) {
+	file, err := c.FormFile("file")
+	if err != nil {
+		return service.EvidenceFile{}, errors.New("File required")
+	}
+	content, err := file.Open()
+	if err != nil {
+		return service.EvidenceFile{}, errors.New("Failed to read file")
+	}
+	return service.EvidenceFile{
+		FileName:    file.Filename,
+		ContentType: file.Header.Get("Content-Type"),
+		Size:        file.Size,
+		Content:     content,
+	}, nil
+}
+
+func evidenceError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, service.ErrFileTooLarge):
+		return helper.Error(c, 413, err.Error())
+	case errors.Is(err, service.ErrUnsupportedFileType):
+		return helper.Error(c, 415, err.Error())
+	case errors.Is(err, service.ErrAttachmentNotFound):
+		return helper.Error(c, 404, err.Error())
+	case errors.Is(err, service.ErrEvidenceRejected):
+		return helper.Error(c, 422, err.Error())
+	}
+	return helper.Error(c, 400, err.Error())
 }
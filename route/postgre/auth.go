@@ -1,17 +1,21 @@
 package postgre
 
 import (
+	"errors"
 	"fmt"
+	"math"
 	"reportachievement/app/service"
 	"reportachievement/helper"
 	"reportachievement/middleware"
+	"strconv"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 )
 
 type AuthHandler struct {
-	Service *service.AuthService
+	Service         *service.AuthService
+	PasswordService *service.PasswordService
 }
 
 type LoginRequest struct {
@@ -19,26 +23,57 @@ type LoginRequest struct {
 	Password string `json:"password" example:"admin123"`
 }
 
-func RegisterAuthRoutes(app *fiber.App, authService *service.AuthService) {
-	h := &AuthHandler{Service: authService}
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" example:"mhs1@unair.ac.id"`
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
+func RegisterAuthRoutes(app *fiber.App, authService *service.AuthService, passwordService *service.PasswordService) {
+	h := &AuthHandler{Service: authService, PasswordService: passwordService}
 	api := app.Group("/api/v1/auth")
 
 	api.Post("/login", h.Login)
+	api.Post("/refresh", h.Refresh)
 
 	// --- TAMBAHAN BARU ---
-	api.Get("/profile", middleware.Protected(), h.GetProfile) // Butuh Token
-	api.Post("/logout", h.Logout)                             // Logout (Stateless)
+	// Tetap bisa diakses selama user wajib ganti password / mengaktifkan 2FA
+	pending := middleware.ProtectedAllowPending()
+	api.Get("/profile", pending, h.GetProfile)            // Butuh Token
+	api.Post("/logout", pending, h.Logout)                // Logout (cabut session saat ini)
+	api.Post("/logout-all", pending, h.LogoutAll)         // Logout dari semua perangkat
+	api.Get("/sessions", pending, h.ListSessions)         // Daftar perangkat yang sedang login
+	api.Delete("/sessions/:id", pending, h.RevokeSession) // Logout satu perangkat
+
+	// Password
+	api.Put("/password", pending, h.ChangePassword)
+	api.Post("/forgot-password", h.ForgotPassword)
+	api.Post("/reset-password", h.ResetPassword)
 }
 
 // Login godoc
 // @Summary      Login User
-// @Description  Authenticate user and get JWT token
+// @Description  Authenticate user and get a short-lived JWT access token plus a refresh token. When mfa_required is true, only a challenge_token is returned: finish with /auth/login/2fa. When must_change_password or mfa_enrollment_required is true, other endpoints return 403 until done.
 // @Tags         Auth
 // @Accept       json
 // @Produce      json
 // @Param        request body LoginRequest true "Login Credentials"
 // @Success      200  {object} helper.APIResponse
 // @Failure      400  {object} helper.APIResponse
+// @Failure      429  {object} helper.APIResponse "Too many failed attempts (see Retry-After)"
+// @Failure      503  {object} helper.APIResponse "LDAP directory unreachable"
 // @Router       /api/v1/auth/login [post]
 func (h *AuthHandler) Login(c *fiber.Ctx) error {
 	var req LoginRequest
@@ -46,14 +81,42 @@ func (h *AuthHandler) Login(c *fiber.Ctx) error {
 		return helper.Error(c, 400, "Invalid request body")
 	}
 
-	resp, err := h.Service.Login(req.Username, req.Password)
+	client := service.ClientInfo{UserAgent: c.Get(fiber.HeaderUserAgent), IPAddress: c.IP()}
+	resp, err := h.Service.Login(req.Username, req.Password, client)
 	if err != nil {
-		return helper.Error(c, 401, err.Error())
+		return loginError(c, err)
+	}
+	if resp["mfa_required"] == true {
+		return helper.Success(c, 200, "2FA code required", resp)
 	}
 
 	return helper.Success(c, 200, "Login successful", resp)
 }
 
+// Refresh godoc
+// @Summary      Refresh Token
+// @Description  Exchange a refresh token for a new access token and refresh token (rotation). Reusing an old refresh token revokes the session.
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Param        request body RefreshRequest true "Refresh Token"
+// @Success      200  {object} helper.APIResponse
+// @Failure      401  {object} helper.APIResponse
+// @Router       /api/v1/auth/refresh [post]
+func (h *AuthHandler) Refresh(c *fiber.Ctx) error {
+	var req RefreshRequest
+	if err := c.BodyParser(&req); err != nil || req.RefreshToken == "" {
+		return helper.Error(c, 400, "refresh_token is required")
+	}
+
+	resp, err := h.Service.Refresh(req.RefreshToken)
+	if err != nil {
+		return helper.Error(c, 401, err.Error())
+	}
+
+	return helper.Success(c, 200, "Token refreshed", resp)
+}
+
 // GetProfile godoc
 // @Summary      Get User Profile
 // @Description  Get currently logged in user profile
@@ -87,14 +150,185 @@ func (h *AuthHandler) GetProfile(c *fiber.Ctx) error {
 
 // Logout godoc
 // @Summary      Logout User
-// @Description  Logout user (client side clear token)
+// @Description  Revoke the current session: the access token and its refresh token stop working immediately
 // @Tags         Auth
 // @Accept       json
 // @Produce      json
+// @Security     BearerAuth
 // @Success      200  {object} helper.APIResponse
+// @Failure      401  {object} helper.APIResponse
 // @Router       /api/v1/auth/logout [post]
 func (h *AuthHandler) Logout(c *fiber.Ctx) error {
-	// Karena pakai JWT (Stateless), server hanya perlu kirim respon OK.
-	// Client yang bertugas menghapus token dari local storage.
+	sessionID, err := uuid.Parse(fmt.Sprintf("%v", c.Locals("session_id")))
+	if err != nil {
+		return helper.Error(c, 401, "Unauthorized")
+	}
+
+	if err := h.Service.Logout(sessionID); err != nil {
+		return helper.Error(c, 500, err.Error())
+	}
 	return helper.Success(c, 200, "Successfully logged out", nil)
 }
+
+// LogoutAll godoc
+// @Summary      Logout All Devices
+// @Description  Revoke every session of the current user, including the one making this request
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object} helper.APIResponse
+// @Failure      401  {object} helper.APIResponse
+// @Router       /api/v1/auth/logout-all [post]
+func (h *AuthHandler) LogoutAll(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(fmt.Sprintf("%v", c.Locals("user_id")))
+	if err != nil {
+		return helper.Error(c, 401, "Unauthorized")
+	}
+
+	if err := h.Service.LogoutAll(userID); err != nil {
+		return helper.Error(c, 500, err.Error())
+	}
+	return helper.Success(c, 200, "Logged out from all devices", nil)
+}
+
+// ListSessions godoc
+// @Summary      List Active Sessions
+// @Description  Devices where the current user is logged in (device, IP, user agent, created, last seen). The session making this request has current=true.
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object} helper.APIResponse
+// @Failure      401  {object} helper.APIResponse
+// @Router       /api/v1/auth/sessions [get]
+func (h *AuthHandler) ListSessions(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return helper.Error(c, 401, "Unauthorized")
+	}
+	sessionID, _ := uuid.Parse(fmt.Sprintf("%v", c.Locals("session_id")))
+
+	sessions, err := h.Service.ListSessions(userID, sessionID)
+	if err != nil {
+		return helper.Error(c, 500, err.Error())
+	}
+	return helper.Success(c, 200, "Active sessions", sessions)
+}
+
+// RevokeSession godoc
+// @Summary      Revoke Session
+// @Description  Sign out one of the current user's devices. Its access token and refresh token stop working immediately. Revoking the current session is the same as logout.
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      string  true  "Session ID"
+// @Success      200  {object} helper.APIResponse
+// @Failure      404  {object} helper.APIResponse
+// @Router       /api/v1/auth/sessions/{id} [delete]
+func (h *AuthHandler) RevokeSession(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return helper.Error(c, 401, "Unauthorized")
+	}
+	sessionID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return helper.Error(c, 400, "Invalid session ID")
+	}
+
+	if err := h.Service.RevokeUserSession(userID, sessionID, "revoked by user"); err != nil {
+		return sessionError(c, err)
+	}
+	return helper.Success(c, 200, "Session revoked", nil)
+}
+
+// ChangePassword godoc
+// @Summary      Change Password
+// @Description  Change own password (current password required). Other sessions are revoked, this one stays logged in.
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request body ChangePasswordRequest true "Current & New Password"
+// @Success      200  {object} helper.APIResponse
+// @Failure      400  {object} helper.APIResponse
+// @Router       /api/v1/auth/password [put]
+func (h *AuthHandler) ChangePassword(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return helper.Error(c, 401, "Unauthorized")
+	}
+	sessionID, _ := uuid.Parse(fmt.Sprintf("%v", c.Locals("session_id")))
+
+	var req ChangePasswordRequest
+	if err := c.BodyParser(&req); err != nil || req.CurrentPassword == "" || req.NewPassword == "" {
+		return helper.Error(c, 400, "current_password and new_password are required")
+	}
+	if err := h.PasswordService.ChangePassword(userID, sessionID, req.CurrentPassword, req.NewPassword); err != nil {
+		return helper.Error(c, 400, err.Error())
+	}
+	return helper.Success(c, 200, "Password changed", nil)
+}
+
+// ForgotPassword godoc
+// @Summary      Forgot Password
+// @Description  Send a single-use reset link to the account email. Always returns 200 so registered emails cannot be discovered.
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Param        request body ForgotPasswordRequest true "Account Email"
+// @Success      200  {object} helper.APIResponse
+// @Router       /api/v1/auth/forgot-password [post]
+func (h *AuthHandler) ForgotPassword(c *fiber.Ctx) error {
+	var req ForgotPasswordRequest
+	if err := c.BodyParser(&req); err != nil || req.Email == "" {
+		return helper.Error(c, 400, "email is required")
+	}
+	if err := h.PasswordService.RequestReset(req.Email, c.IP()); err != nil {
+		return helper.Error(c, 500, "Failed to process request")
+	}
+	return helper.Success(c, 200, "If the email is registered, a reset link has been sent", nil)
+}
+
+// ResetPassword godoc
+// @Summary      Reset Password
+// @Description  Set a new password using the token from the reset email. All sessions of the account are revoked.
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Param        request body ResetPasswordRequest true "Reset Token & New Password"
+// @Success      200  {object} helper.APIResponse
+// @Failure      400  {object} helper.APIResponse
+// @Router       /api/v1/auth/reset-password [post]
+func (h *AuthHandler) ResetPassword(c *fiber.Ctx) error {
+	var req ResetPasswordRequest
+	if err := c.BodyParser(&req); err != nil || req.Token == "" || req.NewPassword == "" {
+		return helper.Error(c, 400, "token and new_password are required")
+	}
+	if err := h.PasswordService.ResetPassword(req.Token, req.NewPassword); err != nil {
+		return helper.Error(c, 400, err.Error())
+	}
+	return helper.Success(c, 200, "Password has been reset, please login again", nil)
+}
+
+// loginError: 429 + Retry-After untuk percobaan yang dibatasi, 503 jika server LDAP tidak bisa dihubungi, selain itu 401
+func loginError(c *fiber.Ctx, err error) error {
+	var throttled *service.LoginThrottledError
+	if errors.As(err, &throttled) {
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+		return helper.Error(c, 429, err.Error())
+	}
+	if errors.Is(err, service.ErrAuthProviderUnavailable) {
+		return helper.Error(c, 503, err.Error())
+	}
+	return helper.Error(c, 401, err.Error())
+}
+
+// sessionError: 404 untuk session yang bukan milik user / sudah tidak aktif
+func sessionError(c *fiber.Ctx, err error) error {
+	if errors.Is(err, service.ErrSessionNotFound) {
+		return helper.Error(c, 404, err.Error())
+	}
+	return helper.Error(c, 500, err.Error())
+}
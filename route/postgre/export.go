@@ -0,0 +1,79 @@
+package postgre
+
+import (
+	"errors"
+	"reportachievement/app/service"
+	"reportachievement/helper"
+	"reportachievement/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type ExportHandler struct {
+	Service *service.ExportService
+}
+
+// Export bundle zip untuk akreditasi (permission export:manage), diproses di background
+func RegisterExportRoutes(app *fiber.App, exportService *service.ExportService) {
+	h := &ExportHandler{Service: exportService}
+	api := app.Group("/api/v1/exports")
+	api.Use(middleware.Protected(), middleware.RequirePermission("export:manage"))
+
+	api.Post("/", h.Create) // Body: {"program_study": "...", "from": "2024-01-01", "to": "2024-12-31"}
+	api.Get("/", h.List)
+	api.Get("/:id", h.Get)
+	api.Get("/:id/download", h.Download)
+}
+
+func (h *ExportHandler) Create(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return helper.Error(c, 401, "Unauthorized")
+	}
+	var req service.ExportRequest
+	if err := c.BodyParser(&req); err != nil {
+		return helper.Error(c, 400, "Invalid JSON")
+	}
+	job, err := h.Service.CreateExport(c.Context(), userID, req)
+	if err != nil {
+		return helper.Error(c, 400, err.Error())
+	}
+	return helper.Success(c, 202, "Export queued", job)
+}
+
+func (h *ExportHandler) List(c *fiber.Ctx) error {
+	jobs, err := h.Service.ListExports(c.Context())
+	if err != nil {
+		return helper.Error(c, 500, err.Error())
+	}
+	return helper.Success(c, 200, "List Exports", jobs)
+}
+
+func (h *ExportHandler) Get(c *fiber.Ctx) error {
+	job, err := h.Service.GetExport(c.Context(), c.Params("id"))
+	if err != nil {
+		return exportError(c, err)
+	}
+	return helper.Success(c, 200, "Export Detail", job)
+}
+
+func (h *ExportHandler) Download(c *fiber.Ctx) error {
+	job, content, err := h.Service.OpenExport(c.Context(), c.Params("id"))
+	if err != nil {
+		return exportError(c, err)
+	}
+	c.Set(fiber.HeaderContentType, "application/zip")
+	c.Set(fiber.HeaderContentDisposition, contentDisposition("attachment", service.ExportFileName(job)))
+	c.Set(fiber.HeaderCacheControl, "private, no-store")
+	return c.SendStream(content)
+}
+
+func exportError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, service.ErrExportNotFound):
+		return helper.Error(c, 404, err.Error())
+	case errors.Is(err, service.ErrExportNotReady):
+		return helper.Error(c, 409, err.Error())
+	}
+	return helper.Error(c, 500, err.Error())
+}
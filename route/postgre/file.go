@@ -0,0 +1,125 @@
+package postgre
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"reportachievement/app/service"
+	"reportachievement/helper"
+	"reportachievement/middleware"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type FileHandler struct {
+	Service *service.AchievementService
+}
+
+// Download evidence menggantikan app.Static("/uploads")
+func RegisterFileRoutes(app *fiber.App, achievementService *service.AchievementService) {
+	h := &FileHandler{Service: achievementService}
+	api := app.Group("/api/v1/files")
+
+	api.Post("/sign", middleware.Protected(), h.Sign) // Body: {"key": "..."}
+	api.Get("/*", h.SignedDownload, middleware.Protected(), h.Download)
+}
+
+func fileKeyParam(c *fiber.Ctx) string {
+	key := c.Params("*")
+	if unescaped, err := url.PathUnescape(key); err == nil {
+		key = unescaped
+	}
+	return key
+}
+
+// SignedDownload: jika ada ?signature= (signed URL untuk embed) file langsung dikirim
+// tanpa header Authorization, selain itu lanjut ke Protected() + Download.
+func (h *FileHandler) SignedDownload(c *fiber.Ctx) error {
+	signature := c.Query("signature")
+	if signature == "" {
+		return c.Next()
+	}
+	file, err := h.Service.OpenSignedAttachment(c.Context(), fileKeyParam(c), c.Query("expires"), signature)
+	if err != nil {
+		return fileError(c, err)
+	}
+	return sendAttachment(c, file)
+}
+
+func (h *FileHandler) Download(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return helper.Error(c, 401, "Unauthorized")
+	}
+	file, err := h.Service.OpenAttachment(c.Context(), userID, accessScope(c), fileKeyParam(c))
+	if err != nil {
+		return fileError(c, err)
+	}
+	return sendAttachment(c, file)
+}
+
+func (h *FileHandler) Sign(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return helper.Error(c, 401, "Unauthorized")
+	}
+	var req struct {
+		Key string `json:"key"`
+	}
+	if err := c.BodyParser(&req); err != nil || req.Key == "" {
+		return helper.Error(c, 400, "key is required")
+	}
+
+	signedURL, expiresAt, err := h.Service.SignAttachmentURL(c.Context(), userID, accessScope(c), req.Key)
+	if err != nil {
+		return fileError(c, err)
+	}
+	return helper.Success(c, 200, "Signed URL created", fiber.Map{"url": signedURL, "expires_at": expiresAt})
+}
+
+// sendAttachment: stream file dengan Content-Type & Content-Disposition yang benar.
+// ?inline=1 untuk ditampilkan di browser, default sebagai download. Tipe diambil dari isi file;
+// selain PDF/gambar/video di allowlist evidence (misal HTML/SVG dari upload lama) selalu dikirim
+// sebagai download octet-stream agar tidak dirender di origin API.
+func sendAttachment(c *fiber.Ctx, file *service.AttachmentFile) error {
+	contentType, allowed := file.Sniff()
+	disposition := "attachment"
+	if !allowed {
+		contentType = fiber.MIMEOctetStream
+	} else if c.QueryBool("inline") {
+		disposition = "inline"
+	}
+
+	c.Set(fiber.HeaderContentType, contentType)
+	c.Set(fiber.HeaderContentDisposition, contentDisposition(disposition, file.Attachment.FileName))
+	c.Set(fiber.HeaderXContentTypeOptions, "nosniff")
+	c.Set(fiber.HeaderCacheControl, "private, no-store")
+	return c.SendStream(file.Content) // Content ditutup oleh fasthttp setelah terkirim
+}
+
+func contentDisposition(disposition, filename string) string {
+	fallback := strings.Map(func(r rune) rune {
+		if r < 0x20 || r > 0x7e || r == '"' || r == '\\' {
+			return '_'
+		}
+		return r
+	}, filename)
+	return fmt.Sprintf(`%s; filename="%s"; filename*=UTF-8''%s`, disposition, fallback, url.PathEscape(filename))
+}
+
+func fileError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, service.ErrAttachmentNotFound):
+		return helper.Error(c, 404, err.Error())
+	case errors.Is(err, service.ErrAccessDenied):
+		return helper.Error(c, 403, err.Error())
+	case errors.Is(err, service.ErrInvalidSignature):
+		return helper.Error(c, 403, err.Error())
+	case errors.Is(err, service.ErrAttachmentPending):
+		return helper.Error(c, 409, err.Error())
+	case errors.Is(err, service.ErrEvidenceRejected):
+		return helper.Error(c, 403, err.Error())
+	}
+	return helper.Error(c, 500, err.Error())
+}
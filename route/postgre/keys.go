@@ -0,0 +1,68 @@
+package postgre
+
+import (
+	"reportachievement/helper"
+	"reportachievement/jwtkeys"
+	"reportachievement/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type KeyHandler struct {
+	Keys *jwtkeys.Manager
+}
+
+func RegisterKeyRoutes(app *fiber.App, keys *jwtkeys.Manager) {
+	h := &KeyHandler{Keys: keys}
+
+	// Publik: service kampus lain memverifikasi token kita lewat JWKS
+	app.Get("/.well-known/jwks.json", h.JWKS)
+
+	api := app.Group("/api/v1/auth/keys")
+	api.Use(middleware.Protected(), middleware.RequirePermission("auth:manage_keys"))
+	api.Get("/", h.List)
+	api.Post("/rotate", h.Rotate)
+}
+
+// JWKS godoc
+// @Summary      JSON Web Key Set
+// @Description  Public keys for verifying access tokens (RS256/EdDSA). Empty when tokens are signed with HS256.
+// @Tags         Auth
+// @Produce      json
+// @Success      200  {object} jwtkeys.JWKSet
+// @Router       /.well-known/jwks.json [get]
+func (h *KeyHandler) JWKS(c *fiber.Ctx) error {
+	// Cache pendek: kunci berikutnya sudah dipublikasikan selama masa overlap sebelum dipakai
+	c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+	return c.JSON(h.Keys.JWKS())
+}
+
+// List godoc
+// @Summary      List Signing Keys
+// @Description  Key IDs, algorithm and creation time of keys currently accepted (permission auth:manage_keys)
+// @Tags         Auth
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object} helper.APIResponse
+// @Failure      403  {object} helper.APIResponse
+// @Router       /api/v1/auth/keys [get]
+func (h *KeyHandler) List(c *fiber.Ctx) error {
+	return helper.Success(c, 200, "Signing keys", h.Keys.Keys())
+}
+
+// Rotate godoc
+// @Summary      Rotate Signing Key
+// @Description  Generate a new signing key now (permission auth:manage_keys). The key is published immediately and used for signing after the overlap period.
+// @Tags         Auth
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object} helper.APIResponse
+// @Failure      400  {object} helper.APIResponse
+// @Failure      403  {object} helper.APIResponse
+// @Router       /api/v1/auth/keys/rotate [post]
+func (h *KeyHandler) Rotate(c *fiber.Ctx) error {
+	if err := h.Keys.Rotate(true); err != nil {
+		return helper.Error(c, 400, err.Error())
+	}
+	return helper.Success(c, 200, "Signing key rotated", h.Keys.Keys())
+}
@@ -0,0 +1,96 @@
+package postgre
+
+import (
+	"strconv"
+
+	repo "reportachievement/app/repository/postgre"
+	"reportachievement/app/service"
+	"reportachievement/helper"
+	"reportachievement/middleware"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type LoginAttemptHandler struct {
+	Guard *service.LoginGuard
+}
+
+// Log percobaan login & unlock akun (permission user:manage)
+func RegisterLoginAttemptRoutes(app *fiber.App, guard *service.LoginGuard) {
+	h := &LoginAttemptHandler{Guard: guard}
+	canManage := middleware.RequirePermission("user:manage")
+
+	app.Get("/api/v1/auth/login-attempts", middleware.Protected(), canManage, h.List)
+	app.Post("/api/v1/users/:id/unlock", middleware.Protected(), canManage, h.Unlock)
+}
+
+// List godoc
+// @Summary      List Login Attempts
+// @Description  Successful and failed login attempts, newest first (permission user:manage)
+// @Tags         Auth
+// @Produce      json
+// @Security     BearerAuth
+// @Param        user_id   query string false "User ID"
+// @Param        username  query string false "Username as typed"
+// @Param        ip        query string false "IP address"
+// @Param        success   query bool   false "Only successful (true) or failed (false) attempts"
+// @Param        page      query int    false "Page" default(1)
+// @Param        limit     query int    false "Limit (max 100)" default(20)
+// @Success      200  {object} helper.APIResponse
+// @Failure      403  {object} helper.APIResponse
+// @Router       /api/v1/auth/login-attempts [get]
+func (h *LoginAttemptHandler) List(c *fiber.Ctx) error {
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	limit, _ := strconv.Atoi(c.Query("limit", "20"))
+	filter := repo.LoginAttemptFilter{
+		Page:      page,
+		Limit:     limit,
+		Username:  c.Query("username"),
+		IPAddress: c.Query("ip"),
+	}
+	if raw := c.Query("user_id"); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			return helper.Error(c, 400, "Invalid user_id")
+		}
+		filter.UserID = &id
+	}
+	if raw := c.Query("success"); raw != "" {
+		success, err := strconv.ParseBool(raw)
+		if err != nil {
+			return helper.Error(c, 400, "Invalid success filter")
+		}
+		filter.Success = &success
+	}
+
+	attempts, total, err := h.Guard.ListAttempts(filter)
+	if err != nil {
+		return helper.Error(c, 500, err.Error())
+	}
+	return helper.Success(c, 200, "Login attempts", fiber.Map{
+		"data": attempts,
+		"meta": fiber.Map{"page": page, "limit": limit, "total": total},
+	})
+}
+
+// Unlock godoc
+// @Summary      Unlock Account
+// @Description  Clear the failed login counter and lift a temporary lockout (permission user:manage)
+// @Tags         Users
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path string true "User ID"
+// @Success      200  {object} helper.APIResponse
+// @Failure      404  {object} helper.APIResponse
+// @Router       /api/v1/users/{id}/unlock [post]
+func (h *LoginAttemptHandler) Unlock(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return helper.Error(c, 400, "Invalid user ID")
+	}
+	if err := h.Guard.Unlock(id); err != nil {
+		return helper.Error(c, 404, err.Error())
+	}
+	return helper.Success(c, 200, "Account unlocked", nil)
+}
@@ -0,0 +1,227 @@
+package postgre
+
+import (
+	"errors"
+
+	"reportachievement/app/service"
+	"reportachievement/helper"
+	"reportachievement/middleware"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type MFAHandler struct {
+	Service *service.MFAService
+}
+
+type MFALoginRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code" example:"123456"` // kode TOTP atau recovery code
+}
+
+type MFACodeRequest struct {
+	Code string `json:"code" example:"123456"`
+}
+
+type MFADisableRequest struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+func RegisterMFARoutes(app *fiber.App, mfaService *service.MFAService) {
+	h := &MFAHandler{Service: mfaService}
+
+	// Langkah kedua login (publik, butuh challenge token dari /auth/login)
+	app.Post("/api/v1/auth/login/2fa", h.CompleteLogin)
+
+	api := app.Group("/api/v1/auth/2fa")
+	// Setup tetap bisa diakses user yang diwajibkan 2FA tetapi belum mengaktifkannya
+	pending := middleware.ProtectedAllowPending()
+	api.Get("/", pending, h.Status)
+	api.Post("/setup", pending, h.Setup)
+	api.Post("/enable", pending, h.Enable)
+	api.Post("/disable", middleware.Protected(), h.Disable)
+	api.Post("/recovery-codes", middleware.Protected(), h.RegenerateRecoveryCodes)
+
+	app.Delete("/api/v1/users/:id/2fa", middleware.Protected(), middleware.RequirePermission("user:manage"), h.Reset)
+}
+
+// CompleteLogin godoc
+// @Summary      Login Step 2 (2FA)
+// @Description  Exchange the challenge token from /auth/login and a TOTP or recovery code for the access & refresh token
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Param        request body MFALoginRequest true "Challenge Token & Code"
+// @Success      200  {object} helper.APIResponse
+// @Failure      401  {object} helper.APIResponse
+// @Failure      429  {object} helper.APIResponse "Too many failed attempts (see Retry-After)"
+// @Router       /api/v1/auth/login/2fa [post]
+func (h *MFAHandler) CompleteLogin(c *fiber.Ctx) error {
+	var req MFALoginRequest
+	if err := c.BodyParser(&req); err != nil || req.ChallengeToken == "" || req.Code == "" {
+		return helper.Error(c, 400, "challenge_token and code are required")
+	}
+	client := service.ClientInfo{UserAgent: c.Get(fiber.HeaderUserAgent), IPAddress: c.IP()}
+	resp, err := h.Service.CompleteLogin(req.ChallengeToken, req.Code, client)
+	if err != nil {
+		return loginError(c, err)
+	}
+	return helper.Success(c, 200, "Login successful", resp)
+}
+
+// Status godoc
+// @Summary      2FA Status
+// @Tags         Auth
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object} helper.APIResponse
+// @Router       /api/v1/auth/2fa [get]
+func (h *MFAHandler) Status(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return helper.Error(c, 401, "Unauthorized")
+	}
+	status, err := h.Service.Status(userID)
+	if err != nil {
+		return helper.Error(c, 404, err.Error())
+	}
+	return helper.Success(c, 200, "2FA status", status)
+}
+
+// Setup godoc
+// @Summary      Start 2FA Setup
+// @Description  Generate a TOTP secret and otpauth:// provisioning URI (render as QR code). 2FA is active only after /auth/2fa/enable.
+// @Tags         Auth
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object} helper.APIResponse
+// @Failure      409  {object} helper.APIResponse
+// @Router       /api/v1/auth/2fa/setup [post]
+func (h *MFAHandler) Setup(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return helper.Error(c, 401, "Unauthorized")
+	}
+	setup, err := h.Service.Setup(userID)
+	if err != nil {
+		return mfaError(c, err)
+	}
+	return helper.Success(c, 200, "Scan the QR code with your authenticator app", setup)
+}
+
+// Enable godoc
+// @Summary      Enable 2FA
+// @Description  Confirm the first code from the authenticator app. Returns recovery codes, shown only once.
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request body MFACodeRequest true "TOTP Code"
+// @Success      200  {object} helper.APIResponse
+// @Failure      400  {object} helper.APIResponse
+// @Router       /api/v1/auth/2fa/enable [post]
+func (h *MFAHandler) Enable(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return helper.Error(c, 401, "Unauthorized")
+	}
+	var req MFACodeRequest
+	if err := c.BodyParser(&req); err != nil || req.Code == "" {
+		return helper.Error(c, 400, "code is required")
+	}
+	codes, err := h.Service.Enable(userID, req.Code)
+	if err != nil {
+		return mfaError(c, err)
+	}
+	return helper.Success(c, 200, "2FA enabled, store the recovery codes safely", fiber.Map{"recovery_codes": codes})
+}
+
+// Disable godoc
+// @Summary      Disable 2FA
+// @Description  Requires the current password and a TOTP or recovery code. Not allowed when 2FA is required for the role.
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request body MFADisableRequest true "Password & Code"
+// @Success      200  {object} helper.APIResponse
+// @Failure      400  {object} helper.APIResponse
+// @Failure      409  {object} helper.APIResponse
+// @Router       /api/v1/auth/2fa/disable [post]
+func (h *MFAHandler) Disable(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return helper.Error(c, 401, "Unauthorized")
+	}
+	var req MFADisableRequest
+	if err := c.BodyParser(&req); err != nil || req.Password == "" || req.Code == "" {
+		return helper.Error(c, 400, "password and code are required")
+	}
+	if err := h.Service.Disable(userID, req.Password, req.Code); err != nil {
+		return mfaError(c, err)
+	}
+	return helper.Success(c, 200, "2FA disabled", nil)
+}
+
+// RegenerateRecoveryCodes godoc
+// @Summary      Regenerate Recovery Codes
+// @Description  Replace all recovery codes (requires a TOTP code). Old codes stop working.
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request body MFACodeRequest true "TOTP Code"
+// @Success      200  {object} helper.APIResponse
+// @Failure      400  {object} helper.APIResponse
+// @Router       /api/v1/auth/2fa/recovery-codes [post]
+func (h *MFAHandler) RegenerateRecoveryCodes(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return helper.Error(c, 401, "Unauthorized")
+	}
+	var req MFACodeRequest
+	if err := c.BodyParser(&req); err != nil || req.Code == "" {
+		return helper.Error(c, 400, "code is required")
+	}
+	codes, err := h.Service.RegenerateRecoveryCodes(userID, req.Code)
+	if err != nil {
+		return mfaError(c, err)
+	}
+	return helper.Success(c, 200, "Recovery codes regenerated", fiber.Map{"recovery_codes": codes})
+}
+
+// Reset godoc
+// @Summary      Reset User 2FA
+// @Description  Remove a user's 2FA, e.g. after a lost phone (permission user:manage)
+// @Tags         Users
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path string true "User ID"
+// @Success      200  {object} helper.APIResponse
+// @Failure      404  {object} helper.APIResponse
+// @Router       /api/v1/users/{id}/2fa [delete]
+func (h *MFAHandler) Reset(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return helper.Error(c, 400, "Invalid user ID")
+	}
+	if err := h.Service.Reset(id); err != nil {
+		return helper.Error(c, 404, err.Error())
+	}
+	return helper.Success(c, 200, "2FA reset", nil)
+}
+
+func mfaError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, service.ErrMFAAlreadyEnabled), errors.Is(err, service.ErrMFARequired):
+		return helper.Error(c, 409, err.Error())
+	case errors.Is(err, service.ErrMFANotEnrolled), errors.Is(err, service.ErrMFANotEnabled),
+		errors.Is(err, service.ErrInvalidMFACode), errors.Is(err, service.ErrInvalidCurrentPassword):
+		return helper.Error(c, 400, err.Error())
+	case errors.Is(err, service.ErrAuthProviderUnavailable):
+		return helper.Error(c, 503, err.Error())
+	}
+	return helper.Error(c, 500, err.Error())
+}
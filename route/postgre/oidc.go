@@ -0,0 +1,108 @@
+package postgre
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+	"time"
+
+	"reportachievement/app/service"
+	"reportachievement/helper"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const oidcStateCookie = "oidc_state"
+
+type OIDCHandler struct {
+	Service     *service.OIDCService
+	FrontendURL string // kosong = callback mengembalikan JSON (uji manual)
+}
+
+// Login SSO (OpenID Connect): browser diarahkan ke provider lalu kembali ke callback
+func RegisterOIDCRoutes(app *fiber.App, oidcService *service.OIDCService, frontendURL string) {
+	h := &OIDCHandler{Service: oidcService, FrontendURL: frontendURL}
+	api := app.Group("/api/v1/auth/oidc")
+	api.Get("/login", h.Login)
+	api.Get("/callback", h.Callback)
+}
+
+// Login godoc
+// @Summary      SSO Login
+// @Description  Redirect the browser to the campus OpenID Connect provider (authorization code + PKCE)
+// @Tags         Auth
+// @Success      302
+// @Failure      404  {object} helper.APIResponse
+// @Router       /api/v1/auth/oidc/login [get]
+func (h *OIDCHandler) Login(c *fiber.Ctx) error {
+	authURL, stateToken, err := h.Service.Start(c.Context())
+	if err != nil {
+		if errors.Is(err, service.ErrOIDCDisabled) {
+			return helper.Error(c, 404, err.Error())
+		}
+		return helper.Error(c, 502, err.Error())
+	}
+
+	c.Cookie(&fiber.Cookie{
+		Name:     oidcStateCookie,
+		Value:    stateToken,
+		Path:     "/api/v1/auth/oidc",
+		Expires:  time.Now().Add(10 * time.Minute),
+		HTTPOnly: true,
+		Secure:   c.Protocol() == "https",
+		SameSite: fiber.CookieSameSiteLaxMode, // cookie ikut terkirim saat provider redirect kembali
+	})
+	return c.Redirect(authURL, fiber.StatusFound)
+}
+
+// Callback godoc
+// @Summary      SSO Callback
+// @Description  Redirect target of the OpenID Connect provider. On success the browser is sent to OIDC_FRONTEND_URL with the tokens in the URL fragment (#token=...&refresh_token=...), or JSON is returned when no frontend URL is configured. Users with 2FA enabled receive mfa_required and challenge_token instead, to be completed through POST /api/v1/auth/login/2fa.
+// @Tags         Auth
+// @Param        code   query string true "Authorization code"
+// @Param        state  query string true "State"
+// @Success      302
+// @Success      200  {object} helper.APIResponse
+// @Failure      401  {object} helper.APIResponse
+// @Router       /api/v1/auth/oidc/callback [get]
+func (h *OIDCHandler) Callback(c *fiber.Ctx) error {
+	stateToken := c.Cookies(oidcStateCookie)
+	c.ClearCookie(oidcStateCookie)
+
+	if providerErr := c.Query("error"); providerErr != "" {
+		return h.fail(c, 401, providerErr+": "+c.Query("error_description"))
+	}
+
+	client := service.ClientInfo{UserAgent: c.Get(fiber.HeaderUserAgent), IPAddress: c.IP()}
+	resp, err := h.Service.Callback(c.Context(), stateToken, c.Query("state"), c.Query("code"), client)
+	if err != nil {
+		return h.fail(c, 401, err.Error())
+	}
+
+	if h.FrontendURL == "" {
+		return helper.Success(c, 200, "Login successful", resp)
+	}
+	// Fragment tidak dikirim ke server manapun & tidak tercatat di access log
+	fragment := url.Values{}
+	if _, ok := resp["mfa_required"]; ok {
+		// Frontend meminta kode 2FA lalu mengirim challenge_token ke POST /api/v1/auth/login/2fa
+		fragment.Set("mfa_required", "true")
+		fragment.Set("challenge_token", resp["challenge_token"].(string))
+		fragment.Set("expires_in", strconv.Itoa(resp["expires_in"].(int)))
+		return c.Redirect(h.FrontendURL+"#"+fragment.Encode(), fiber.StatusFound)
+	}
+	fragment.Set("token", resp["token"].(string))
+	fragment.Set("token_type", "Bearer")
+	fragment.Set("expires_in", strconv.Itoa(resp["expires_in"].(int)))
+	fragment.Set("refresh_token", resp["refresh_token"].(string))
+	fragment.Set("must_change_password", strconv.FormatBool(resp["must_change_password"].(bool)))
+	fragment.Set("mfa_enrollment_required", strconv.FormatBool(resp["mfa_enrollment_required"].(bool)))
+	return c.Redirect(h.FrontendURL+"#"+fragment.Encode(), fiber.StatusFound)
+}
+
+func (h *OIDCHandler) fail(c *fiber.Ctx, status int, message string) error {
+	if h.FrontendURL == "" {
+		return helper.Error(c, status, message)
+	}
+	return c.Redirect(h.FrontendURL+"#"+url.Values{"error": {message}}.Encode(), fiber.StatusFound)
+}
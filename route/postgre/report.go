@@ -1,23 +1,32 @@
 package postgre
 
 import (
+	"errors"
 	"reportachievement/app/service"
 	"reportachievement/helper" // Import Helper
 	"reportachievement/middleware"
+	"strings"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
 )
 
 type ReportHandler struct {
-	Service *service.ReportService
+	Service     *service.ReportService
+	SKPIService *service.SKPIService
 }
 
-func RegisterReportRoutes(app *fiber.App, reportService *service.ReportService) {
-	h := &ReportHandler{Service: reportService}
+func RegisterReportRoutes(app *fiber.App, reportService *service.ReportService, skpiService *service.SKPIService) {
+	h := &ReportHandler{Service: reportService, SKPIService: skpiService}
 	api := app.Group("/api/v1/reports")
 	api.Use(middleware.Protected())
 
-	api.Get("/statistics", h.GetStats)
+	api.Get("/statistics", middleware.RequirePermission("report:read"), h.GetStats)
+	api.Get("/duplicate-evidence", middleware.RequirePermission("report:audit"), h.GetDuplicateEvidence)
+
+	// SKPI (Surat Keterangan Pendamping Ijazah), ?format=pdf|html|doc
+	api.Get("/skpi/students/:id", h.GetStudentSKPI)
+	api.Get("/skpi/cohort", middleware.RequirePermission("skpi:export_cohort"), h.GetCohortSKPI)
 }
 
 func (h *ReportHandler) GetStats(c *fiber.Ctx) error {
@@ -27,3 +36,92 @@ func (h *ReportHandler) GetStats(c *fiber.Ctx) error {
 	}
 	return helper.Success(c, 200, "Dashboard Statistics", stats)
 }
+
+// Evidence dengan hash SHA-256 sama yang dipakai oleh beberapa mahasiswa (permission report:audit)
+func (h *ReportHandler) GetDuplicateEvidence(c *fiber.Ctx) error {
+	result, err := h.Service.GetDuplicateEvidence(c.Context())
+	if err != nil {
+		return helper.Error(c, 500, err.Error())
+	}
+	return helper.Success(c, 200, "Duplicate Evidence", result)
+}
+
+func (h *ReportHandler) GetStudentSKPI(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return helper.Error(c, 401, "Unauthorized")
+	}
+	studentID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return helper.Error(c, 400, "Invalid student ID")
+	}
+
+	doc, err := h.SKPIService.GetStudentSKPI(c.Context(), userID, accessScope(c), studentID)
+	if err != nil {
+		return skpiError(c, err)
+	}
+
+	return sendSKPI(c, []service.SKPIDocument{*doc}, "SKPI_"+doc.NIM)
+}
+
+func (h *ReportHandler) GetCohortSKPI(c *fiber.Ctx) error {
+	academicYear := c.Query("academic_year")
+	programStudy := c.Query("program_study")
+
+	docs, err := h.SKPIService.GetCohortSKPI(c.Context(), academicYear, programStudy)
+	if err != nil {
+		return skpiError(c, err)
+	}
+
+	return sendSKPI(c, docs, "SKPI_Angkatan_"+academicYear)
+}
+
+func skpiError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, service.ErrStudentNotFound), errors.Is(err, service.ErrCohortNotFound):
+		return helper.Error(c, 404, err.Error())
+	case errors.Is(err, service.ErrSKPIForbidden):
+		return helper.Error(c, 403, err.Error())
+	case errors.Is(err, service.ErrAcademicYearRequired):
+		return helper.Error(c, 400, err.Error())
+	}
+	return helper.Error(c, 500, err.Error())
+}
+
+// sendSKPI: render sesuai query ?format= (default pdf)
+func sendSKPI(c *fiber.Ctx, docs []service.SKPIDocument, baseName string) error {
+	switch c.Query("format", "pdf") {
+	case "pdf":
+		content, err := service.RenderSKPIPDF(docs)
+		if err != nil {
+			return helper.Error(c, 500, err.Error())
+		}
+		c.Set(fiber.HeaderContentType, "application/pdf")
+		c.Set(fiber.HeaderContentDisposition, contentDisposition("attachment", skpiFileName(baseName, ".pdf")))
+		return c.Send(content)
+	case "html":
+		content, err := service.RenderSKPIHTML(docs)
+		if err != nil {
+			return helper.Error(c, 500, err.Error())
+		}
+		c.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+		return c.Send(content)
+	case "doc":
+		// HTML yang kompatibel dengan MS Word
+		content, err := service.RenderSKPIHTML(docs)
+		if err != nil {
+			return helper.Error(c, 500, err.Error())
+		}
+		c.Set(fiber.HeaderContentType, "application/msword")
+		c.Set(fiber.HeaderContentDisposition, contentDisposition("attachment", skpiFileName(baseName, ".doc")))
+		return c.Send(content)
+	default:
+		return helper.Error(c, 400, "Invalid format, use pdf, html or doc")
+	}
+}
+
+// skpiFileName: baseName memuat input query (academic_year), dibersihkan seperti nama file evidence.
+// "/" pada tahun ajaran (2021/2022) bukan pemisah path.
+func skpiFileName(baseName, ext string) string {
+	return service.SanitizeFileName(strings.NewReplacer("/", "-", "\\", "-").Replace(baseName)+ext, ext)
+}
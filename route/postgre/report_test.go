@@ -0,0 +1,38 @@
+package postgre
+
+import (
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"reportachievement/app/service"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSKPIError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"mahasiswa tidak ada", service.ErrStudentNotFound, 404},
+		{"angkatan kosong", service.ErrCohortNotFound, 404},
+		{"bukan dosen wali", fmt.Errorf("%w: you are not the advisor for this student", service.ErrSKPIForbidden), 403},
+		{"tahun ajaran kosong", service.ErrAcademicYearRequired, 400},
+		{"database gagal", errors.New("connection refused"), 500},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := fiber.New()
+			app.Get("/", func(c *fiber.Ctx) error { return skpiError(c, tt.err) })
+
+			resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/", nil))
+			if assert.NoError(t, err) {
+				assert.Equal(t, tt.want, resp.StatusCode)
+			}
+		})
+	}
+}
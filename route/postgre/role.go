@@ -0,0 +1,152 @@
+package postgre
+
+import (
+	"errors"
+	"net/url"
+	"reportachievement/app/service"
+	"reportachievement/helper"
+	"reportachievement/middleware"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type RoleHandler struct {
+	Service *service.RoleService
+}
+
+type PermissionRequest struct {
+	Permission string `json:"permission" example:"achievement:verify"`
+}
+
+type ChangeRoleRequest struct {
+	Role string `json:"role" example:"Dosen Wali"`
+}
+
+// Administrasi role & permission (permission role:manage)
+func RegisterRoleRoutes(app *fiber.App, roleService *service.RoleService) {
+	h := &RoleHandler{Service: roleService}
+	canManage := middleware.RequirePermission("role:manage")
+
+	api := app.Group("/api/v1/roles")
+	api.Use(middleware.Protected(), canManage)
+	api.Get("/", h.List)
+	api.Post("/", h.Create)
+	api.Put("/:id", h.Update)
+	api.Delete("/:id", h.Delete)
+	api.Post("/:id/permissions", h.AttachPermission)
+	api.Delete("/:id/permissions/:permission", h.DetachPermission)
+
+	app.Get("/api/v1/permissions", middleware.Protected(), canManage, h.ListPermissions)
+	// Berada di bawah grup /api/v1/users, sehingga juga butuh user:manage
+	app.Put("/api/v1/users/:id/role", middleware.Protected(), canManage, h.ChangeUserRole)
+}
+
+func (h *RoleHandler) List(c *fiber.Ctx) error {
+	roles, err := h.Service.ListRoles()
+	if err != nil {
+		return helper.Error(c, 500, err.Error())
+	}
+	return helper.Success(c, 200, "List Roles", roles)
+}
+
+func (h *RoleHandler) Create(c *fiber.Ctx) error {
+	var req service.RoleRequest
+	if err := c.BodyParser(&req); err != nil {
+		return helper.Error(c, 400, "Invalid JSON")
+	}
+	role, err := h.Service.CreateRole(req)
+	if err != nil {
+		return roleError(c, err)
+	}
+	return helper.Success(c, 201, "Role created", role)
+}
+
+func (h *RoleHandler) Update(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return helper.Error(c, 400, "Invalid role ID")
+	}
+	var req service.RoleRequest
+	if err := c.BodyParser(&req); err != nil {
+		return helper.Error(c, 400, "Invalid JSON")
+	}
+	if err := h.Service.UpdateRole(id, req); err != nil {
+		return roleError(c, err)
+	}
+	return helper.Success(c, 200, "Role updated", nil)
+}
+
+func (h *RoleHandler) Delete(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return helper.Error(c, 400, "Invalid role ID")
+	}
+	if err := h.Service.DeleteRole(id); err != nil {
+		return roleError(c, err)
+	}
+	return helper.Success(c, 200, "Role deleted", nil)
+}
+
+func (h *RoleHandler) ListPermissions(c *fiber.Ctx) error {
+	permissions, err := h.Service.ListPermissions()
+	if err != nil {
+		return helper.Error(c, 500, err.Error())
+	}
+	return helper.Success(c, 200, "List Permissions", permissions)
+}
+
+func (h *RoleHandler) AttachPermission(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return helper.Error(c, 400, "Invalid role ID")
+	}
+	var req PermissionRequest
+	if err := c.BodyParser(&req); err != nil || req.Permission == "" {
+		return helper.Error(c, 400, "permission is required")
+	}
+	if err := h.Service.AttachPermission(id, req.Permission); err != nil {
+		return roleError(c, err)
+	}
+	return helper.Success(c, 200, "Permission attached", nil)
+}
+
+func (h *RoleHandler) DetachPermission(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return helper.Error(c, 400, "Invalid role ID")
+	}
+	permission, err := url.PathUnescape(c.Params("permission")) // "achievement%3Averify"
+	if err != nil {
+		return helper.Error(c, 400, "Invalid permission")
+	}
+	if err := h.Service.DetachPermission(id, permission); err != nil {
+		return roleError(c, err)
+	}
+	return helper.Success(c, 200, "Permission detached", nil)
+}
+
+func (h *RoleHandler) ChangeUserRole(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return helper.Error(c, 400, "Invalid user ID")
+	}
+	var req ChangeRoleRequest
+	if err := c.BodyParser(&req); err != nil || req.Role == "" {
+		return helper.Error(c, 400, "role is required")
+	}
+	if err := h.Service.ChangeUserRole(id, req.Role); err != nil {
+		return roleError(c, err)
+	}
+	return helper.Success(c, 200, "User role changed", nil)
+}
+
+func roleError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, service.ErrRoleNotFound), errors.Is(err, service.ErrPermissionNotFound):
+		return helper.Error(c, 404, err.Error())
+	case errors.Is(err, service.ErrBuiltinRole), errors.Is(err, service.ErrRoleInUse), errors.Is(err, service.ErrLastAdmin):
+		return helper.Error(c, 409, err.Error())
+	}
+	return helper.Error(c, 400, err.Error())
+}
@@ -0,0 +1,211 @@
+package postgre
+
+import (
+	"errors"
+
+	"reportachievement/app/service"
+	"reportachievement/helper"
+	"reportachievement/middleware"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type ServiceAccountHandler struct {
+	Service *service.APIKeyService
+}
+
+// Service account & API key untuk integrasi mesin (permission service_account:manage)
+func RegisterServiceAccountRoutes(app *fiber.App, apiKeyService *service.APIKeyService) {
+	h := &ServiceAccountHandler{Service: apiKeyService}
+	api := app.Group("/api/v1/service-accounts")
+	api.Use(middleware.Protected(), middleware.RequirePermission("service_account:manage"))
+
+	api.Get("/", h.List)
+	api.Post("/", h.Create)
+	api.Put("/:id", h.Update)
+	api.Delete("/:id", h.Delete)
+	api.Get("/:id/keys", h.ListKeys)
+	api.Post("/:id/keys", h.CreateKey)
+	api.Delete("/:id/keys/:keyId", h.RevokeKey)
+}
+
+// List godoc
+// @Summary      List Service Accounts
+// @Description  Machine accounts used by integrations (permission service_account:manage)
+// @Tags         Service Accounts
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object} helper.APIResponse
+// @Failure      403  {object} helper.APIResponse
+// @Router       /api/v1/service-accounts [get]
+func (h *ServiceAccountHandler) List(c *fiber.Ctx) error {
+	accounts, err := h.Service.ListAccounts()
+	if err != nil {
+		return helper.Error(c, 500, err.Error())
+	}
+	return helper.Success(c, 200, "List Service Accounts", accounts)
+}
+
+// Create godoc
+// @Summary      Create Service Account
+// @Tags         Service Accounts
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request body service.ServiceAccountRequest true "Name & Description"
+// @Success      201  {object} helper.APIResponse
+// @Failure      400  {object} helper.APIResponse
+// @Router       /api/v1/service-accounts [post]
+func (h *ServiceAccountHandler) Create(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return helper.Error(c, 401, "Unauthorized")
+	}
+	var req service.ServiceAccountRequest
+	if err := c.BodyParser(&req); err != nil {
+		return helper.Error(c, 400, "Invalid JSON")
+	}
+	account, err := h.Service.CreateAccount(req, userID)
+	if err != nil {
+		return helper.Error(c, 400, err.Error())
+	}
+	return helper.Success(c, 201, "Service account created", account)
+}
+
+// Update godoc
+// @Summary      Update Service Account
+// @Description  Change the description or deactivate the account (all its API keys stop working)
+// @Tags         Service Accounts
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id      path string true "Service Account ID"
+// @Param        request body service.ServiceAccountRequest true "Description / is_active"
+// @Success      200  {object} helper.APIResponse
+// @Failure      404  {object} helper.APIResponse
+// @Router       /api/v1/service-accounts/{id} [put]
+func (h *ServiceAccountHandler) Update(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return helper.Error(c, 400, "Invalid service account ID")
+	}
+	var req service.ServiceAccountRequest
+	if err := c.BodyParser(&req); err != nil {
+		return helper.Error(c, 400, "Invalid JSON")
+	}
+	if err := h.Service.UpdateAccount(id, req); err != nil {
+		return serviceAccountError(c, err)
+	}
+	return helper.Success(c, 200, "Service account updated", nil)
+}
+
+// Delete godoc
+// @Summary      Delete Service Account
+// @Description  Delete the account together with all its API keys
+// @Tags         Service Accounts
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path string true "Service Account ID"
+// @Success      200  {object} helper.APIResponse
+// @Failure      404  {object} helper.APIResponse
+// @Router       /api/v1/service-accounts/{id} [delete]
+func (h *ServiceAccountHandler) Delete(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return helper.Error(c, 400, "Invalid service account ID")
+	}
+	if err := h.Service.DeleteAccount(id); err != nil {
+		return serviceAccountError(c, err)
+	}
+	return helper.Success(c, 200, "Service account deleted", nil)
+}
+
+// ListKeys godoc
+// @Summary      List API Keys
+// @Description  Keys of a service account with scopes and last use (the key itself is never shown again)
+// @Tags         Service Accounts
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path string true "Service Account ID"
+// @Success      200  {object} helper.APIResponse
+// @Failure      404  {object} helper.APIResponse
+// @Router       /api/v1/service-accounts/{id}/keys [get]
+func (h *ServiceAccountHandler) ListKeys(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return helper.Error(c, 400, "Invalid service account ID")
+	}
+	keys, err := h.Service.ListKeys(id)
+	if err != nil {
+		return serviceAccountError(c, err)
+	}
+	return helper.Success(c, 200, "List API Keys", keys)
+}
+
+// CreateKey godoc
+// @Summary      Create API Key
+// @Description  Returns the key once. Send it as "X-API-Key: prs_..." or "Authorization: Bearer prs_...". Without scopes the read-only defaults are used; non-read scopes need allow_write.
+// @Tags         Service Accounts
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id      path string true "Service Account ID"
+// @Param        request body service.CreateAPIKeyRequest true "Name, Scopes & Expiry"
+// @Success      201  {object} helper.APIResponse
+// @Failure      400  {object} helper.APIResponse
+// @Router       /api/v1/service-accounts/{id}/keys [post]
+func (h *ServiceAccountHandler) CreateKey(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return helper.Error(c, 401, "Unauthorized")
+	}
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return helper.Error(c, 400, "Invalid service account ID")
+	}
+	var req service.CreateAPIKeyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return helper.Error(c, 400, "Invalid JSON")
+	}
+	key, err := h.Service.CreateKey(id, req, userID)
+	if err != nil {
+		return serviceAccountError(c, err)
+	}
+	return helper.Success(c, 201, "API key created, store it now: it will not be shown again", key)
+}
+
+// RevokeKey godoc
+// @Summary      Revoke API Key
+// @Tags         Service Accounts
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id     path string true "Service Account ID"
+// @Param        keyId  path string true "API Key ID"
+// @Success      200  {object} helper.APIResponse
+// @Failure      404  {object} helper.APIResponse
+// @Router       /api/v1/service-accounts/{id}/keys/{keyId} [delete]
+func (h *ServiceAccountHandler) RevokeKey(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return helper.Error(c, 400, "Invalid service account ID")
+	}
+	keyID, err := uuid.Parse(c.Params("keyId"))
+	if err != nil {
+		return helper.Error(c, 400, "Invalid API key ID")
+	}
+	if err := h.Service.RevokeKey(id, keyID); err != nil {
+		return serviceAccountError(c, err)
+	}
+	return helper.Success(c, 200, "API key revoked", nil)
+}
+
+func serviceAccountError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, service.ErrServiceAccountNotFound), errors.Is(err, service.ErrAPIKeyNotFound):
+		return helper.Error(c, 404, err.Error())
+	case errors.Is(err, service.ErrAPIKeyScope):
+		return helper.Error(c, 400, err.Error())
+	}
+	return helper.Error(c, 500, err.Error())
+}
@@ -0,0 +1,44 @@
+package postgre
+
+import (
+	"reportachievement/app/service"
+	"reportachievement/helper"
+	"reportachievement/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type StorageHandler struct {
+	GC *service.StorageGC
+}
+
+// Garbage collector file evidence (permission storage:manage), padanan CLI: go run . gc
+func RegisterStorageRoutes(app *fiber.App, gc *service.StorageGC) {
+	h := &StorageHandler{GC: gc}
+	api := app.Group("/api/v1/storage")
+	api.Use(middleware.Protected(), middleware.RequirePermission("storage:manage"))
+
+	api.Get("/orphans", h.ListOrphans) // dry-run
+	api.Post("/gc", h.CollectGarbage)  // ?dry_run=true untuk laporan saja
+}
+
+func (h *StorageHandler) ListOrphans(c *fiber.Ctx) error {
+	report, err := h.GC.Run(c.Context(), true)
+	if err != nil {
+		return helper.Error(c, 500, err.Error())
+	}
+	return helper.Success(c, 200, "Orphaned files (dry run)", report)
+}
+
+func (h *StorageHandler) CollectGarbage(c *fiber.Ctx) error {
+	dryRun := c.QueryBool("dry_run")
+	report, err := h.GC.Run(c.Context(), dryRun)
+	if err != nil {
+		return helper.Error(c, 500, err.Error())
+	}
+	message := "Orphaned files deleted"
+	if dryRun {
+		message = "Orphaned files (dry run)"
+	}
+	return helper.Success(c, 200, message, report)
+}
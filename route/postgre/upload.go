@@ -0,0 +1,214 @@
+package postgre
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"reportachievement/app/service"
+	"reportachievement/helper"
+	"reportachievement/middleware"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// Protokol tus (https://tus.io/protocols/resumable-upload) versi 1.0.0
+const (
+	tusVersion    = "1.0.0"
+	tusExtensions = "creation,termination,expiration"
+	tusChunkType  = "application/offset+octet-stream"
+)
+
+type UploadHandler struct {
+	Service *service.ResumableUploadService
+}
+
+// Resumable upload evidence, alternatif POST /:id/attachments untuk file besar.
+// Ukuran tiap chunk (PATCH) tetap dibatasi BodyLimit Fiber.
+func RegisterUploadRoutes(app *fiber.App, uploadService *service.ResumableUploadService) {
+	h := &UploadHandler{Service: uploadService}
+	api := app.Group("/api/v1/achievements/:id/uploads", h.tusHeaders)
+
+	api.Options("/", h.Options)
+	api.Options("/:uploadId", h.Options)
+
+	canWrite := middleware.RequirePermission("achievement:create")
+	api.Post("/", middleware.Protected(), canWrite, h.Create)
+	api.Head("/:uploadId", middleware.Protected(), canWrite, h.Head)
+	api.Get("/:uploadId", middleware.Protected(), canWrite, h.Get)
+	api.Patch("/:uploadId", middleware.Protected(), canWrite, h.Patch)
+	api.Delete("/:uploadId", middleware.Protected(), canWrite, h.Terminate)
+}
+
+// tusHeaders: semua response membawa Tus-Resumable, request selain OPTIONS wajib versi yang didukung
+func (h *UploadHandler) tusHeaders(c *fiber.Ctx) error {
+	c.Set("Tus-Resumable", tusVersion)
+	if c.Method() == fiber.MethodOptions || c.Method() == fiber.MethodGet {
+		return c.Next()
+	}
+	if c.Get("Tus-Resumable") != tusVersion {
+		c.Set("Tus-Version", tusVersion)
+		return helper.Error(c, 412, "unsupported Tus-Resumable version, supported: "+tusVersion)
+	}
+	return c.Next()
+}
+
+func (h *UploadHandler) Options(c *fiber.Ctx) error {
+	c.Set("Tus-Version", tusVersion)
+	c.Set("Tus-Extension", tusExtensions)
+	c.Set("Tus-Max-Size", strconv.FormatInt(h.Service.MaxSize(), 10))
+	return c.SendStatus(204)
+}
+
+// Create: header Upload-Length wajib, nama file dari Upload-Metadata "filename <base64>"
+func (h *UploadHandler) Create(c *fiber.Ctx) error {
+	userID, achievementID, err := uploadParams(c)
+	if err != nil {
+		return helper.Error(c, 400, err.Error())
+	}
+	if c.Get("Upload-Defer-Length") != "" {
+		return helper.Error(c, 400, "Upload-Defer-Length is not supported")
+	}
+	length, err := strconv.ParseInt(c.Get("Upload-Length"), 10, 64)
+	if err != nil || length < 0 {
+		return helper.Error(c, 400, "invalid Upload-Length")
+	}
+	metadata, err := parseUploadMetadata(c.Get("Upload-Metadata"))
+	if err != nil {
+		return helper.Error(c, 400, err.Error())
+	}
+	fileName := metadata["filename"]
+	if fileName == "" {
+		fileName = metadata["name"]
+	}
+
+	upload, err := h.Service.Create(userID, achievementID, length, fileName)
+	if err != nil {
+		return uploadError(c, err)
+	}
+
+	c.Set(fiber.HeaderLocation, c.BaseURL()+strings.TrimRight(c.Path(), "/")+"/"+upload.ID)
+	c.Set("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	return c.SendStatus(201)
+}
+
+// Head: offset terakhir yang diterima server, dipakai client untuk melanjutkan upload
+func (h *UploadHandler) Head(c *fiber.Ctx) error {
+	userID, achievementID, err := uploadParams(c)
+	if err != nil {
+		return c.SendStatus(404)
+	}
+	upload, err := h.Service.Get(userID, achievementID, c.Params("uploadId"))
+	if err != nil {
+		return c.SendStatus(404)
+	}
+	setUploadHeaders(c, upload)
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.SendStatus(200)
+}
+
+// Get: status upload dalam JSON (bukan bagian tus), termasuk attachment jika sudah selesai
+func (h *UploadHandler) Get(c *fiber.Ctx) error {
+	userID, achievementID, err := uploadParams(c)
+	if err != nil {
+		return helper.Error(c, 400, err.Error())
+	}
+	upload, err := h.Service.Get(userID, achievementID, c.Params("uploadId"))
+	if err != nil {
+		return uploadError(c, err)
+	}
+	return helper.Success(c, 200, "Upload status", upload)
+}
+
+// Patch: tambahkan chunk di Upload-Offset, upload yang lengkap langsung dilampirkan ke prestasi
+func (h *UploadHandler) Patch(c *fiber.Ctx) error {
+	userID, achievementID, err := uploadParams(c)
+	if err != nil {
+		return helper.Error(c, 400, err.Error())
+	}
+	if c.Get(fiber.HeaderContentType) != tusChunkType {
+		return helper.Error(c, 415, "Content-Type must be "+tusChunkType)
+	}
+	offset, err := strconv.ParseInt(c.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		return helper.Error(c, 400, "invalid Upload-Offset")
+	}
+
+	upload, err := h.Service.Append(c.Context(), userID, achievementID, c.Params("uploadId"), offset, bytes.NewReader(c.Body()))
+	if upload != nil {
+		setUploadHeaders(c, upload)
+	}
+	if err != nil {
+		return uploadError(c, err)
+	}
+	if upload.Attachment != nil {
+		c.Set("Upload-Attachment-Id", upload.Attachment.ID)
+	}
+	return c.SendStatus(204)
+}
+
+func (h *UploadHandler) Terminate(c *fiber.Ctx) error {
+	userID, achievementID, err := uploadParams(c)
+	if err != nil {
+		return helper.Error(c, 400, err.Error())
+	}
+	if err := h.Service.Terminate(userID, achievementID, c.Params("uploadId")); err != nil {
+		return uploadError(c, err)
+	}
+	return c.SendStatus(204)
+}
+
+func uploadParams(c *fiber.Ctx) (uuid.UUID, uuid.UUID, error) {
+	userID, err := getUserID(c)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, err
+	}
+	achievementID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return uuid.Nil, uuid.Nil, errors.New("invalid achievement id")
+	}
+	return userID, achievementID, nil
+}
+
+func setUploadHeaders(c *fiber.Ctx, upload *service.ResumableUpload) {
+	c.Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	c.Set("Upload-Length", strconv.FormatInt(upload.Length, 10))
+	c.Set("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+}
+
+// parseUploadMetadata: "key base64value,key2 base64value2" (value boleh kosong)
+func parseUploadMetadata(header string) (map[string]string, error) {
+	metadata := map[string]string{}
+	if strings.TrimSpace(header) == "" {
+		return metadata, nil
+	}
+	for _, pair := range strings.Split(header, ",") {
+		key, encoded, _ := strings.Cut(strings.TrimSpace(pair), " ")
+		if key == "" {
+			return nil, errors.New("invalid Upload-Metadata")
+		}
+		value, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, errors.New("invalid Upload-Metadata value for " + key)
+		}
+		metadata[key] = string(value)
+	}
+	return metadata, nil
+}
+
+func uploadError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, service.ErrUploadNotFound):
+		return helper.Error(c, 404, err.Error())
+	case errors.Is(err, service.ErrUploadOffsetMismatch), errors.Is(err, service.ErrUploadCompleted):
+		return helper.Error(c, 409, err.Error())
+	case errors.Is(err, service.ErrUploadLocked):
+		return helper.Error(c, 423, err.Error())
+	case errors.Is(err, service.ErrUploadExceedsLength):
+		return helper.Error(c, 413, err.Error())
+	}
+	return evidenceError(c, err)
+}
@@ -0,0 +1,115 @@
+package postgre
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"reportachievement/app/service"
+	"reportachievement/config"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseUploadMetadata(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  string
+		want    map[string]string
+		wantErr bool
+	}{
+		{"kosong", "", map[string]string{}, false},
+		{"satu pasangan", "filename c2VydGlmaWthdC5wZGY=", map[string]string{"filename": "sertifikat.pdf"}, false},
+		{"beberapa pasangan & spasi", "filename c2VydGlmaWthdC5wZGY=, filetype YXBwbGljYXRpb24vcGRm", map[string]string{"filename": "sertifikat.pdf", "filetype": "application/pdf"}, false},
+		{"value kosong", "is_confidential", map[string]string{"is_confidential": ""}, false},
+		{"base64 rusak", "filename bm90IGJhc2U2NA!!", nil, true},
+		{"base64 url-safe ditolak", "filename c2VydGlm_a2F0", nil, true},
+		{"lebih dari dua bagian", "filename c2VydGlm YXQucGRm", nil, true},
+		{"pasangan kosong", "filename c2VydGlmaWthdC5wZGY=,,", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseUploadMetadata(tt.header)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// testUploadApp: route PATCH tanpa JWT (user_id diset langsung) dan satu upload yang sudah menerima offset byte
+func testUploadApp(t *testing.T, length, offset int64) (*fiber.App, string) {
+	dir := t.TempDir()
+	uploadService, err := service.NewResumableUploadService(&config.Config{TusUploadDir: dir, TusUploadTTL: time.Hour}, nil, service.EvidencePolicy{MaxSizes: map[string]int64{"application/pdf": 4096}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	userID, achievementID, uploadID := uuid.New(), uuid.New(), "0123456789abcdef0123456789abcdef"
+	info, _ := json.Marshal(service.ResumableUpload{
+		ID: uploadID, AchievementID: achievementID, UserID: userID, FileName: "bukti.pdf",
+		Length: length, ContentType: "application/pdf", CreatedAt: time.Now(), ExpiresAt: time.Now().Add(time.Hour),
+	})
+	content := append([]byte("%PDF-1.7\n"), bytes.Repeat([]byte{0}, int(offset)-9)...)
+	os.WriteFile(filepath.Join(dir, uploadID+".info"), info, 0o640)
+	os.WriteFile(filepath.Join(dir, uploadID+".bin"), content, 0o640)
+
+	h := &UploadHandler{Service: uploadService}
+	app := fiber.New()
+	app.Patch("/api/v1/achievements/:id/uploads/:uploadId", h.tusHeaders, func(c *fiber.Ctx) error {
+		c.Locals("user_id", userID.String())
+		return c.Next()
+	}, h.Patch)
+	return app, "/api/v1/achievements/" + achievementID.String() + "/uploads/" + uploadID
+}
+
+func TestUploadPatch_Status(t *testing.T) {
+	tests := []struct {
+		name       string
+		offset     int64
+		body       []byte
+		wantStatus int
+		wantOffset string
+	}{
+		{"offset tidak cocok", 100, bytes.Repeat([]byte{0}, 50), 409, "512"},
+		{"melebihi Upload-Length", 512, bytes.Repeat([]byte{0}, 600), 413, "1024"},
+		{"chunk valid", 512, bytes.Repeat([]byte{0}, 100), 204, "612"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, path := testUploadApp(t, 1024, 512)
+
+			req := httptest.NewRequest(fiber.MethodPatch, path, bytes.NewReader(tt.body))
+			req.Header.Set("Tus-Resumable", tusVersion)
+			req.Header.Set(fiber.HeaderContentType, tusChunkType)
+			req.Header.Set("Upload-Offset", strconv.FormatInt(tt.offset, 10))
+			resp, err := app.Test(req)
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, tt.wantStatus, resp.StatusCode)
+			assert.Equal(t, tt.wantOffset, resp.Header.Get("Upload-Offset"))
+			assert.Equal(t, tusVersion, resp.Header.Get("Tus-Resumable"))
+		})
+	}
+
+	t.Run("Versi tus tidak didukung", func(t *testing.T) {
+		app, path := testUploadApp(t, 1024, 512)
+		req := httptest.NewRequest(fiber.MethodPatch, path, bytes.NewReader(nil))
+		req.Header.Set("Tus-Resumable", "0.2.2")
+		resp, err := app.Test(req)
+		if assert.NoError(t, err) {
+			assert.Equal(t, 412, resp.StatusCode)
+		}
+	})
+}
@@ -1,37 +1,37 @@
 package postgre
 
 import (
+	"errors"
 	"reportachievement/app/service"
 	"reportachievement/helper" // Import Helper
 	"reportachievement/middleware"
+	"reportachievement/password"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 )
 
 type UserHandler struct {
-	Service *service.UserService
+	Service         *service.UserService
+	PasswordService *service.PasswordService
 }
 
-func RegisterUserRoutes(app *fiber.App, userService *service.UserService) {
-	h := &UserHandler{Service: userService}
+func RegisterUserRoutes(app *fiber.App, userService *service.UserService, passwordService *service.PasswordService) {
+	h := &UserHandler{Service: userService, PasswordService: passwordService}
 	api := app.Group("/api/v1/users")
-	api.Use(middleware.Protected())
+	api.Use(middleware.Protected(), middleware.RequirePermission("user:manage"))
 
 	api.Get("/", h.GetAll)
 	api.Post("/", h.Create)
 	api.Put("/:id", h.Update)
 	api.Delete("/:id", h.Delete)
-}
-
-func (h *UserHandler) isAdmin(c *fiber.Ctx) bool {
-	return c.Locals("role") == "Admin"
+	api.Post("/:id/revoke-sessions", h.RevokeSessions)
+	api.Get("/:id/sessions", h.ListSessions)
+	api.Delete("/:id/sessions/:sessionId", h.RevokeSession)
+	api.Post("/:id/password-reset", h.SendPasswordReset)
 }
 
 func (h *UserHandler) GetAll(c *fiber.Ctx) error {
-	if !h.isAdmin(c) {
-		return helper.Error(c, 403, "Forbidden")
-	}
 	users, err := h.Service.GetAll()
 	if err != nil {
 		return helper.Error(c, 500, err.Error())
@@ -40,41 +40,97 @@ func (h *UserHandler) GetAll(c *fiber.Ctx) error {
 }
 
 func (h *UserHandler) Create(c *fiber.Ctx) error {
-	if !h.isAdmin(c) {
-		return helper.Error(c, 403, "Forbidden")
-	}
 	var req service.CreateUserRequest
 	if err := c.BodyParser(&req); err != nil {
 		return helper.Error(c, 400, "Invalid JSON")
 	}
 	if err := h.Service.Create(req); err != nil {
+		if password.IsPolicyError(err) || errors.Is(err, service.ErrUnknownAuthSource) {
+			return helper.Error(c, 400, err.Error())
+		}
 		return helper.Error(c, 500, err.Error())
 	}
 	return helper.Success(c, 201, "User created", nil)
 }
 
 func (h *UserHandler) Update(c *fiber.Ctx) error {
-	if !h.isAdmin(c) {
-		return helper.Error(c, 403, "Forbidden")
-	}
 	id, _ := uuid.Parse(c.Params("id"))
 	var req service.UpdateUserRequest
 	if err := c.BodyParser(&req); err != nil {
 		return helper.Error(c, 400, "Invalid JSON")
 	}
 	if err := h.Service.Update(id, req); err != nil {
+		if errors.Is(err, service.ErrUnknownAuthSource) {
+			return helper.Error(c, 400, err.Error())
+		}
+		if errors.Is(err, service.ErrLastAdmin) {
+			return helper.Error(c, 409, err.Error())
+		}
 		return helper.Error(c, 500, err.Error())
 	}
 	return helper.Success(c, 200, "User Updated", nil)
 }
 
 func (h *UserHandler) Delete(c *fiber.Ctx) error {
-	if !h.isAdmin(c) {
-		return helper.Error(c, 403, "Forbidden")
-	}
 	id, _ := uuid.Parse(c.Params("id"))
 	if err := h.Service.Delete(id); err != nil {
+		if errors.Is(err, service.ErrLastAdmin) {
+			return helper.Error(c, 409, err.Error())
+		}
 		return helper.Error(c, 500, err.Error())
 	}
 	return helper.Success(c, 200, "User Deleted", nil)
 }
+
+// RevokeSessions: paksa logout user dari semua perangkat (misal akun diduga dibobol)
+func (h *UserHandler) RevokeSessions(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return helper.Error(c, 400, "Invalid user ID")
+	}
+	if err := h.Service.RevokeSessions(id); err != nil {
+		return helper.Error(c, 400, err.Error())
+	}
+	return helper.Success(c, 200, "All sessions revoked", nil)
+}
+
+// ListSessions: perangkat tempat user sedang login
+func (h *UserHandler) ListSessions(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return helper.Error(c, 400, "Invalid user ID")
+	}
+	sessions, err := h.Service.ListSessions(id)
+	if err != nil {
+		return helper.Error(c, 404, err.Error())
+	}
+	return helper.Success(c, 200, "Active sessions", sessions)
+}
+
+// RevokeSession: logout user dari satu perangkat saja
+func (h *UserHandler) RevokeSession(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return helper.Error(c, 400, "Invalid user ID")
+	}
+	sessionID, err := uuid.Parse(c.Params("sessionId"))
+	if err != nil {
+		return helper.Error(c, 400, "Invalid session ID")
+	}
+	if err := h.Service.RevokeSession(id, sessionID); err != nil {
+		return sessionError(c, err)
+	}
+	return helper.Success(c, 200, "Session revoked", nil)
+}
+
+// SendPasswordReset: kirim link reset password ke email user (user lupa password)
+func (h *UserHandler) SendPasswordReset(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return helper.Error(c, 400, "Invalid user ID")
+	}
+	if err := h.PasswordService.SendResetLink(c.Context(), id, c.IP()); err != nil {
+		return helper.Error(c, 400, err.Error())
+	}
+	return helper.Success(c, 200, "Password reset link sent", nil)
+}
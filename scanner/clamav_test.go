@@ -0,0 +1,85 @@
+package scanner
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeClamd: terima satu koneksi INSTREAM, balas FOUND jika isi mengandung string EICAR
+func fakeClamd(t *testing.T) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				cmd, _ := r.ReadString('\x00')
+				if cmd != "zINSTREAM\x00" {
+					conn.Write([]byte("UNKNOWN COMMAND\x00"))
+					return
+				}
+				var content strings.Builder
+				size := make([]byte, 4)
+				for {
+					if _, err := io.ReadFull(r, size); err != nil {
+						return
+					}
+					n := binary.BigEndian.Uint32(size)
+					if n == 0 {
+						break
+					}
+					chunk := make([]byte, n)
+					io.ReadFull(r, chunk)
+					content.Write(chunk)
+				}
+				if strings.Contains(content.String(), "EICAR-STANDARD-ANTIVIRUS-TEST-FILE") {
+					conn.Write([]byte("stream: Eicar-Test-Signature FOUND\x00"))
+				} else {
+					conn.Write([]byte("stream: OK\x00"))
+				}
+			}(conn)
+		}
+	}()
+	return "tcp://" + ln.Addr().String()
+}
+
+func TestClamAV_Scan(t *testing.T) {
+	s := NewClamAV(fakeClamd(t), 5*time.Second)
+
+	t.Run("File Bersih", func(t *testing.T) {
+		result, err := s.Scan(context.Background(), strings.NewReader("%PDF-1.4 sertifikat"))
+		assert.NoError(t, err)
+		assert.False(t, result.Infected)
+	})
+
+	t.Run("File Terinfeksi", func(t *testing.T) {
+		eicar := `X5O!P%@AP[4\PZX54(P^)7CC)7}$EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*`
+		result, err := s.Scan(context.Background(), strings.NewReader(eicar))
+		assert.NoError(t, err)
+		assert.True(t, result.Infected)
+		assert.Equal(t, "Eicar-Test-Signature", result.Signature)
+	})
+}
+
+func TestClamAV_Unreachable(t *testing.T) {
+	s := NewClamAV("tcp://127.0.0.1:1", time.Second)
+	_, err := s.Scan(context.Background(), strings.NewReader("x"))
+	assert.Error(t, err)
+}
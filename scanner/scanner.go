@@ -0,0 +1,44 @@
+package scanner
+
+import (
+	"context"
+	"io"
+	"log"
+
+	"reportachievement/config"
+)
+
+// Result hasil scan satu file
+type Result struct {
+	Infected  bool
+	Signature string // Nama malware jika Infected
+}
+
+// Scanner: pemindai malware untuk file evidence yang diupload
+type Scanner interface {
+	Scan(ctx context.Context, r io.Reader) (Result, error)
+	Name() string
+}
+
+// New: pilih scanner sesuai SCANNER (noop | clamav)
+func New(cfg *config.Config) Scanner {
+	switch cfg.Scanner {
+	case "clamav":
+		log.Println("✅ Malware scanner: ClamAV", cfg.ClamAVAddress)
+		return NewClamAV(cfg.ClamAVAddress, cfg.ClamAVTimeout)
+	default:
+		log.Println("⚠️ Malware scanner: noop (file tidak dipindai)")
+		return Noop{}
+	}
+}
+
+// Noop: default, semua file dianggap bersih
+type Noop struct{}
+
+func (Noop) Scan(ctx context.Context, r io.Reader) (Result, error) {
+	return Result{}, nil
+}
+
+func (Noop) Name() string {
+	return "noop"
+}
@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalStorage_PutGetDelete(t *testing.T) {
+	root := t.TempDir()
+	s, err := NewLocalStorage(root, "http://localhost:3000/uploads/")
+	assert.NoError(t, err)
+	ctx := context.Background()
+
+	err = s.Put(ctx, "sertifikat juara.pdf", strings.NewReader("isi file"), 8, "application/pdf")
+	assert.NoError(t, err)
+
+	r, err := s.Get(ctx, "sertifikat juara.pdf")
+	assert.NoError(t, err)
+	content, _ := io.ReadAll(r)
+	r.Close()
+	assert.Equal(t, "isi file", string(content))
+
+	assert.Equal(t, "http://localhost:3000/uploads/sertifikat%20juara.pdf", s.URL("sertifikat juara.pdf"))
+
+	assert.NoError(t, s.Delete(ctx, "sertifikat juara.pdf"))
+	_, err = s.Get(ctx, "sertifikat juara.pdf")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestLocalStorage_RejectsPathTraversal(t *testing.T) {
+	root := t.TempDir()
+	s, _ := NewLocalStorage(filepath.Join(root, "uploads"), "")
+
+	err := s.Put(context.Background(), "../../escape.txt", strings.NewReader("x"), 1, "text/plain")
+	assert.NoError(t, err)
+
+	// Key dinormalisasi sehingga tetap berada di dalam root storage
+	_, err = os.Stat(filepath.Join(root, "uploads", "escape.txt"))
+	assert.NoError(t, err)
+	_, err = os.Stat(filepath.Join(root, "escape.txt"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestLocalStorage_List(t *testing.T) {
+	s, _ := NewLocalStorage(t.TempDir(), "")
+	ctx := context.Background()
+	s.Put(ctx, "sha256/ab/abc", strings.NewReader("isi"), 3, "application/pdf")
+	s.Put(ctx, "thumbnails/ab/abc.jpg", strings.NewReader("jpeg"), 4, "image/jpeg")
+
+	sizes := map[string]int64{}
+	err := s.List(ctx, func(obj Object) error {
+		sizes[obj.Key] = obj.Size
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int64{"sha256/ab/abc": 3, "thumbnails/ab/abc.jpg": 4}, sizes)
+}
@@ -0,0 +1,81 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"reportachievement/config"
+)
+
+var ErrNotFound = errors.New("file not found in storage")
+
+// Object: satu file di storage (hasil List)
+type Object struct {
+	Key     string
+	Size    int64
+	ModTime time.Time
+}
+
+// Storage: abstraksi penyimpanan file bukti (evidence).
+// Key selalu berupa path relatif dengan separator "/", contoh: "1765011873_ronaldo.jpg".
+type Storage interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+	URL(key string) string
+	// List: panggil fn untuk setiap file, berhenti jika fn mengembalikan error
+	List(ctx context.Context, fn func(Object) error) error
+}
+
+// New: pilih backend sesuai STORAGE_DRIVER (local | s3)
+func New(cfg *config.Config) Storage {
+	switch cfg.StorageDriver {
+	case "s3":
+		s, err := NewS3Storage(S3Options{
+			Endpoint:      cfg.S3Endpoint,
+			AccessKey:     cfg.S3AccessKey,
+			SecretKey:     cfg.S3SecretKey,
+			Bucket:        cfg.S3Bucket,
+			Region:        cfg.S3Region,
+			UseSSL:        cfg.S3UseSSL,
+			PublicBaseURL: cfg.StoragePublicURL,
+		})
+		if err != nil {
+			log.Fatal("❌ Gagal inisialisasi S3 storage:", err)
+		}
+		log.Println("✅ Storage: S3 bucket", cfg.S3Bucket)
+		return s
+	default:
+		s, err := NewLocalStorage(cfg.StorageLocalPath, cfg.StoragePublicURL)
+		if err != nil {
+			log.Fatal("❌ Gagal inisialisasi local storage:", err)
+		}
+		log.Println("✅ Storage: local", cfg.StorageLocalPath)
+		return s
+	}
+}
+
+// cleanKey: normalisasi key dan tolak path traversal ("../")
+func cleanKey(key string) (string, error) {
+	cleaned := path.Clean("/" + strings.ReplaceAll(key, "\\", "/"))
+	cleaned = strings.TrimPrefix(cleaned, "/")
+	if cleaned == "" || cleaned == "." {
+		return "", errors.New("invalid storage key")
+	}
+	return cleaned, nil
+}
+
+// joinURL: base URL + key, tiap segmen di-escape (nama file bisa mengandung spasi)
+func joinURL(base, key string) string {
+	segments := strings.Split(key, "/")
+	for i, seg := range segments {
+		segments[i] = url.PathEscape(seg)
+	}
+	return strings.TrimRight(base, "/") + "/" + strings.Join(segments, "/")
+}